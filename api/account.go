@@ -1,14 +1,11 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
 	"github.com/harrychopra/go-api/token"
-	"github.com/lib/pq"
 )
 
 type createAccountRequest struct {
@@ -18,28 +15,13 @@ type createAccountRequest struct {
 func (server *Server) CreateAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		writeBindingError(ctx, err)
 		return
 	}
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.CreateAccountParams{
-		Owner:    authPayload.Username,
-		Currency: req.Currency,
-		Balance:  0,
-	}
-	account, err := server.store.CreateAccount(ctx, arg)
+	account, err := server.bank.CreateAccount(ctx, authPayload.Username, req.Currency)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			// FK_Constraint: User for this account does not exist
-			case "foreign_key_violation",
-				// Unique Constraint: User for this account already has an account with this currency
-				"unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-			}
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeServiceError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, account)
@@ -52,48 +34,53 @@ type getAccountRequest struct {
 func (server *Server) GetAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
-		return
-	}
-	account, err := server.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeBindingError(ctx, err)
 		return
 	}
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
-		err := errors.New("account does not belong to authenticated user")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+	account, err := server.bank.GetAccount(ctx, service.NewActor(authPayload), req.ID)
+	if err != nil {
+		writeServiceError(ctx, err)
 		return
 	}
 	ctx.JSON(http.StatusOK, account)
 }
 
-type listAccountsRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
-}
-
 func (server *Server) ListAccounts(ctx *gin.Context) {
-	var req listAccountsRequest
+	var req pageRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		writeBindingError(ctx, err)
+		return
+	}
+	isOffset, err := req.isOffset()
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
 		return
 	}
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	if isOffset {
+		markDeprecated(ctx)
+		accounts, err := server.bank.ListAccountsByOffset(ctx, authPayload.Username, req.PageSize, req.offset())
+		if err != nil {
+			writeServiceError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, accounts)
+		return
 	}
-	accounts, err := server.store.ListAccounts(ctx, arg)
+	page, err := req.keyset()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, accounts)
+	accounts, hasMore, err := server.bank.ListAccounts(ctx, authPayload.Username, page.servicePage())
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ids := make([]int64, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+	ctx.JSON(http.StatusOK, page.response(accounts, ids, hasMore))
 }
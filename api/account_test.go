@@ -34,7 +34,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "OK",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mock.MockStore) {
 				// Expecting api.GetAccount to call (once) the mocked Store.GetAccount() with a specific id
@@ -54,7 +54,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "UnauthorizedUser",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "unauthorized_user", time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mock.MockStore) {
 				store.EXPECT().
@@ -63,7 +63,24 @@ func TestGetAccountAPI(t *testing.T) {
 					Return(account, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "Admin",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
 		{
@@ -85,7 +102,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "Not Found",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mock.MockStore) {
 				store.EXPECT().
@@ -101,7 +118,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "Internal Error",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mock.MockStore) {
 				store.EXPECT().
@@ -117,7 +134,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "Invalid ID_Bad Request",
 			accountID: 0, // Account handler should invalidate the request as min accepted ID value is 1
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mock.MockStore) {
 				store.EXPECT().
@@ -146,7 +163,7 @@ func TestGetAccountAPI(t *testing.T) {
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
-			testCase.setupAuth(t, request, server.tokenMaker)
+			testCase.setupAuth(t, request, testTokenMaker(t, server.config))
 
 			// Send request to the server.router to serve
 			server.router.ServeHTTP(recorder, request)
@@ -157,6 +174,133 @@ func TestGetAccountAPI(t *testing.T) {
 	}
 }
 
+func TestListAccountsAPI(t *testing.T) {
+	user, _ := randomUser()
+	n := 3
+	accounts := make([]db.Account, n)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].ID = int64(i + 1)
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "FirstPage",
+			query: "page_size=2",
+			buildStubs: func(store *mock.MockStore) {
+				arg := db.ListAccountsAfterParams{
+					Owner:   user.Username,
+					Limit:   3,
+					AfterID: 0,
+				}
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyMatchAccountsPage(t, recorder.Body, accounts[:2])
+				requireCursor(t, accounts[1].ID, resp.NextCursor)
+				require.Empty(t, resp.PrevCursor)
+			},
+		},
+		{
+			name:  "Before",
+			query: "page_size=2&before=" + encodeCursor(accounts[2].ID),
+			buildStubs: func(store *mock.MockStore) {
+				arg := db.ListAccountsBeforeParams{
+					Owner:    user.Username,
+					Limit:    3,
+					BeforeID: accounts[2].ID,
+				}
+				// Rows come back in descending order
+				store.EXPECT().
+					ListAccountsBefore(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Account{accounts[1], accounts[0]}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyMatchAccountsPage(t, recorder.Body, accounts[:2])
+				requireCursor(t, accounts[1].ID, resp.NextCursor)
+				require.Empty(t, resp.PrevCursor)
+			},
+		},
+		{
+			name:  "DeprecatedOffset",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mock.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get("Deprecation"))
+
+				var gotAccounts []db.Account
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &gotAccounts))
+				require.Equal(t, accounts, gotAccounts)
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			query: "after=invalid",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "BothCursors",
+			query: "after=" + encodeCursor(1) + "&before=" + encodeCursor(3),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "PageSizeTooLarge",
+			query: "page_size=101",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/accounts?"+testCase.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
 func randomAccount(owner string) db.Account {
 	return db.Account{
 		ID:       util.RandomInt(1, 1000),
@@ -176,3 +320,13 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	require.NoError(t, err)
 	require.Equal(t, account, gotAccount)
 }
+
+// requireBodyMatchAccountsPage asserts the response's page of accounts against the input accounts
+func requireBodyMatchAccountsPage(t *testing.T, body *bytes.Buffer, accounts []db.Account) pageResponse {
+	var gotAccounts []db.Account
+	resp := pageResponse{Data: &gotAccounts}
+	err := json.Unmarshal(body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.Equal(t, accounts, gotAccounts)
+	return resp
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type revokeUserSessionsRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// revokeUserSessions logs a user out everywhere, invalidating all of their tokens and sessions
+func (server *Server) revokeUserSessions(ctx *gin.Context) {
+	var req revokeUserSessionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if err := server.bank.RevokeUserSessions(ctx, req.Username); err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type listUsersRequest struct {
+	PageSize int32  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	After    string `form:"after"`
+}
+
+// listUsers pages through all users by username
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if req.PageSize == 0 {
+		req.PageSize = defaultPageSize
+	}
+	var after string
+	if len(req.After) > 0 {
+		var err error
+		if after, err = decodeUserCursor(req.After); err != nil {
+			writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+			return
+		}
+	}
+	users, hasMore, err := server.bank.ListUsers(ctx, after, req.PageSize)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	data := make([]userResponse, len(users))
+	for i, user := range users {
+		data[i] = newUserResponse(user)
+	}
+	resp := pageResponse{Data: data}
+	if hasMore {
+		resp.NextCursor = encodeUserCursor(users[len(users)-1].Username)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+// updateUserRole changes the role of a user, which applies from their next login
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri revokeUserSessionsRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	user, err := server.bank.UpdateUserRole(ctx, uri.Username, req.Role)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// freezeAccount stops an account from sending or receiving money
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.setAccountFrozen(ctx, true)
+}
+
+// unfreezeAccount lifts the freeze of an account
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.setAccountFrozen(ctx, false)
+}
+
+func (server *Server) setAccountFrozen(ctx *gin.Context, frozen bool) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	account, err := server.bank.FreezeAccount(ctx, req.ID, frozen)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, account)
+}
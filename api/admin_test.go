@@ -0,0 +1,367 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeUserSessionsAPI(t *testing.T) {
+	admin, _ := randomUser()
+	admin.Role = util.AdminRole
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenmaker token.Maker)
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).Times(1)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+
+				require.True(t, isRevoked(t, server, userToken))
+			},
+		},
+		{
+			name: "NotAdmin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+				require.False(t, isRevoked(t, server, userToken))
+			},
+		},
+		{
+			name: "UserNotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			userToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/admin/users/%s/revoke_sessions", user.Username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, testTokenMaker(t, server.config))
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, server, recorder, userToken)
+		})
+	}
+}
+
+func TestListUsersAPI(t *testing.T) {
+	admin, _ := randomUser()
+	admin.Role = util.AdminRole
+	users := make([]db.User, 3)
+	for i := range users {
+		users[i], _ = randomUser()
+	}
+
+	testCases := []struct {
+		name          string
+		role          string
+		query         string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "FirstPage",
+			role:  util.AdminRole,
+			query: "page_size=2",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Eq(db.ListUsersParams{Limit: 3})).
+					Times(1).
+					Return(users, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp struct {
+					Data       []userResponse `json:"data"`
+					NextCursor string         `json:"next_cursor"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Len(t, resp.Data, 2)
+				require.Equal(t, users[0].Username, resp.Data[0].Username)
+				require.Equal(t, encodeUserCursor(users[1].Username), resp.NextCursor)
+			},
+		},
+		{
+			name:  "After",
+			role:  util.AdminRole,
+			query: "after=" + encodeUserCursor(users[1].Username),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Eq(db.ListUsersParams{Limit: defaultPageSize + 1, AfterUsername: users[1].Username})).
+					Times(1).
+					Return(users[2:], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "next_cursor")
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			role:  util.AdminRole,
+			query: "after=invalid",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Teller",
+			role: util.TellerRole,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/admin/users?"+testCase.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, admin.Username, testCase.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateUserRoleAPI(t *testing.T) {
+	admin, _ := randomUser()
+	admin.Role = util.AdminRole
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		body          string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string)
+	}{
+		{
+			name: "OK",
+			body: `{"role": "teller"}`,
+			buildStubs: func(store *mock.MockStore) {
+				updatedUser := user
+				updatedUser.Role = util.TellerRole
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{Username: user.Username, Role: util.TellerRole})).
+					Times(1).
+					Return(updatedUser, nil)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).Times(1)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, util.TellerRole, resp.Role)
+
+				// Tokens issued with the old role no longer work
+				require.True(t, isRevoked(t, server, userToken))
+			},
+		},
+		{
+			name: "UnsupportedRole",
+			body: `{"role": "owner"}`,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: `{"role": "admin"}`,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, userToken string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			userToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/admin/users/%s/role", user.Username)
+			request, err := http.NewRequest(http.MethodPut, url, strings.NewReader(testCase.body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, server, recorder, userToken)
+		})
+	}
+}
+
+func TestFreezeAccountAPI(t *testing.T) {
+	admin, _ := randomUser()
+	admin.Role = util.AdminRole
+	account := randomAccount(util.RandomName())
+
+	testCases := []struct {
+		name          string
+		action        string
+		role          string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Freeze",
+			action: "freeze",
+			role:   util.AdminRole,
+			buildStubs: func(store *mock.MockStore) {
+				frozenAccount := account
+				frozenAccount.IsFrozen = true
+				store.EXPECT().
+					UpdateAccountFrozen(gomock.Any(), gomock.Eq(db.UpdateAccountFrozenParams{ID: account.ID, IsFrozen: true})).
+					Times(1).
+					Return(frozenAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotAccount db.Account
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &gotAccount))
+				require.True(t, gotAccount.IsFrozen)
+			},
+		},
+		{
+			name:   "Unfreeze",
+			action: "unfreeze",
+			role:   util.AdminRole,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateAccountFrozen(gomock.Any(), gomock.Eq(db.UpdateAccountFrozenParams{ID: account.ID, IsFrozen: false})).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "NotFound",
+			action: "freeze",
+			role:   util.AdminRole,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateAccountFrozen(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Customer",
+			action: "freeze",
+			role:   util.CustomerRole,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().UpdateAccountFrozen(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/admin/accounts/%d/%s", account.ID, testCase.action)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, admin.Username, testCase.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
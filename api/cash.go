@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+)
+
+type cashRequest struct {
+	Amount            int64  `json:"amount" binding:"required,gt=0"`
+	Currency          string `json:"currency" binding:"required,currency"`
+	ExternalReference string `json:"external_reference" binding:"required,max=255"`
+	Memo              string `json:"memo" binding:"max=140"`
+}
+
+// createDeposit credits cash paid in at the counter to an account
+func (server *Server) createDeposit(ctx *gin.Context) {
+	createCashEntry(ctx, server.bank.Deposit)
+}
+
+// createWithdrawal debits cash paid out at the counter from an account
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	createCashEntry(ctx, server.bank.Withdraw)
+}
+
+func createCashEntry(ctx *gin.Context, cashTx func(context.Context, service.CashParams) (db.CashTxResult, error)) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req cashRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	result, err := cashTx(ctx, service.CashParams{
+		AccountID:         uri.ID,
+		Amount:            req.Amount,
+		Currency:          req.Currency,
+		ExternalReference: req.ExternalReference,
+		Memo:              req.Memo,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCashEntryAPI(t *testing.T) {
+	account := randomAccount(util.RandomName())
+	account.Currency = util.USD
+	reference := util.RandomString(12)
+	amount := int64(40)
+
+	testCases := []struct {
+		name          string
+		path          string
+		role          string
+		body          gin.H
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Deposit",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Eq(db.CashTxParams{
+						AccountID:         account.ID,
+						Amount:            amount,
+						ExternalReference: reference,
+					})).
+					Times(1).
+					Return(db.CashTxResult{
+						Account: account,
+						Entry:   db.Entry{AccountID: account.ID, Amount: amount, Type: db.EntryTypeDeposit},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.CashTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+				require.Equal(t, db.EntryTypeDeposit, result.Entry.Type)
+				require.Equal(t, amount, result.Entry.Amount)
+			},
+		},
+		{
+			name: "Withdrawal",
+			path: "withdrawals",
+			role: util.AdminRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Eq(db.CashTxParams{
+						AccountID:         account.ID,
+						Amount:            amount,
+						ExternalReference: reference,
+					})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Customer",
+			path: "deposits",
+			role: util.CustomerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "MissingReference",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.EUR, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FrozenAccount",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				frozenAccount := account
+				frozenAccount.IsFrozen = true
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateReference",
+			path: "deposits",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CashTxResult{}, fmt.Errorf("tx error: %w", &pq.Error{Code: "23505"}))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			path: "withdrawals",
+			role: util.TellerRole,
+			body: gin.H{"amount": amount, "currency": util.USD, "external_reference": reference},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CashTxResult{}, fmt.Errorf("tx error: %w", &db.ErrInsufficientFunds{
+						AccountID: account.ID,
+						Available: amount - 1,
+						Amount:    amount,
+					}))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var resp errorResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, codeInsufficientFunds, resp.Error.Code)
+				require.Equal(t, amount-1, *resp.Error.AvailableBalance)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			url := fmt.Sprintf("/accounts/%d/%s", account.ID, testCase.path)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, util.RandomName(), testCase.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
+)
+
+func (server *Server) ListEntries(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	isOffset, err := req.isOffset()
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+		return
+	}
+	page, err := req.keyset()
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if isOffset {
+		entries, err := server.bank.ListEntriesByOffset(ctx, service.NewActor(authPayload), uri.ID, req.PageSize, req.offset())
+		if err != nil {
+			writeServiceError(ctx, err)
+			return
+		}
+		markDeprecated(ctx)
+		ctx.JSON(http.StatusOK, entries)
+		return
+	}
+	entries, hasMore, err := server.bank.ListEntries(ctx, service.NewActor(authPayload), uri.ID, page.servicePage())
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ids := make([]int64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	ctx.JSON(http.StatusOK, page.response(entries, ids, hasMore))
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/throttle"
+)
+
+// errorCode is a stable, machine-readable identifier of an API error. Clients should branch on it
+// rather than on the message, which is meant for humans and may change.
+type errorCode string
+
+// The codes of domain errors are the names of the service.ErrorKind they come from
+const (
+	codeInvalidArgument    errorCode = "invalid_argument"
+	codeValidationFailed   errorCode = "validation_failed"
+	codeUnauthenticated    errorCode = "unauthenticated"
+	codeForbidden          errorCode = "forbidden"
+	codeNotFound           errorCode = "not_found"
+	codeConflict           errorCode = "conflict"
+	codeCurrencyMismatch   errorCode = "currency_mismatch"
+	codeInsufficientFunds  errorCode = "insufficient_funds"
+	codeFailedPrecondition errorCode = "failed_precondition"
+	codeTooManyRequests    errorCode = "too_many_requests"
+	codeUnavailable        errorCode = "unavailable"
+	codeInternal           errorCode = "internal"
+)
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code      errorCode        `json:"code"`
+	Message   string           `json:"message"`
+	Details   []fieldViolation `json:"details,omitempty"`
+	RequestID string           `json:"request_id,omitempty"`
+	// Set for insufficient_funds
+	AvailableBalance *int64 `json:"available_balance,omitempty"`
+}
+
+// fieldViolation describes why a single request field was rejected
+type fieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// writeError aborts the request with an error response. err is recorded on the context for the request
+// log; for server errors the client only gets the status text, as the cause may expose internals.
+func writeError(ctx *gin.Context, status int, code errorCode, err error) {
+	writeAPIError(ctx, status, apiError{Code: code, Message: err.Error()}, err)
+}
+
+func writeAPIError(ctx *gin.Context, status int, resp apiError, err error) {
+	ctx.Error(err)
+	if status >= http.StatusInternalServerError {
+		resp.Message = strings.ToLower(http.StatusText(status))
+	}
+	resp.RequestID = ctx.GetString(requestIDKey)
+	ctx.AbortWithStatusJSON(status, errorResponse{Error: resp})
+}
+
+// writeBindingError answers a request that couldn't be bound, listing the offending fields when possible
+func writeBindingError(ctx *gin.Context, err error) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+		numErr         *strconv.NumError
+		timeErr        *time.ParseError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		resp := apiError{Code: codeValidationFailed, Message: "request validation failed"}
+		for _, fieldErr := range validationErrs {
+			resp.Details = append(resp.Details, fieldViolation{
+				Field:       fieldErr.Field(),
+				Description: describeFieldError(fieldErr),
+			})
+		}
+		writeAPIError(ctx, http.StatusBadRequest, resp, err)
+	case errors.As(err, &typeErr):
+		writeAPIError(ctx, http.StatusBadRequest, apiError{
+			Code:    codeValidationFailed,
+			Message: "request validation failed",
+			Details: []fieldViolation{{
+				Field:       typeErr.Field,
+				Description: fmt.Sprintf("must be of type %s", typeErr.Type),
+			}},
+		}, err)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, errors.New("request body is not valid JSON"))
+	case errors.Is(err, io.EOF):
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, errors.New("request body is empty"))
+	case errors.As(err, &timeErr):
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, fmt.Errorf("%q is not a valid date", timeErr.Value))
+	case errors.As(err, &numErr):
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, fmt.Errorf("%q is not a valid number", numErr.Num))
+	default:
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+	}
+}
+
+// describeFieldError translates a failed validator.v10 tag into a sentence about the field
+func describeFieldError(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		}
+		return fmt.Sprintf("must be at least %s", param)
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", param)
+		}
+		return fmt.Sprintf("must be at most %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", param)
+	case "gtefield":
+		// As for excluded_with, the param is the Go name of the other field
+		return fmt.Sprintf("must not be before %s", strings.ToLower(param))
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(param, " ", ", "))
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "numeric":
+		return "must contain only digits"
+	case "url":
+		return "must be a valid URL"
+	case "currency":
+		return "must be a supported currency"
+	case "role":
+		return "must be one of: customer, teller, admin"
+	case "excluded_with":
+		// The param is the Go name of the other field, the single word fields using it match their tags
+		return fmt.Sprintf("must not be set together with %s", strings.ToLower(param))
+	}
+	return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+}
+
+// requestFieldName names the fields of request structs in validation errors as clients send them
+func requestFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(key), ",", 2)[0]
+		if len(name) > 0 && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// writeServiceError answers a request with an error returned by service.Bank
+func writeServiceError(ctx *gin.Context, err error) {
+	resp := apiError{
+		Code:    errorCode(service.KindOf(err).String()),
+		Message: err.Error(),
+	}
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) && len(serviceErr.Message) > 0 {
+		// Leave the cause out, it's only for the request log
+		resp.Message = serviceErr.Message
+	}
+	var fundsErr *db.ErrInsufficientFunds
+	if errors.As(err, &fundsErr) {
+		resp.Message = fundsErr.Error()
+		resp.AvailableBalance = &fundsErr.Available
+	}
+	var throttledErr *throttle.ErrThrottled
+	if errors.As(err, &throttledErr) {
+		ctx.Header("Retry-After", strconv.Itoa(int(throttledErr.RetryAfter/time.Second)))
+	}
+	writeAPIError(ctx, httpStatus(err), resp, err)
+}
+
+// httpStatus maps the kind of a service error to an HTTP status code
+func httpStatus(err error) int {
+	switch service.KindOf(err) {
+	case service.InvalidArgument, service.CurrencyMismatch:
+		return http.StatusBadRequest
+	case service.Unauthenticated:
+		return http.StatusUnauthorized
+	case service.Forbidden:
+		return http.StatusForbidden
+	case service.NotFound:
+		return http.StatusNotFound
+	case service.Conflict:
+		return http.StatusConflict
+	case service.InsufficientFunds, service.FailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case service.TooManyRequests:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// notFound answers requests that matched no route
+func notFound(ctx *gin.Context) {
+	writeError(ctx, http.StatusNotFound, codeNotFound, fmt.Errorf("no route for %s %s", ctx.Request.Method, ctx.Request.URL.Path))
+}
+
+// recovered answers a request whose handler panicked
+func recovered(ctx *gin.Context, recovered interface{}) {
+	writeError(ctx, http.StatusInternalServerError, codeInternal, fmt.Errorf("panic: %v", recovered))
+}
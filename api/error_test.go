@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponse(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError)
+	}{
+		{
+			name:   "ValidationFailed",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body:   `{"from_account_id": 1, "amount": -5, "currency": "XXX"}`,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, codeValidationFailed, resp.Code)
+				require.ElementsMatch(t, []fieldViolation{
+					{Field: "to_account_id", Description: "is required"},
+					{Field: "amount", Description: "must be greater than 0"},
+					{Field: "currency", Description: "must be a supported currency"},
+				}, resp.Details)
+			},
+		},
+		{
+			name:   "WrongType",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   `{"currency": 1}`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, codeValidationFailed, resp.Code)
+				require.Equal(t, []fieldViolation{{Field: "currency", Description: "must be of type string"}}, resp.Details)
+			},
+		},
+		{
+			name:   "MalformedJSON",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   `{"currency":`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, codeInvalidArgument, resp.Code)
+				require.Equal(t, "request body is not valid JSON", resp.Message)
+			},
+		},
+		{
+			name:   "InvalidURIParam",
+			method: http.MethodGet,
+			url:    "/accounts/abc",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, codeInvalidArgument, resp.Code)
+				require.Equal(t, `"abc" is not a valid number`, resp.Message)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, codeNotFound, resp.Code)
+				require.Equal(t, fmt.Sprintf("account [%d] not found", account.ID), resp.Message)
+			},
+		},
+		{
+			name:   "InternalErrorIsMasked",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   fmt.Sprintf(`{"currency": %q}`, util.USD),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23514", Message: "new row violates check constraint"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, codeInternal, resp.Code)
+				require.Equal(t, "internal server error", resp.Message)
+				require.NotContains(t, recorder.Body.String(), "constraint")
+			},
+		},
+		{
+			name:   "UnknownRoute",
+			method: http.MethodGet,
+			url:    "/unknown",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, resp apiError) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, codeNotFound, resp.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			if testCase.buildStubs != nil {
+				testCase.buildStubs(store)
+			}
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(testCase.method, testCase.url, bytes.NewReader([]byte(testCase.body)))
+			require.NoError(t, err)
+			request.Header.Set(requestIDHeaderKey, "error-request-1")
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			var resp errorResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+			require.Equal(t, "error-request-1", resp.Error.RequestID)
+			testCase.checkResponse(t, recorder, resp.Error)
+		})
+	}
+}
+
+func TestErrorResponseRecoversPanic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mock.NewMockStore(ctrl))
+	server.router.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/panic", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, codeInternal, resp.Error.Code)
+	require.NotEmpty(t, resp.Error.RequestID)
+	require.NotContains(t, recorder.Body.String(), "boom")
+}
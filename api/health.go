@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds the database ping of the readiness probe
+const readinessTimeout = 2 * time.Second
+
+// healthz reports the process is up
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports whether the server can take traffic, i.e. the database is reachable
+func (server *Server) readyz(ctx *gin.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+	if err := server.store.Ping(pingCtx); err != nil {
+		writeError(ctx, http.StatusServiceUnavailable, codeUnavailable, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+)
+
+const (
+	idempotencyKeyHeaderKey      = "Idempotency-Key"
+	idempotencyReplayedHeaderKey = "Idempotent-Replayed"
+	maxIdempotencyKeyLength      = 255
+	// idempotencyWriteTimeout bounds storing the response against a key, or releasing it, once the request is over
+	idempotencyWriteTimeout = 5 * time.Second
+	// idempotencyEvictionInterval is the minimum time between two sweeps of expired keys
+	idempotencyEvictionInterval = time.Minute
+)
+
+// bodyRecorder keeps a copy of the response body so it can be stored against the idempotency key
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyMiddleware replays the stored response for a repeated Idempotency-Key header.
+// Must run after authMiddleware, as keys are scoped to the authenticated user.
+func idempotencyMiddleware(store db.Store, ttl time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	lastEviction := time.Now()
+	evictExpired := func(ctx context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(lastEviction) < idempotencyEvictionInterval {
+			return
+		}
+		if err := store.DeleteExpiredIdempotencyKeys(ctx); err != nil {
+			util.LoggerFromContext(ctx).Error().Err(err).Msg("failed to delete expired idempotency keys")
+			return
+		}
+		lastEviction = now
+	}
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeaderKey)
+		if len(key) == 0 {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			err := errors.New("idempotency key is too long")
+			writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+			return
+		}
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+			return
+		}
+		// Restore the body for the handler
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		requestHash := hashRequest(ctx.Request.Method, ctx.Request.URL.Path, body)
+
+		_, err = store.CreateIdempotencyKey(ctx, db.CreateIdempotencyKeyParams{
+			Key:         key,
+			Username:    authPayload.Username,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// A live key already exists for this user
+				replayIdempotentResponse(ctx, store, authPayload.Username, key, requestHash)
+				return
+			}
+			writeError(ctx, http.StatusInternalServerError, codeInternal, err)
+			return
+		}
+		evictExpired(ctx)
+
+		// The request context is cancelled once the client goes away, which mustn't leave the key in progress
+		// until it expires, so the key is written on a context of its own
+		logger := util.LoggerFromContext(ctx)
+		writeCtx := func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(util.ContextWithLogger(context.Background(), *logger), idempotencyWriteTimeout)
+		}
+		recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		answered := false
+		defer func() {
+			if answered {
+				return
+			}
+			// Release the key so the client can retry the failed request, also when the handler panicked
+			releaseCtx, cancel := writeCtx()
+			defer cancel()
+			if err := store.DeleteIdempotencyKey(releaseCtx, db.DeleteIdempotencyKeyParams{
+				Username: authPayload.Username,
+				Key:      key,
+			}); err != nil {
+				logger.Error().Err(err).Msg("failed to release idempotency key")
+			}
+		}()
+		ctx.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		// From here the request took effect, so a retry mustn't run it again even if the response isn't stored
+		answered = true
+		updateCtx, cancel := writeCtx()
+		defer cancel()
+		if err := store.UpdateIdempotencyKeyResponse(updateCtx, db.UpdateIdempotencyKeyResponseParams{
+			Username:       authPayload.Username,
+			Key:            key,
+			ResponseStatus: int32(recorder.Status()),
+			ResponseBody:   recorder.body.Bytes(),
+		}); err != nil {
+			logger.Error().Err(err).Msg("failed to store idempotent response")
+		}
+	}
+}
+
+// replayIdempotentResponse writes the stored response of an existing key, if the request matches it
+func replayIdempotentResponse(ctx *gin.Context, store db.Store, username, key, requestHash string) {
+	idempotencyKey, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: username,
+		Key:      key,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, codeInternal, err)
+		return
+	}
+	if idempotencyKey.RequestHash != requestHash {
+		err := errors.New("idempotency key was already used with a different request")
+		writeError(ctx, http.StatusConflict, codeConflict, err)
+		return
+	}
+	if idempotencyKey.ResponseStatus == 0 {
+		err := errors.New("a request with this idempotency key is still in progress")
+		writeError(ctx, http.StatusConflict, codeConflict, err)
+		return
+	}
+	ctx.Header(idempotencyReplayedHeaderKey, "true")
+	ctx.Data(int(idempotencyKey.ResponseStatus), gin.MIMEJSON, idempotencyKey.ResponseBody)
+	ctx.Abort()
+}
+
+// hashRequest returns a hex encoded sha256 digest identifying a request
+func hashRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	user1, _ := randomUser()
+	user2, _ := randomUser()
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	key := util.RandomString(16)
+	body, err := json.Marshal(gin.H{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          10,
+		"currency":        util.USD,
+	})
+	require.NoError(t, err)
+	requestHash := hashRequest(http.MethodPost, "/transfers", body)
+	storedResponse := []byte(`{"transfer":{"id":1}}`)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "NewKey",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{Key: key, Username: user1.Username, RequestHash: requestHash}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(user1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateIdempotencyKeyResponseParams) error {
+						require.Equal(t, key, arg.Key)
+						require.Equal(t, user1.Username, arg.Username)
+						require.Equal(t, int32(http.StatusOK), arg.ResponseStatus)
+						require.NotEmpty(t, arg.ResponseBody)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotencyReplayedHeaderKey))
+			},
+		},
+		{
+			name: "Replay",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user1.Username, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Key:            key,
+						Username:       user1.Username,
+						RequestHash:    requestHash,
+						ResponseStatus: http.StatusOK,
+						ResponseBody:   storedResponse,
+					}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotencyReplayedHeaderKey))
+				require.Equal(t, storedResponse, recorder.Body.Bytes())
+			},
+		},
+		{
+			name: "DifferentRequest",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Key:            key,
+						Username:       user1.Username,
+						RequestHash:    "another request",
+						ResponseStatus: http.StatusOK,
+						ResponseBody:   storedResponse,
+					}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InProgress",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{Key: key, Username: user1.Username, RequestHash: requestHash}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "HandlerFailureReleasesKey",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{Key: key, Username: user1.Username, RequestHash: requestHash}, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(user1, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{Username: user1.Username, Key: key})).
+					Times(1)
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeaderKey, key)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareOutlivesRequest(t *testing.T) {
+	user, _ := randomUser()
+	key := util.RandomString(16)
+
+	testCases := []struct {
+		name       string
+		handler    func(ctx *gin.Context, cancel context.CancelFunc)
+		buildStubs func(store *mock.MockStore)
+	}{
+		{
+			name: "ClientGone",
+			handler: func(ctx *gin.Context, cancel context.CancelFunc) {
+				cancel()
+				ctx.JSON(http.StatusOK, gin.H{"id": 1})
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.UpdateIdempotencyKeyResponseParams) error {
+						require.NoError(t, ctx.Err())
+						_, bounded := ctx.Deadline()
+						require.True(t, bounded)
+						require.Equal(t, int32(http.StatusOK), arg.ResponseStatus)
+						return nil
+					})
+				store.EXPECT().DeleteIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "HandlerPanic",
+			handler: func(ctx *gin.Context, cancel context.CancelFunc) {
+				panic("boom")
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{Username: user.Username, Key: key})).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, _ db.DeleteIdempotencyKeyParams) error {
+						require.NoError(t, ctx.Err())
+						_, bounded := ctx.Deadline()
+						require.True(t, bounded)
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.IdempotencyKey{Key: key, Username: user.Username}, nil)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			requestCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			server.router.POST("/idempotent",
+				authMiddleware(server.bank),
+				idempotencyMiddleware(server.store, time.Minute),
+				func(ctx *gin.Context) { testCase.handler(ctx, cancel) },
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, "/idempotent", bytes.NewReader([]byte(`{}`)))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeaderKey, key)
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+		})
+	}
+}
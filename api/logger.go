@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+	requestIDKey       = "request_id"
+)
+
+// maxLoggedErrorBody is the length of an error response kept to log its message, error bodies are short JSON
+const maxLoggedErrorBody = 4 << 10
+
+// validRequestID limits which client supplied request IDs are propagated, so they are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// loggerMiddleware assigns or propagates the X-Request-ID header, stores a logger carrying it on the
+// request context and logs one line per request once it has been handled
+func loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeaderKey, requestID)
+
+		logger := log.Logger.With().Str(requestIDKey, requestID).Logger()
+		// Handlers pass *gin.Context to the store, which resolves the logger with Get
+		ctx.Set(util.GinLoggerKey, &logger)
+		ctx.Request = ctx.Request.WithContext(util.ContextWithLogger(ctx.Request.Context(), logger))
+
+		recorder := &errorBodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		event := logger.Info()
+		if status >= 500 {
+			event = logger.Error()
+		} else if status >= 400 {
+			event = logger.Warn()
+		}
+		event.
+			Str("method", ctx.Request.Method).
+			Str("route", ctx.FullPath()).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", ctx.ClientIP())
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			event.Str("username", payload.(*token.Payload).Username)
+		}
+		if status >= 400 {
+			event.Str("error", responseError(ctx, recorder.body.Bytes()))
+		}
+		event.Msg("request")
+	}
+}
+
+// responseError returns the error reported by the handler, either through ctx.Error or in the response body
+func responseError(ctx *gin.Context, body []byte) string {
+	if len(ctx.Errors) > 0 {
+		return strings.Join(ctx.Errors.Errors(), "; ")
+	}
+	var resp errorResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	return resp.Error.Message
+}
+
+// errorBodyRecorder keeps the start of an error response, for its message to be logged. Successful responses,
+// which can be streamed statements or metrics, go straight through.
+type errorBodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorBodyRecorder) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorBodyRecorder) WriteString(s string) (int, error) {
+	if w.Status() >= 400 {
+		w.record([]byte(s))
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *errorBodyRecorder) record(data []byte) {
+	if w.Status() < 400 {
+		return
+	}
+	if room := maxLoggedErrorBody - w.body.Len(); room < len(data) {
+		data = data[:room]
+	}
+	w.body.Write(data)
+}
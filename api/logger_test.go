@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerMiddleware(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		requestID     string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, line map[string]interface{})
+	}{
+		{
+			name:      "PropagatesRequestID",
+			requestID: "client-request-1",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, line map[string]interface{}) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "client-request-1", recorder.Header().Get(requestIDHeaderKey))
+				require.Equal(t, "client-request-1", line[requestIDKey])
+				require.Equal(t, http.MethodGet, line["method"])
+				require.Equal(t, "/accounts/:id", line["route"])
+				require.Equal(t, float64(http.StatusOK), line["status"])
+				require.Equal(t, user.Username, line["username"])
+				require.Equal(t, "info", line["level"])
+				require.Contains(t, line, "latency")
+				require.NotContains(t, line, "error")
+			},
+		},
+		{
+			name:      "InvalidRequestID",
+			requestID: "not a valid request id",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, line map[string]interface{}) {
+				requestID := recorder.Header().Get(requestIDHeaderKey)
+				require.Len(t, requestID, 36)
+				require.Equal(t, requestID, line[requestIDKey])
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, line map[string]interface{}) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(requestIDHeaderKey))
+				require.Equal(t, float64(http.StatusInternalServerError), line["status"])
+				require.Equal(t, "error", line["level"])
+				require.Equal(t, sql.ErrConnDone.Error(), line["error"])
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			globalLogger := log.Logger
+			log.Logger = zerolog.New(&logs)
+			defer func() { log.Logger = globalLogger }()
+
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+			require.NoError(t, err)
+			if len(testCase.requestID) > 0 {
+				request.Header.Set(requestIDHeaderKey, testCase.requestID)
+			}
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+			testCase.checkResponse(t, recorder, line)
+		})
+	}
+}
+
+func TestLoggerMiddlewareContext(t *testing.T) {
+	var logs bytes.Buffer
+	globalLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = globalLogger }()
+
+	// Handlers find the logger through *gin.Context as well as through the request context
+	router := gin.New()
+	router.GET("/log", loggerMiddleware(), func(ctx *gin.Context) {
+		util.LoggerFromContext(ctx).Info().Msg("gin")
+		util.LoggerFromContext(ctx.Request.Context()).Info().Msg("request")
+		ctx.Status(http.StatusNoContent)
+	})
+	request, err := http.NewRequest(http.MethodGet, "/log", nil)
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, "client-request-1")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines[:2] {
+		require.Contains(t, line, `"request_id":"client-request-1"`)
+	}
+}
+
+func TestErrorBodyRecorder(t *testing.T) {
+	// Successful responses aren't kept, however long
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	recorder := &errorBodyRecorder{ResponseWriter: ctx.Writer}
+	recorder.WriteHeader(http.StatusOK)
+	_, err := recorder.Write(bytes.Repeat([]byte("a"), 2*maxLoggedErrorBody))
+	require.NoError(t, err)
+	require.Zero(t, recorder.body.Len())
+
+	// Error responses are, up to a limit
+	response := httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(response)
+	recorder = &errorBodyRecorder{ResponseWriter: ctx.Writer}
+	recorder.WriteHeader(http.StatusBadRequest)
+	for i := 0; i < 3; i++ {
+		_, err = recorder.WriteString(strings.Repeat("b", maxLoggedErrorBody/2))
+		require.NoError(t, err)
+	}
+	require.Equal(t, maxLoggedErrorBody, recorder.body.Len())
+	require.Equal(t, 3*maxLoggedErrorBody/2, response.Body.Len())
+}
@@ -1,13 +1,22 @@
 package api
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
 	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
 	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,14 +24,57 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
 		TokenSymmetricKey:     util.RandomString(32),
 		ACCESS_TOKEN_DURATION: time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		IdempotencyKeyTTL:     time.Minute,
+		LoginMaxFailures:      4,
+		LoginMaxFailuresPerIP: 20,
+		LoginLockoutDuration:  time.Minute,
+		TOTPIssuer:            "Simple Bank",
+		MFATokenDuration:      time.Minute,
 	}
-	server, err := NewServer(config, store)
+	if mockStore, ok := store.(*mock.MockStore); ok {
+		// Every authenticated request checks when the password last changed, which most tests don't care for.
+		// Expectations set beforehand are matched first.
+		mockStore.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes()
+	}
+	fxProvider, err := util.NewFXProvider("", 0)
+	require.NoError(t, err)
+	server, err := NewServer(config, store, newTestBank(t, config, store, fxProvider))
 	require.NoError(t, err)
 	return server
 }
 
+// newTestBank creates the bank of a test server, keeping revoked tokens and failed logins in memory
+func newTestBank(t *testing.T, config util.Config, store db.Store, fxProvider util.FXProvider) *service.Bank {
+	revocationStore := token.NewMemoryRevocationStore(config.MaxTokenDuration())
+	loginLimiter := throttle.NewLimiter(throttle.NewMemoryStore(config.LoginLockoutDuration), throttle.Options{
+		MaxFailures:      config.LoginMaxFailures,
+		MaxFailuresPerIP: config.LoginMaxFailuresPerIP,
+		LockoutDuration:  config.LoginLockoutDuration,
+	})
+	return service.NewBank(config, store, testTokenMaker(t, config), revocationStore, fxProvider, mail.NewMailer(config), loginLimiter)
+}
+
+// testTokenMaker returns a token maker sharing the key of a test server, whose bank accepts the tokens it creates
+func testTokenMaker(t *testing.T, config util.Config) token.Maker {
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	require.NoError(t, err)
+	return tokenMaker
+}
+
+// isRevoked reports whether the bank of a test server rejects a token for having been revoked
+func isRevoked(t *testing.T, server *Server, tokenString string) bool {
+	_, err := server.bank.Authenticate(context.Background(), tokenString)
+	if err == nil {
+		return false
+	}
+	require.Contains(t, err.Error(), "revoked")
+	return true
+}
+
 func TestMain(m *testing.M) {
 	// Cleaner test output
 	gin.SetMode(gin.TestMode)
+	log.Logger = zerolog.Nop()
 	os.Exit(m.Run())
 }
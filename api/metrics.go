@@ -0,0 +1,25 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harrychopra/go-api/metrics"
+)
+
+// unmatchedRoute labels requests that match no route, so arbitrary paths don't become metric labels
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware records the latency of each request by method, route template and status
+func metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if len(route) == 0 {
+			route = unmatchedRoute
+		}
+		metrics.ObserveHTTPRequest(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(start))
+	}
+}
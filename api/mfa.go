@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
+)
+
+// mfaRequiredResponse answers the login of a user with two-factor authentication, who then posts the MFA token
+// and a code to /users/login/mfa
+type mfaRequiredResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+type loginUserMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// A TOTP code, or one of the recovery codes
+	Code string `json:"code" binding:"required"`
+}
+
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	result, err := server.bank.LoginUserMFA(ctx, service.LoginUserMFAParams{
+		MFAToken:  req.MFAToken,
+		Code:      req.Code,
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newLoginUserResponse(result))
+}
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	// For a QR code scanned by authenticator apps
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// enrollTOTP creates a TOTP secret for the user, confirmed with a code by confirmTOTP
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	enrollment, err := server.bank.EnrollTOTP(ctx, authPayload.Username)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{Secret: enrollment.Secret, OTPAuthURL: enrollment.URL})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	// Shown this once, each logs in once in place of a TOTP code
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor authentication with a code of the enrolled secret
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	recoveryCodes, err := server.bank.ConfirmTOTP(ctx, authPayload.Username, req.Code)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: recoveryCodes})
+}
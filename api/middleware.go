@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/harrychopra/go-api/service"
 	"github.com/harrychopra/go-api/token"
 )
 
@@ -16,33 +17,49 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenmaker token.Maker) gin.HandlerFunc {
+// authMiddleware lets requests with a valid access token through, as authenticated by the bank
+func authMiddleware(bank *service.Bank) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			writeError(ctx, http.StatusUnauthorized, codeUnauthenticated, err)
 			return
 		}
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			writeError(ctx, http.StatusUnauthorized, codeUnauthenticated, err)
 			return
 		}
 		authorizationnType := strings.ToLower(fields[0])
 		if authorizationnType != authorizationTypeBearer {
 			err := fmt.Errorf("unsupported authorization type")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			writeError(ctx, http.StatusUnauthorized, codeUnauthenticated, err)
 			return
 		}
-		accessToken := fields[1]
-		payload, err := tokenmaker.VerifyToken(accessToken)
+		payload, err := bank.Authenticate(ctx, fields[1])
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			writeServiceError(ctx, err)
 			return
 		}
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next() // Forward the request to next handler
 	}
 }
+
+// authorize only lets users with one of roles through, declaring who may use a route group.
+// Must run after authMiddleware.
+func authorize(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if role == authPayload.Role {
+				ctx.Next()
+				return
+			}
+		}
+		err := fmt.Errorf("role %q is not allowed, requires one of: %s", authPayload.Role, strings.Join(roles, ", "))
+		writeError(ctx, http.StatusForbidden, codeForbidden, err)
+	}
+}
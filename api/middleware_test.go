@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,7 +10,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
 	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,7 +26,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "test user", time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "test user", util.CustomerRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -38,7 +43,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnsupportedAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, "unsupported", "test user", time.Minute)
+				addAuthorization(t, request, tokenmaker, "unsupported", "test user", util.CustomerRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -47,7 +52,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthorizationformat",
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, "", "test user", time.Minute)
+				addAuthorization(t, request, tokenmaker, "", "test user", util.CustomerRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -56,7 +61,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenmaker token.Maker) {
-				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "test user", -time.Minute)
+				addAuthorization(t, request, tokenmaker, authorizationTypeBearer, "test user", util.CustomerRole, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -66,9 +71,9 @@ func TestAuthMiddleware(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			server := newTestServer(t, mock.NewMockStore(gomock.NewController(t)))
 			authPath := "/auth"
-			server.router.GET(authPath, authMiddleware(server.tokenMaker), func(ctx *gin.Context) {
+			server.router.GET(authPath, authMiddleware(server.bank), func(ctx *gin.Context) {
 				ctx.JSON(http.StatusOK, gin.H{})
 			})
 
@@ -76,7 +81,99 @@ func TestAuthMiddleware(t *testing.T) {
 			request, err := http.NewRequest(http.MethodGet, authPath, nil)
 			require.NoError(t, err)
 
-			testCase.setupAuth(t, request, server.tokenMaker)
+			testCase.setupAuth(t, request, testTokenMaker(t, server.config))
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareRevokedToken(t *testing.T) {
+	server := newTestServer(t, mock.NewMockStore(gomock.NewController(t)))
+	authPath := "/auth"
+	server.router.GET(authPath, authMiddleware(server.bank), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	accessToken, payload, err := testTokenMaker(t, server.config).CreateToken("test user", util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, server.bank.LogoutUser(context.Background(), payload, ""))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, authPath, nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAuthMiddlewarePasswordChanged(t *testing.T) {
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ChangedBefore",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("test user")).Times(1).
+					Return(time.Now().Add(-time.Minute), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ChangedAfter",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("test user")).Times(1).
+					// The token is issued right after, within the minute
+					Return(time.Now().Add(time.Minute), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UserDeleted",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("test user")).Times(1).
+					Return(time.Time{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("test user")).Times(1).
+					Return(time.Time{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store := mock.NewMockStore(gomock.NewController(t))
+			testCase.buildStubs(store)
+			server := newTestServer(t, store)
+			accessToken, _, err := testTokenMaker(t, server.config).CreateToken("test user", util.CustomerRole, time.Minute)
+			require.NoError(t, err)
+
+			authPath := "/auth"
+			server.router.GET(authPath, authMiddleware(server.bank), func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
 			server.router.ServeHTTP(recorder, request)
 			testCase.checkResponse(t, recorder)
 		})
@@ -89,10 +186,12 @@ func addAuthorization(
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	token, err := tokenMaker.CreateToken(username, duration)
+	token, payload, err := tokenMaker.CreateToken(username, role, duration)
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/harrychopra/go-api/service"
+)
+
+const (
+	defaultPageSize = 20
+	// Limits of the deprecated offset pagination
+	minOffsetPageSize = 5
+	maxOffsetPageSize = 10
+)
+
+// pageRequest holds the pagination query parameters shared by list endpoints.
+// Cursor pagination is used unless page_id is set.
+type pageRequest struct {
+	// Deprecated: offset pagination, use the After and Before cursors instead
+	PageID   int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize int32  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	After    string `form:"after" binding:"excluded_with=Before"`
+	Before   string `form:"before"`
+}
+
+// pageResponse is the envelope of a cursor paginated list
+type pageResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+}
+
+// pageCursor is the decoded form of the opaque cursors handed to clients
+type pageCursor struct {
+	ID       int64  `json:"id,omitempty"`
+	Username string `json:"username,omitempty"` // Lists of users are sorted by username instead
+}
+
+// keysetPage is a validated cursor page request
+type keysetPage struct {
+	before bool  // page backwards from cursorID
+	id     int64 // Exclusive bound on the row ids
+	size   int32
+}
+
+// isOffset checks if the request uses the deprecated offset pagination, validating its page size
+func (req pageRequest) isOffset() (bool, error) {
+	if req.PageID == 0 {
+		return false, nil
+	}
+	if req.PageSize < minOffsetPageSize || req.PageSize > maxOffsetPageSize {
+		return true, errors.New("page_size must be between 5 and 10 with page_id")
+	}
+	return true, nil
+}
+
+// offset returns the number of rows to skip in the deprecated offset pagination
+func (req pageRequest) offset() int32 {
+	return (req.PageID - 1) * req.PageSize
+}
+
+// keyset decodes the cursors of the request
+func (req pageRequest) keyset() (keysetPage, error) {
+	page := keysetPage{size: req.PageSize}
+	if page.size == 0 {
+		page.size = defaultPageSize
+	}
+	var err error
+	switch {
+	case len(req.Before) > 0:
+		page.before = true
+		page.id, err = decodeCursor(req.Before)
+	case len(req.After) > 0:
+		page.id, err = decodeCursor(req.After)
+	}
+	return page, err
+}
+
+// servicePage converts the page for the list methods of service.Bank
+func (page keysetPage) servicePage() service.Page {
+	if page.before {
+		return service.Page{BeforeID: page.id, Size: page.size}
+	}
+	return service.Page{AfterID: page.id, Size: page.size}
+}
+
+// response wraps a page of rows, sorted by ascending id, in a pageResponse.
+// ids are the ids of the rows in data, hasMore tells if rows are left in the page direction.
+func (page keysetPage) response(data interface{}, ids []int64, hasMore bool) pageResponse {
+	resp := pageResponse{Data: data}
+	if len(ids) == 0 {
+		return resp
+	}
+	first, last := ids[0], ids[len(ids)-1]
+	if page.before {
+		resp.NextCursor = encodeCursor(last)
+		if hasMore {
+			resp.PrevCursor = encodeCursor(first)
+		}
+		return resp
+	}
+	if hasMore {
+		resp.NextCursor = encodeCursor(last)
+	}
+	if page.id > 0 {
+		resp.PrevCursor = encodeCursor(first)
+	}
+	return resp
+}
+
+// markDeprecated flags responses of the offset pagination
+func markDeprecated(ctx *gin.Context) {
+	ctx.Header("Deprecation", "true")
+}
+
+func encodeCursor(id int64) string {
+	data, _ := json.Marshal(pageCursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID < 1 {
+		return 0, errors.New("invalid cursor")
+	}
+	return decoded.ID, nil
+}
+
+func encodeUserCursor(username string) string {
+	data, _ := json.Marshal(pageCursor{Username: username})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errors.New("invalid cursor")
+	}
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Username) == 0 {
+		return "", errors.New("invalid cursor")
+	}
+	return decoded.Username, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := encodeCursor(42)
+	require.NotEmpty(t, cursor)
+
+	id, err := decodeCursor(cursor)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), id)
+
+	for _, invalid := range []string{"not base64!", encodeCursor(0), "e30"} {
+		_, err = decodeCursor(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestKeysetPageResponse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		page     keysetPage
+		ids      []int64
+		hasMore  bool
+		wantNext int64
+		wantPrev int64
+	}{
+		{
+			name:     "FirstPageWithMore",
+			page:     keysetPage{size: 2},
+			ids:      []int64{1, 2},
+			hasMore:  true,
+			wantNext: 2,
+		},
+		{
+			name:     "LastPage",
+			page:     keysetPage{id: 2, size: 2},
+			ids:      []int64{3},
+			wantPrev: 3,
+		},
+		{
+			name:     "BackwardsWithMore",
+			page:     keysetPage{before: true, id: 5, size: 2},
+			ids:      []int64{3, 4},
+			hasMore:  true,
+			wantNext: 4,
+			wantPrev: 3,
+		},
+		{
+			name:     "BackwardsToStart",
+			page:     keysetPage{before: true, id: 3, size: 2},
+			ids:      []int64{1, 2},
+			wantNext: 2,
+		},
+		{
+			name: "Empty",
+			page: keysetPage{size: 2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp := testCase.page.response(testCase.ids, testCase.ids, testCase.hasMore)
+			requireCursor(t, testCase.wantNext, resp.NextCursor)
+			requireCursor(t, testCase.wantPrev, resp.PrevCursor)
+		})
+	}
+}
+
+// requireCursor asserts that cursor points at the input id, or is empty if id is zero
+func requireCursor(t *testing.T, id int64, cursor string) {
+	if id == 0 {
+		require.Empty(t, cursor)
+		return
+	}
+	gotID, err := decodeCursor(cursor)
+	require.NoError(t, err)
+	require.Equal(t, id, gotID)
+}
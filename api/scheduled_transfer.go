@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	Memo          string `json:"memo" binding:"max=140"`
+	// Cron expression, @daily like descriptor or @every <duration>, empty for a single transfer at StartAt
+	Schedule string    `json:"schedule" binding:"max=100"`
+	StartAt  time.Time `json:"start_at"`
+}
+
+// createScheduledTransfer schedules a single or recurring transfer out of an account of the user
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.bank.CreateScheduledTransfer(ctx, service.NewActor(authPayload), service.CreateScheduledTransferParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Memo:          req.Memo,
+		Schedule:      req.Schedule,
+		StartAt:       req.StartAt,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type getScheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var req getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.bank.GetScheduledTransfer(ctx, service.NewActor(authPayload), req.ID)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if req.PageID > 0 {
+		// Offset pagination is deprecated, so new lists don't support it
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, errors.New("page_id is not supported, use the after and before cursors"))
+		return
+	}
+	page, err := req.keyset()
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, hasMore, err := server.bank.ListScheduledTransfers(ctx, service.NewActor(authPayload), page.servicePage())
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ids := make([]int64, len(scheduledTransfers))
+	for i, scheduledTransfer := range scheduledTransfers {
+		ids[i] = scheduledTransfer.ID
+	}
+	ctx.JSON(http.StatusOK, page.response(scheduledTransfers, ids, hasMore))
+}
+
+type updateScheduledTransferRequest struct {
+	Amount   *int64  `json:"amount" binding:"omitempty,gt=0"`
+	Memo     *string `json:"memo" binding:"omitempty,max=140"`
+	Schedule *string `json:"schedule" binding:"omitempty,max=100"`
+	Status   *string `json:"status" binding:"omitempty,oneof=active paused"`
+}
+
+// updateScheduledTransfer changes, pauses or resumes a scheduled transfer of the user
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	arg := service.UpdateScheduledTransferParams{
+		ID:       uri.ID,
+		Amount:   req.Amount,
+		Memo:     req.Memo,
+		Schedule: req.Schedule,
+	}
+	if req.Status != nil {
+		status := db.ScheduledTransferStatus(*req.Status)
+		arg.Status = &status
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.bank.UpdateScheduledTransfer(ctx, service.NewActor(authPayload), arg)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+// cancelScheduledTransfer stops a scheduled transfer of the user for good
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var req getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.bank.CancelScheduledTransfer(ctx, service.NewActor(authPayload), req.ID)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type listScheduledTransferRunsRequest struct {
+	Limit int32 `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// listScheduledTransferRuns returns the latest attempts of a scheduled transfer, newest first
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultPageSize
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	runs, err := server.bank.ListScheduledTransferRuns(ctx, service.NewActor(authPayload), uri.ID, req.Limit)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, runs)
+}
@@ -1,13 +1,15 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/harrychopra/go-api/db/models"
-	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/service"
 	"github.com/harrychopra/go-api/util"
 )
 
@@ -15,48 +17,111 @@ import (
 type Server struct {
 	config     util.Config
 	store      db.Store
-	tokenMaker token.Maker
+	bank       *service.Bank
 	router     *gin.Engine
+	httpServer *http.Server
 }
 
-// NewServer creates a new HTTP server and sets up routing
-func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create a token maker: %w", err)
-	}
+// NewServer creates a new HTTP server and sets up routing. The bank is shared with the gRPC server and the
+// background workers.
+func NewServer(config util.Config, store db.Store, bank *service.Bank) (*Server, error) {
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config: config,
+		store:  store,
+		bank:   bank,
 	}
 	// Register custom validator
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("role", validRole)
+		v.RegisterTagNameFunc(requestFieldName)
 	}
 	server.setupRouter()
+	// The forwarding headers are spoofable unless set by a proxy, and client IPs key the login throttling
+	if err := server.router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	server.httpServer = &http.Server{
+		Handler:      server.router,
+		ReadTimeout:  config.HTTPReadTimeout,
+		WriteTimeout: config.HTTPWriteTimeout,
+		IdleTimeout:  config.HTTPIdleTimeout,
+	}
 	return server, nil
 }
 
 func (server *Server) setupRouter() {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(loggerMiddleware(), metricsMiddleware(), gin.CustomRecovery(recovered))
+	router.NoRoute(notFound)
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
 	router.POST("/users", server.CreateUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/mfa", server.loginUserMFA)
+	router.GET("/users/verify_email", server.verifyEmail)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.bank))
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/verify_email/resend", server.resendVerifyEmail)
+	authRoutes.PATCH("/users/me", server.updateUser)
+	authRoutes.POST("/users/me/password", server.changePassword)
+	authRoutes.POST("/users/me/mfa/totp", server.enrollTOTP)
+	authRoutes.POST("/users/me/mfa/totp/confirm", server.confirmTOTP)
+	idempotent := idempotencyMiddleware(server.store, server.config.IdempotencyKeyTTL)
 
-	authRoutes.POST("/accounts", server.CreateAccount)
+	authRoutes.POST("/accounts", idempotent, server.CreateAccount)
 	authRoutes.GET("/accounts/:id", server.GetAccount)
 	authRoutes.GET("/accounts", server.ListAccounts)
-	authRoutes.POST("/transfers", server.CreateTransfer)
+	authRoutes.GET("/accounts/:id/entries", server.ListEntries)
+	authRoutes.GET("/accounts/:id/transfers", server.ListTransfers)
+	authRoutes.GET("/accounts/:id/statement", server.getStatement)
+	authRoutes.POST("/transfers", idempotent, server.CreateTransfer)
+	authRoutes.GET("/transfers/:id", server.GetTransfer)
+	authRoutes.POST("/scheduled_transfers", idempotent, server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.cancelScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+	authRoutes.POST("/webhooks", idempotent, server.createWebhook)
+	authRoutes.GET("/webhooks", server.listWebhooks)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhook)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+
+	tellerRoutes := router.Group("/").Use(
+		authMiddleware(server.bank),
+		authorize(util.TellerRole, util.AdminRole),
+	)
+	tellerRoutes.POST("/accounts/:id/deposits", idempotent, server.createDeposit)
+	tellerRoutes.POST("/accounts/:id/withdrawals", idempotent, server.createWithdrawal)
+
+	adminRoutes := router.Group("/admin").Use(
+		authMiddleware(server.bank),
+		authorize(util.AdminRole),
+	)
+	adminRoutes.GET("/users", server.listUsers)
+	adminRoutes.PUT("/users/:username/role", server.updateUserRole)
+	adminRoutes.POST("/users/:username/revoke_sessions", server.revokeUserSessions)
+	adminRoutes.POST("/accounts/:id/freeze", server.freezeAccount)
+	adminRoutes.POST("/accounts/:id/unfreeze", server.unfreezeAccount)
 	server.router = router
 }
 
-// Start starts an HTTP Server and listens on the input "address:port"
+// Start starts an HTTP Server and listens on the input "address:port".
+// It blocks until the server fails or is stopped by Shutdown, in which case it returns nil.
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	server.httpServer.Addr = address
+	if err := server.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
-func errResponse(err error) *gin.H {
-	return &gin.H{"error": err.Error()}
+// Shutdown stops accepting connections and waits for in-flight requests to complete, until ctx is done
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+)
+
+const statementDateFormat = "2006-01-02"
+
+type statementRequest struct {
+	From   time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To     time.Time `form:"to" binding:"required,gtefield=From" time_format:"2006-01-02" time_utc:"1"` // Inclusive
+	Format string    `form:"format" binding:"omitempty,oneof=csv json ofx"`
+}
+
+// getStatement streams the statement of an account between two dates, in UTC. CSV and OFX statements, made for
+// spreadsheets and personal finance software, give amounts as decimals in major units, e.g. 12.05. JSON statements
+// give them in minor units, as everywhere else in the API: their fields end in _minor and currency_exponent gives
+// the decimal places, e.g. an amount_minor of 1205 with a currency_exponent of 2. As the statement is written while
+// it's read, an error past its start can't be answered anymore: the statement is cut short of its closing balance.
+// The server's write timeout (HTTP_WRITE_TIMEOUT) also bounds the whole stream, since it can't be extended per
+// response before Go 1.20, so a statement taking longer to write is cut short the same way.
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req statementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	file := statementFile{ctx: ctx, from: req.From, to: req.To}
+	var handler db.StatementHandler
+	switch req.Format {
+	case "csv":
+		handler = &csvStatement{statementFile: file}
+	case "ofx":
+		handler = &ofxStatement{statementFile: file}
+	default:
+		handler = &jsonStatement{statementFile: file}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	err := server.bank.Statement(ctx, service.NewActor(authPayload), service.StatementParams{
+		AccountID: uri.ID,
+		From:      req.From,
+		To:        req.To.AddDate(0, 0, 1),
+	}, handler)
+	if err != nil {
+		if ctx.Writer.Written() {
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+		// Nothing of the statement went out yet, answer with an error instead
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		writeServiceError(ctx, err)
+	}
+}
+
+// statementFile answers with a statement attachment once the store began reading it
+type statementFile struct {
+	ctx      *gin.Context
+	from, to time.Time
+	account  db.Account
+}
+
+func (file *statementFile) begin(account db.Account, contentType, extension string) io.Writer {
+	file.account = account
+	file.ctx.Header("Content-Type", contentType)
+	file.ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%d-%s-%s.%s"`,
+		account.ID, file.from.Format(statementDateFormat), file.to.Format(statementDateFormat), extension))
+	file.ctx.Status(http.StatusOK)
+	return file.ctx.Writer
+}
+
+// csvStatement writes a row per entry, between an opening and a closing balance row. Amounts are in major units.
+type csvStatement struct {
+	statementFile
+	writer *csv.Writer
+}
+
+func (statement *csvStatement) Begin(account db.Account, openingBalance int64) error {
+	statement.writer = csv.NewWriter(statement.begin(account, "text/csv; charset=utf-8", "csv"))
+	if err := statement.writer.Write([]string{
+		"date", "entry_id", "type", "memo", "counterparty_account_id", "external_reference", "amount", "balance",
+	}); err != nil {
+		return err
+	}
+	return statement.balance(statement.from, "opening_balance", openingBalance)
+}
+
+func (statement *csvStatement) Entry(entry db.ListStatementEntriesRow) error {
+	counterparty := ""
+	if entry.CounterpartyAccountID != 0 {
+		counterparty = strconv.FormatInt(entry.CounterpartyAccountID, 10)
+	}
+	return statement.writer.Write([]string{
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(entry.ID, 10),
+		string(entry.Type),
+		entry.Memo,
+		counterparty,
+		entry.ExternalReference,
+		util.FormatAmount(entry.Amount, statement.account.Currency),
+		util.FormatAmount(entry.BalanceAfter, statement.account.Currency),
+	})
+}
+
+func (statement *csvStatement) End(closingBalance int64) error {
+	if err := statement.balance(statement.to, "closing_balance", closingBalance); err != nil {
+		return err
+	}
+	statement.writer.Flush()
+	return statement.writer.Error()
+}
+
+func (statement *csvStatement) balance(date time.Time, kind string, balance int64) error {
+	return statement.writer.Write([]string{
+		date.Format(statementDateFormat), "", kind, "", "", "", "",
+		util.FormatAmount(balance, statement.account.Currency),
+	})
+}
+
+// jsonStatement writes a single object, the entries in an array between the opening and the closing balance.
+// Amounts are in minor units, as everywhere else in the API, and their names say so.
+type jsonStatement struct {
+	statementFile
+	writer  io.Writer
+	entries int
+}
+
+func (statement *jsonStatement) Begin(account db.Account, openingBalance int64) error {
+	statement.writer = statement.begin(account, "application/json; charset=utf-8", "json")
+	head, err := json.Marshal(struct {
+		AccountID        int64  `json:"account_id"`
+		Currency         string `json:"currency"`
+		CurrencyExponent int    `json:"currency_exponent"`
+		From             string `json:"from"`
+		To               string `json:"to"`
+		OpeningBalance   int64  `json:"opening_balance_minor"`
+	}{
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		CurrencyExponent: util.CurrencyExponent(account.Currency),
+		From:             statement.from.Format(statementDateFormat),
+		To:               statement.to.Format(statementDateFormat),
+		OpeningBalance:   openingBalance,
+	})
+	if err != nil {
+		return err
+	}
+	// Leave the object open for the entries
+	_, err = fmt.Fprintf(statement.writer, `%s,"entries":[`, head[:len(head)-1])
+	return err
+}
+
+// jsonStatementEntry is an entry of a JSON statement
+type jsonStatementEntry struct {
+	ID                    int64          `json:"id"`
+	Type                  db.EntryType   `json:"type"`
+	Amount                int64          `json:"amount_minor"`
+	BalanceAfter          int64          `json:"balance_after_minor"`
+	Memo                  string         `json:"memo"`
+	ExternalReference     string         `json:"external_reference"`
+	TransferID            util.NullInt64 `json:"transfer_id"`
+	CreatedAt             time.Time      `json:"created_at"`
+	CounterpartyAccountID int64          `json:"counterparty_account_id"`
+}
+
+func (statement *jsonStatement) Entry(entry db.ListStatementEntriesRow) error {
+	data, err := json.Marshal(jsonStatementEntry(entry))
+	if err != nil {
+		return err
+	}
+	if statement.entries > 0 {
+		data = append([]byte{','}, data...)
+	}
+	statement.entries++
+	_, err = statement.writer.Write(data)
+	return err
+}
+
+func (statement *jsonStatement) End(closingBalance int64) error {
+	_, err := fmt.Fprintf(statement.writer, `],"closing_balance_minor":%d}`, closingBalance)
+	return err
+}
+
+// ofxBankID stands in for the routing number OFX requires along the account id
+const ofxBankID = "000000000"
+
+// ofxStatement writes an OFX 2.2 bank statement response, the format personal finance software imports
+type ofxStatement struct {
+	statementFile
+	writer io.Writer
+}
+
+func (statement *ofxStatement) Begin(account db.Account, _ int64) error {
+	statement.writer = statement.begin(account, "application/x-ofx", "ofx")
+	_, err := fmt.Fprintf(statement.writer, `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(time.Now()), account.Currency, ofxBankID, account.ID, ofxDate(statement.from), ofxDate(statement.to))
+	return err
+}
+
+func (statement *ofxStatement) Entry(entry db.ListStatementEntriesRow) error {
+	name := string(entry.Type)
+	if entry.CounterpartyAccountID != 0 {
+		name = fmt.Sprintf("Account %d", entry.CounterpartyAccountID)
+	}
+	memo := ""
+	if len(entry.Memo) > 0 {
+		memo = "<MEMO>" + ofxText(entry.Memo) + "</MEMO>"
+	}
+	_, err := fmt.Fprintf(statement.writer,
+		"<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%d</FITID><NAME>%s</NAME>%s</STMTTRN>\n",
+		ofxTransactionType(entry), ofxTime(entry.CreatedAt), util.FormatAmount(entry.Amount, statement.account.Currency),
+		entry.ID, ofxText(name), memo)
+	return err
+}
+
+func (statement *ofxStatement) End(closingBalance int64) error {
+	_, err := fmt.Fprintf(statement.writer, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, util.FormatAmount(closingBalance, statement.account.Currency), ofxDate(statement.to))
+	return err
+}
+
+// ofxTransactionType maps an entry type to the closest OFX TRNTYPE
+func ofxTransactionType(entry db.ListStatementEntriesRow) string {
+	switch entry.Type {
+	case db.EntryTypeTransfer:
+		return "XFER"
+	case db.EntryTypeDeposit:
+		return "DEP"
+	case db.EntryTypeWithdrawal:
+		return "CASH"
+	case db.EntryTypeFee:
+		return "FEE"
+	}
+	if entry.Amount < 0 {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+// ofxText escapes free text for an OFX element
+func ofxText(text string) string {
+	var builder strings.Builder
+	xml.EscapeText(&builder, []byte(text))
+	return builder.String()
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102")
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
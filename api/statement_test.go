@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatementAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	account.Currency = util.USD
+	from := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.September, 30, 0, 0, 0, 0, time.UTC)
+	entries := []db.ListStatementEntriesRow{
+		{
+			ID:                    7,
+			Type:                  db.EntryTypeTransfer,
+			Amount:                -1205,
+			BalanceAfter:          8795,
+			Memo:                  "rent & bills",
+			TransferID:            util.NewNullInt64(3),
+			CreatedAt:             from.Add(time.Hour),
+			CounterpartyAccountID: account.ID + 1,
+		},
+		{
+			ID:                8,
+			Type:              db.EntryTypeDeposit,
+			Amount:            500,
+			BalanceAfter:      9295,
+			ExternalReference: "receipt-1",
+			CreatedAt:         from.Add(48 * time.Hour),
+		},
+	}
+	statementParams := db.StatementTxParams{AccountID: account.ID, From: from, To: to.AddDate(0, 0, 1)}
+
+	// streamStatement plays the store reading the statement
+	streamStatement := func(ctx context.Context, arg db.StatementTxParams, handler db.StatementHandler) error {
+		if err := handler.Begin(account, 10000); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := handler.Entry(entry); err != nil {
+				return err
+			}
+		}
+		return handler.End(9295)
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "JSON",
+			query:    "from=2026-09-01&to=2026-09-30",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Eq(statementParams), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamStatement)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Disposition"),
+					fmt.Sprintf("statement-%d-2026-09-01-2026-09-30.json", account.ID))
+
+				var statement struct {
+					AccountID        int64                `json:"account_id"`
+					CurrencyExponent int                  `json:"currency_exponent"`
+					From             string               `json:"from"`
+					To               string               `json:"to"`
+					OpeningBalance   int64                `json:"opening_balance_minor"`
+					Entries          []jsonStatementEntry `json:"entries"`
+					ClosingBalance   int64                `json:"closing_balance_minor"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statement))
+				require.Equal(t, account.ID, statement.AccountID)
+				require.Equal(t, util.CurrencyExponent(account.Currency), statement.CurrencyExponent)
+				require.Equal(t, "2026-09-30", statement.To)
+				require.Equal(t, int64(10000), statement.OpeningBalance)
+				require.Equal(t, int64(9295), statement.ClosingBalance)
+				require.Len(t, statement.Entries, 2)
+				require.Equal(t, entries[0].CounterpartyAccountID, statement.Entries[0].CounterpartyAccountID)
+				require.Equal(t, entries[0].TransferID, statement.Entries[0].TransferID)
+				require.Equal(t, entries[0].Amount, statement.Entries[0].Amount)
+				require.Equal(t, entries[0].BalanceAfter, statement.Entries[0].BalanceAfter)
+			},
+		},
+		{
+			name:     "CSV",
+			query:    "from=2026-09-01&to=2026-09-30&format=csv",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Eq(statementParams), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamStatement)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "text/csv")
+
+				records, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Equal(t, [][]string{
+					{"date", "entry_id", "type", "memo", "counterparty_account_id", "external_reference", "amount", "balance"},
+					{"2026-09-01", "", "opening_balance", "", "", "", "", "100.00"},
+					{"2026-09-01T01:00:00Z", "7", "transfer", "rent & bills", fmt.Sprint(account.ID + 1), "", "-12.05", "87.95"},
+					{"2026-09-03T00:00:00Z", "8", "deposit", "", "", "receipt-1", "5.00", "92.95"},
+					{"2026-09-30", "", "closing_balance", "", "", "", "", "92.95"},
+				}, records)
+			},
+		},
+		{
+			name:     "OFX",
+			query:    "from=2026-09-01&to=2026-09-30&format=ofx",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Eq(statementParams), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamStatement)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				body := recorder.Body.String()
+				require.Contains(t, body, "<DTSTART>20260901</DTSTART><DTEND>20260930</DTEND>")
+				require.Contains(t, body, "<TRNTYPE>XFER</TRNTYPE><DTPOSTED>20260901010000</DTPOSTED><TRNAMT>-12.05</TRNAMT><FITID>7</FITID>")
+				require.Contains(t, body, "<MEMO>rent &amp; bills</MEMO>")
+				require.Contains(t, body, "<TRNTYPE>DEP</TRNTYPE>")
+				require.Contains(t, body, "<BALAMT>92.95</BALAMT>")
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			query:    "from=2026-09-01&to=2026-09-30",
+			username: "unauthorized_user",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "EndsBeforeStart",
+			query:    "from=2026-09-30&to=2026-09-01",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var resp errorResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, []fieldViolation{{Field: "to", Description: "must not be before from"}}, resp.Error.Details)
+			},
+		},
+		{
+			name:     "InvalidDate",
+			query:    "from=2026-09-31&to=2026-10-01",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "UnsupportedFormat",
+			query:    "from=2026-09-01&to=2026-09-30&format=pdf",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			query:    "from=2026-09-01&to=2026-09-30",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errors.New("connection reset"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:     "ErrorBeforeFirstWrite",
+			query:    "from=2026-09-01&to=2026-09-30&format=csv",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.StatementTxParams, handler db.StatementHandler) error {
+						// The CSV writer buffers, so nothing was sent yet
+						require.NoError(t, handler.Begin(account, 10000))
+						return errors.New("connection reset")
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+				require.Empty(t, recorder.Header().Get("Content-Disposition"))
+			},
+		},
+		{
+			name:     "ErrorAfterBegin",
+			query:    "from=2026-09-01&to=2026-09-30",
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					StatementTx(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.StatementTxParams, handler db.StatementHandler) error {
+						require.NoError(t, handler.Begin(account, 10000))
+						return errors.New("connection reset")
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// The statement is cut short of its closing balance
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "closing_balance")
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/accounts/%d/statement?%s", account.ID, testCase.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, testCase.username, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
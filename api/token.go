@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// renewAccessToken issues a new access token for the session of a refresh token
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	result, err := server.bank.RenewAccessToken(ctx, req.RefreshToken)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	resp := renewAccessTokenResponse{
+		AccessToken:          result.AccessToken,
+		AccessTokenExpiresAt: result.AccessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
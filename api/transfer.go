@@ -1,13 +1,10 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
 	"github.com/harrychopra/go-api/token"
 )
 
@@ -16,55 +13,90 @@ type createTransferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	Memo          string `json:"memo" binding:"max=140"`
 }
 
 func (server *Server) CreateTransfer(ctx *gin.Context) {
 	var req createTransferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		writeBindingError(ctx, err)
 		return
 	}
-	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
-	if !valid {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.bank.CreateTransfer(ctx, service.NewActor(authPayload), service.CreateTransferParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Memo:          req.Memo,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) GetTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
 		return
 	}
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if authPayload.Username != fromAccount.Owner {
-		err := errors.New("from account doesn't belong to authenticated user")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+	transfer, err := server.bank.GetTransfer(ctx, service.NewActor(authPayload), req.ID)
+	if err != nil {
+		writeServiceError(ctx, err)
 		return
 	}
-	if _, valid = server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+func (server *Server) ListTransfers(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
 		return
 	}
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
 	}
-	result, err := server.store.TransferTx(ctx, arg)
+	isOffset, err := req.isOffset()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, result)
-}
-
-// validAccount confirms if the input account (id) exists and has a matching input currency
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := server.store.GetAccount(ctx, accountID)
+	page, err := req.keyset()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return account, false
+		writeError(ctx, http.StatusBadRequest, codeInvalidArgument, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if isOffset {
+		// Transfers sent from or received by the account
+		transfers, err := server.bank.ListTransfersByOffset(ctx, service.NewActor(authPayload), uri.ID, req.PageSize, req.offset())
+		if err != nil {
+			writeServiceError(ctx, err)
+			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-		return account, false
+		markDeprecated(ctx)
+		ctx.JSON(http.StatusOK, transfers)
+		return
+	}
+	transfers, hasMore, err := server.bank.ListTransfers(ctx, service.NewActor(authPayload), uri.ID, page.servicePage())
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
 	}
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
-		return account, false
+	ids := make([]int64, len(transfers))
+	for i, transfer := range transfers {
+		ids[i] = transfer.ID
 	}
-	return account, true
+	ctx.JSON(http.StatusOK, page.response(transfers, ids, hasMore))
 }
@@ -1,14 +1,16 @@
 package api
 
 import (
-	"database/sql"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
 	"github.com/harrychopra/go-api/util"
-	"github.com/lib/pq"
 )
 
 type createUserRequest struct {
@@ -22,6 +24,9 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	IsMFAEnabled      bool      `json:"is_mfa_enabled"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -31,6 +36,9 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		IsMFAEnabled:      user.IsMfaEnabled,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -39,35 +47,146 @@ func newUserResponse(user db.User) userResponse {
 func (server *Server) CreateUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		writeBindingError(ctx, err)
 		return
 	}
-	hashedPassword, err := util.HashedPassword(req.Password)
+	user, err := server.bank.CreateUser(ctx, service.CreateUserParams{
+		Username: req.Username,
+		Password: req.Password,
+		FullName: req.FullName,
+		Email:    req.Email,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeServiceError(ctx, err)
 		return
 	}
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type verifyEmailRequest struct {
+	ID   int64  `form:"id" binding:"required,min=1"`
+	Code string `form:"code" binding:"required,len=64"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail uses the code of the link emailed to a new user, which lets it open accounts and make transfers
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
 	}
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.bank.VerifyEmail(ctx, req.ID, req.Code)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			// Unique Constraint: User for this account already has an account with this currency
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-			}
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		writeServiceError(ctx, err)
 		return
 	}
-	resp := newUserResponse(user)
-	ctx.JSON(http.StatusOK, resp)
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: user.IsEmailVerified})
+}
+
+// resendVerifyEmail emails the authenticated user a new link verifying its address
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := server.bank.ResendVerifyEmail(ctx, authPayload.Username); err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+type updateUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+// updateUser changes the profile of the authenticated user, only the fields sent are updated
+func (server *Server) updateUser(ctx *gin.Context) {
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.bank.UpdateUser(ctx, service.UpdateUserParams{
+		Username: authPayload.Username,
+		FullName: req.FullName,
+		Email:    req.Email,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePassword replaces the password of the authenticated user, who then has to log in again everywhere
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.bank.ChangePassword(ctx, service.ChangePasswordParams{
+		Username:    authPayload.Username,
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a link resetting the password to the user with the address. It is accepted whether there is
+// such a user or not, so that it can't be used to find out who has an account.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if err := server.bank.ForgotPassword(ctx, req.Email); err != nil {
+		util.LoggerFromContext(ctx).Error().Err(err).Msg("failed to send password reset")
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets the password of the user the reset token was emailed to, who then has to log in again everywhere
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	_, err := server.bank.ResetPassword(ctx, service.ResetPasswordParams{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
 }
 
 type loginUserRequest struct {
@@ -76,40 +195,68 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+		writeBindingError(ctx, err)
 		return
 	}
-	user, err := server.store.GetUser(ctx, req.Username)
+	result, err := server.bank.LoginUser(ctx, service.LoginUserParams{
+		Username:  req.Username,
+		Password:  req.Password,
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-		} else {
-			ctx.JSON(http.StatusInternalServerError, errResponse(err))
-		}
+		writeServiceError(ctx, err)
 		return
 	}
-	if err := util.CheckPassword(req.Password, user.HashedPassword); err != nil {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+	if len(result.MFAToken) > 0 {
+		ctx.JSON(http.StatusOK, mfaRequiredResponse{
+			MFARequired:       true,
+			MFAToken:          result.MFAToken,
+			MFATokenExpiresAt: result.MFAPayload.ExpiredAt,
+		})
 		return
 	}
-	accessToken, err := server.tokenMaker.CreateToken(
-		user.Username,
-		server.config.ACCESS_TOKEN_DURATION,
-	)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	ctx.JSON(http.StatusOK, newLoginUserResponse(result))
+}
+
+func newLoginUserResponse(result service.LoginUserResult) loginUserResponse {
+	return loginUserResponse{
+		SessionID:             result.Session.ID,
+		AccessToken:           result.AccessToken,
+		AccessTokenExpiresAt:  result.AccessPayload.ExpiredAt,
+		RefreshToken:          result.RefreshToken,
+		RefreshTokenExpiresAt: result.RefreshPayload.ExpiredAt,
+		User:                  newUserResponse(result.User),
+	}
+}
+
+type logoutUserRequest struct {
+	// Optional: also ends the session of this refresh token
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	// The request body is optional
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		writeBindingError(ctx, err)
 		return
 	}
-	resp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := server.bank.LogoutUser(ctx, authPayload, req.RefreshToken); err != nil {
+		writeServiceError(ctx, err)
+		return
 	}
-	ctx.JSON(http.StatusOK, resp)
+	ctx.Status(http.StatusNoContent)
 }
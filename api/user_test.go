@@ -2,6 +2,8 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,12 +11,15 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/harrychopra/go-api/db/mock"
 	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/token"
 	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,13 +30,14 @@ type eqCreateUserParamsMatcher struct {
 	expPassword string
 }
 
-// Matches matches expected CreateUser() parameters with an actual argument
+// Matches matches expected CreateUserTx() parameters with an actual argument
 func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
 	// x is the "actual" sent by handler
-	gotUser, ok := x.(db.CreateUserParams)
-	if !ok {
+	gotArg, ok := x.(db.CreateUserTxParams)
+	if !ok || len(gotArg.SecretCode) == 0 {
 		return false
 	}
+	gotUser := gotArg.CreateUserParams
 	// Verify that the "expected" naked password string, matches the hashed password
 	if err := util.CheckPassword(e.expPassword, gotUser.HashedPassword); err != nil {
 		return false
@@ -76,9 +82,9 @@ func TestCreateUserAPI(t *testing.T) {
 					Email:    user.Email,
 				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
-					Return(user, nil)
+					Return(db.CreateUserTxResult{User: user}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -108,11 +114,791 @@ func TestCreateUserAPI(t *testing.T) {
 	}
 }
 
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser()
+	emailID := util.RandomInt(1, 1000)
+	code := util.RandomString(64)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("id=%d&code=%s", emailID, code),
+			buildStubs: func(store *mock.MockStore) {
+				arg := db.VerifyEmailTxParams{EmailID: emailID, SecretCode: code}
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.VerifyEmailTxResult{User: user}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var resp verifyEmailResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.True(t, resp.IsVerified)
+			},
+		},
+		{
+			name:  "InvalidCode",
+			query: fmt.Sprintf("id=%d&code=%s", emailID, code),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmailTxResult{}, fmt.Errorf("tx error: %w", sql.ErrNoRows))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingCode",
+			query: fmt.Sprintf("id=%d", emailID),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/users/verify_email?"+testCase.query, nil)
+			require.NoError(t, err)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResendVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser()
+	user.IsEmailVerified = false
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						return db.VerifyEmail{ID: 1, Username: arg.Username, Email: arg.Email, SecretCode: arg.SecretCode}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verified, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/verify_email/resend", nil)
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, testTokenMaker(t, server.config))
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						// Only a sha256 of the refresh token is kept
+						require.Len(t, arg.RefreshTokenHash, 64)
+						require.False(t, arg.IsBlocked)
+						return db.Session{
+							ID:               arg.ID,
+							Username:         arg.Username,
+							RefreshTokenHash: arg.RefreshTokenHash,
+							ExpiresAt:        arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.NotZero(t, resp.SessionID)
+				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.True(t, resp.RefreshTokenExpiresAt.After(resp.AccessTokenExpiresAt))
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// Answered as a wrong password, not to tell which usernames exist
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				var resp errorResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, codeUnauthenticated, resp.Error.Code)
+				require.Equal(t, "incorrect username or password", resp.Error.Message)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "incorrect",
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				var resp errorResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, "incorrect username or password", resp.Error.Message)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserThrottled(t *testing.T) {
+	username := util.RandomName()
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	// The throttled login is turned down before the user is looked up
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(3).Return(db.User{}, sql.ErrNoRows)
+	server := newTestServer(t, store)
+
+	login := func() *httptest.ResponseRecorder {
+		data, err := json.Marshal(gin.H{"username": username, "password": util.RandomString(8)})
+		require.NoError(t, err)
+		request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Of the 4 failures allowed by the test server, the first 2 are free and the third delays the next login
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusUnauthorized, login().Code)
+	}
+
+	recorder := login()
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, codeTooManyRequests, resp.Error.Code)
+}
+
+func TestLoginUserClientIP(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		clientIP       string
+	}{
+		{
+			// A client can't pick the address its failed logins are counted against
+			name:     "NoTrustedProxy",
+			clientIP: "192.0.2.1",
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: []string{"192.0.2.0/24"},
+			clientIP:       "203.0.113.7",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			store.EXPECT().
+				CreateSession(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+					require.Equal(t, testCase.clientIP, arg.ClientIp)
+					return db.Session{ID: arg.ID, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+				})
+
+			server := newTestServer(t, store)
+			if testCase.trustedProxies != nil {
+				require.NoError(t, server.router.SetTrustedProxies(testCase.trustedProxies))
+			}
+			data, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+			request.Header.Set("X-Forwarded-For", "203.0.113.7")
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestUpdateUserAPI(t *testing.T) {
+	user, _ := randomUser()
+	newName := util.RandomName()
+	newEmail := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FullNameOnly",
+			body: gin.H{"full_name": newName},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, sql.NullString{String: newName, Valid: true}, arg.FullName)
+						require.False(t, arg.Email.Valid)
+						require.False(t, arg.HashedPassword.Valid)
+						updated := user
+						updated.FullName = newName
+						return db.UpdateUserTxResult{User: updated}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var resp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, newName, resp.FullName)
+				require.Equal(t, user.Email, resp.Email)
+			},
+		},
+		{
+			name: "Email",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.False(t, arg.FullName.Valid)
+						require.Equal(t, sql.NullString{String: newEmail, Valid: true}, arg.Email)
+						updated := user
+						updated.Email = newEmail
+						updated.IsEmailVerified = false
+						return db.UpdateUserTxResult{User: updated}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var resp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+				require.Equal(t, newEmail, resp.Email)
+				require.False(t, resp.IsEmailVerified)
+			},
+		},
+		{
+			name: "EmailInUse",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, fmt.Errorf("tx error: %w", &pq.Error{Code: "23505"}))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NothingToUpdate",
+			body: gin.H{},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"old_password": password, "new_password": newPassword},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateUserParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.HashedPassword.Valid)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword.String))
+						require.WithinDuration(t, time.Now(), arg.PasswordChangedAt, time.Second)
+						updated := user
+						updated.HashedPassword = arg.HashedPassword.String
+						updated.PasswordChangedAt = arg.PasswordChangedAt
+						return updated, nil
+					})
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{"old_password": "incorrect", "new_password": newPassword},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SamePassword",
+			body: gin.H{"old_password": password, "new_password": password},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{"old_password": password, "new_password": "123"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/me/password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, testTokenMaker(t, server.config), authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestChangePasswordRejectsOldTokens(t *testing.T) {
+	user, password := randomUser()
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+	store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).Times(1)
+	store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	accessToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	send := func(method, url string, body gin.H) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		request, err := http.NewRequest(method, url, bytes.NewReader(data))
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(http.MethodPost, "/users/me/password", gin.H{"old_password": password, "new_password": util.RandomString(8)})
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The token was issued before the password changed
+	recorder = send(http.MethodPatch, "/users/me", gin.H{"full_name": util.RandomName()})
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			// Unknown addresses get the same response
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		createToken   func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload)
+		buildStubs    func(store *mock.MockStore, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				resetToken, payload, err := tokenMaker.CreatePurposeToken(user.Username, token.PurposePasswordReset, time.Minute)
+				require.NoError(t, err)
+				return resetToken, payload
+			},
+			buildStubs: func(store *mock.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					ResetUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetUserPasswordParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+						require.True(t, payload.IssuedAt.Equal(arg.RequestedAt))
+						require.True(t, arg.PasswordChangedAt.After(arg.RequestedAt))
+						return user, nil
+					})
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyUsed",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				resetToken, payload, err := tokenMaker.CreatePurposeToken(user.Username, token.PurposePasswordReset, time.Minute)
+				require.NoError(t, err)
+				return resetToken, payload
+			},
+			buildStubs: func(store *mock.MockStore, payload *token.Payload) {
+				store.EXPECT().ResetUserPassword(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Expired",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				resetToken, payload, err := tokenMaker.CreatePurposeToken(user.Username, token.PurposePasswordReset, -time.Minute)
+				require.NoError(t, err)
+				return resetToken, payload
+			},
+			buildStubs: func(store *mock.MockStore, payload *token.Payload) {
+				store.EXPECT().ResetUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				accessToken, payload, err := tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return accessToken, payload
+			},
+			buildStubs: func(store *mock.MockStore, payload *token.Payload) {
+				store.EXPECT().ResetUserPassword(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
+			resetToken, payload := testCase.createToken(t, testTokenMaker(t, server.config))
+			testCase.buildStubs(store, payload)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{"token": resetToken, "new_password": newPassword})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResetTokenIsNotAnAccessToken(t *testing.T) {
+	user, _ := randomUser()
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().UpdateUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	resetToken, _, err := testTokenMaker(t, server.config).CreatePurposeToken(user.Username, token.PurposePasswordReset, time.Minute)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(gin.H{"full_name": util.RandomName()})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, resetToken))
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
 func randomUser() (db.User, string) {
 	return db.User{
-		Username: util.RandomName(),
-		FullName: util.RandomName(),
-		Email:    util.RandomEmail(),
+		Username:        util.RandomName(),
+		FullName:        util.RandomName(),
+		Email:           util.RandomEmail(),
+		Role:            util.CustomerRole,
+		IsEmailVerified: true,
 	}, util.RandomString(8)
 }
 
@@ -125,3 +911,80 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.NoError(t, err)
 	require.Equal(t, user, gotUser)
 }
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock.NewMockStore(ctrl)
+		server := newTestServer(t, store)
+
+		accessToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+		require.NoError(t, err)
+		refreshToken, refreshPayload, err := testTokenMaker(t, server.config).CreateRefreshToken(user.Username, user.Role, time.Hour)
+		require.NoError(t, err)
+
+		store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(refreshPayload.ID)).Times(1)
+
+		recorder := sendLogoutRequest(t, server, accessToken, gin.H{"refresh_token": refreshToken})
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+
+		require.True(t, isRevoked(t, server, accessToken))
+		_, err = server.bank.RenewAccessToken(context.Background(), refreshToken)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "revoked")
+
+		// The revoked access token can't be used again
+		recorder = sendLogoutRequest(t, server, accessToken, nil)
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("WithoutRefreshToken", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock.NewMockStore(ctrl)
+		server := newTestServer(t, store)
+
+		accessToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+		require.NoError(t, err)
+
+		store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+
+		recorder := sendLogoutRequest(t, server, accessToken, nil)
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+
+	t.Run("RefreshTokenOfAnotherUser", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mock.NewMockStore(ctrl)
+		server := newTestServer(t, store)
+
+		accessToken, _, err := testTokenMaker(t, server.config).CreateToken(user.Username, user.Role, time.Minute)
+		require.NoError(t, err)
+		refreshToken, _, err := testTokenMaker(t, server.config).CreateRefreshToken("another", util.CustomerRole, time.Hour)
+		require.NoError(t, err)
+
+		store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+
+		recorder := sendLogoutRequest(t, server, accessToken, gin.H{"refresh_token": refreshToken})
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		require.False(t, isRevoked(t, server, accessToken))
+	})
+}
+
+func sendLogoutRequest(t *testing.T, server *Server, accessToken string, body gin.H) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	request, err := http.NewRequest(http.MethodPost, "/users/logout", bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
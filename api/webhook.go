@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/token"
+)
+
+type webhookResponse struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Only returned when the webhook is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.Url,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+type webhookDeliveryResponse struct {
+	ID            int64                    `json:"id"`
+	EventID       int64                    `json:"event_id"`
+	Status        db.WebhookDeliveryStatus `json:"status"`
+	Attempts      int32                    `json:"attempts"`
+	NextAttemptAt time.Time                `json:"next_attempt_at"`
+	LastError     string                   `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time               `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventID:       delivery.EventID,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		CreatedAt:     delivery.CreatedAt,
+	}
+	if delivery.DeliveredAt.Valid {
+		resp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return resp
+}
+
+type createWebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048"`
+}
+
+// createWebhook registers a URL to which the account and transfer events of the user are posted
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	webhook, err := server.bank.CreateWebhook(ctx, service.NewActor(authPayload), req.URL)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	resp := newWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	webhooks, err := server.bank.ListWebhooks(ctx, service.NewActor(authPayload))
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	resp := make([]webhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		resp[i] = newWebhookResponse(webhook)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type getWebhookRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var req getWebhookRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := server.bank.DeleteWebhook(ctx, service.NewActor(authPayload), req.ID); err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	Limit int32 `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// listWebhookDeliveries returns the latest deliveries to a webhook, newest first, dead ones included
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri getWebhookRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindingError(ctx, err)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultPageSize
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	deliveries, err := server.bank.ListWebhookDeliveries(ctx, service.NewActor(authPayload), uri.ID, req.Limit)
+	if err != nil {
+		writeServiceError(ctx, err)
+		return
+	}
+	resp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		resp[i] = newWebhookDeliveryResponse(delivery)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,15 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric(20,8) NOT NULL DEFAULT 1;
+
+ALTER TABLE "transfers" ADD COLUMN "rounding" varchar NOT NULL DEFAULT 'none';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited, in the to account currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the to currency bought by one unit of the from currency';
+
+COMMENT ON COLUMN "transfers"."rounding" IS 'rounding applied to the converted amount';
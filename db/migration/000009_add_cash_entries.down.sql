@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "external_reference";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "type";
+
+DROP TYPE IF EXISTS "entry_type";
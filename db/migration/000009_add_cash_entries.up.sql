@@ -0,0 +1,15 @@
+CREATE TYPE "entry_type" AS ENUM (
+  'transfer',
+  'deposit',
+  'withdrawal'
+);
+
+ALTER TABLE "entries" ADD COLUMN "type" entry_type NOT NULL DEFAULT 'transfer';
+
+ALTER TABLE "entries" ALTER COLUMN "type" DROP DEFAULT;
+
+ALTER TABLE "entries" ADD COLUMN "external_reference" varchar NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX ON "entries" ("type", "external_reference") WHERE "external_reference" <> '';
+
+COMMENT ON COLUMN "entries"."external_reference" IS 'reference of the cash movement outside the bank, e.g. a teller receipt, empty for transfers';
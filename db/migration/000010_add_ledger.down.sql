@@ -0,0 +1,13 @@
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "owner" = '_bank');
+
+DELETE FROM "accounts" WHERE "owner" = '_bank';
+
+DELETE FROM "users" WHERE "username" = '_bank';
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "balance_after";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "memo";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+-- Postgres can't drop values from an enum, so exchange, fee and adjustment stay in entry_type
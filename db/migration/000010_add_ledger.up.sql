@@ -0,0 +1,40 @@
+ALTER TYPE "entry_type" ADD VALUE 'exchange';
+
+ALTER TYPE "entry_type" ADD VALUE 'fee';
+
+ALTER TYPE "entry_type" ADD VALUE 'adjustment';
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+ALTER TABLE "entries" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "entries" ADD COLUMN "balance_after" bigint;
+
+-- Walk back from the current balance, as accounts may have been opened with a balance no entry accounts for
+UPDATE "entries" SET "balance_after" = "running"."balance_after"
+FROM (
+  SELECT "entries"."id", "accounts"."balance" - COALESCE(SUM("entries"."amount") OVER (
+    PARTITION BY "entries"."account_id" ORDER BY "entries"."id" DESC
+    ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+  ), 0) AS "balance_after"
+  FROM "entries" JOIN "accounts" ON "accounts"."id" = "entries"."account_id"
+) AS "running"
+WHERE "entries"."id" = "running"."id";
+
+ALTER TABLE "entries" ALTER COLUMN "balance_after" SET NOT NULL;
+
+-- The bank's own accounts, one per currency, hold the other side of cash movements and currency exchanges
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email")
+VALUES ('_bank', '!', 'Bank ledger', 'ledger@bank.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+SELECT '_bank', 0, "currency"
+FROM unnest(ARRAY['USD', 'CAD', 'GBP', 'EUR', 'AUD', 'NZD', 'CHF', 'SEK', 'SGD', 'JPY']) AS "currency";
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'transfer the entry is a leg of, if any';
+
+COMMENT ON COLUMN "entries"."balance_after" IS 'balance of the account once the entry was applied';
@@ -0,0 +1 @@
+-- The backfilled links can't be told apart from those written by the store, so they are kept
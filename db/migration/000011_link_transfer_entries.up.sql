@@ -0,0 +1,10 @@
+-- Entries written before transfer_id existed are matched to their transfer by leg, amount and transaction timestamp
+UPDATE "entries" SET "transfer_id" = "transfers"."id"
+FROM "transfers"
+WHERE "entries"."transfer_id" IS NULL
+  AND "entries"."type" = 'transfer'
+  AND "entries"."created_at" = "transfers"."created_at"
+  AND (
+    ("entries"."account_id" = "transfers"."from_account_id" AND "entries"."amount" = -"transfers"."amount")
+    OR ("entries"."account_id" = "transfers"."to_account_id" AND "entries"."amount" = "transfers"."to_amount")
+  );
@@ -0,0 +1,63 @@
+CREATE TYPE "scheduled_transfer_status" AS ENUM (
+  'active',
+  'paused',
+  'completed',
+  'cancelled'
+);
+
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "memo" varchar NOT NULL DEFAULT '',
+  "schedule" varchar NOT NULL DEFAULT '',
+  "next_run_at" timestamptz NOT NULL,
+  "status" scheduled_transfer_status NOT NULL DEFAULT 'active',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "amount_positive" CHECK ("amount" > 0);
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+-- The worker only looks for active rows that are due
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "status" = 'active';
+
+CREATE TYPE "scheduled_transfer_run_status" AS ENUM (
+  'pending',
+  'succeeded',
+  'failed'
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "scheduled_at" timestamptz NOT NULL,
+  "status" scheduled_transfer_run_status NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule" IS 'cron expression, descriptor or @every interval, empty for a single transfer';
+
+COMMENT ON COLUMN "scheduled_transfers"."next_run_at" IS 'time of the next run while active, of the last one otherwise';
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."scheduled_at" IS 'next_run_at of the scheduled transfer when the run was claimed';
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."error" IS 'why the transfer failed, empty unless failed';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+DROP TYPE IF EXISTS "webhook_delivery_status";
+
+DROP TABLE IF EXISTS "webhooks";
+
+DROP TABLE IF EXISTS "outbox_events";
@@ -0,0 +1,59 @@
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "event_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "dispatched_at" timestamptz
+);
+
+ALTER TABLE "outbox_events" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+-- The dispatcher only looks for events not yet fanned out to webhooks
+CREATE INDEX ON "outbox_events" ("id") WHERE "dispatched_at" IS NULL;
+
+CREATE TABLE "webhooks" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhooks" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "webhooks" ("owner");
+
+CREATE TYPE "webhook_delivery_status" AS ENUM (
+  'pending',
+  'delivered',
+  'dead'
+);
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL,
+  "event_id" bigint NOT NULL,
+  "status" webhook_delivery_status NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar NOT NULL DEFAULT '',
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("webhook_id") REFERENCES "webhooks" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("event_id") REFERENCES "outbox_events" ("id");
+
+CREATE UNIQUE INDEX ON "webhook_deliveries" ("webhook_id", "event_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "outbox_events"."owner" IS 'user whose webhooks receive the event';
+
+COMMENT ON COLUMN "outbox_events"."dispatched_at" IS 'when deliveries were created for the webhooks of the owner';
+
+COMMENT ON COLUMN "webhooks"."secret" IS 'key of the HMAC signature of the deliveries';
+
+COMMENT ON COLUMN "webhook_deliveries"."next_attempt_at" IS 'when a pending delivery is next attempted, or its attempt lease ends';
@@ -0,0 +1,9 @@
+CREATE TABLE "login_attempts" (
+  "key" varchar PRIMARY KEY,
+  "failures" integer NOT NULL,
+  "last_failed_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "login_attempts" ("last_failed_at");
+
+COMMENT ON COLUMN "login_attempts"."key" IS 'username or client IP the failures are counted for, prefixed by its kind';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "recovery_codes";
+
+DROP TABLE IF EXISTS "totp_secrets";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_mfa_enabled";
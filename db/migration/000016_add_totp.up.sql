@@ -0,0 +1,26 @@
+ALTER TABLE "users" ADD COLUMN "is_mfa_enabled" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "totp_secrets" (
+  "username" varchar PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "hashed_code" varchar NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "totp_secrets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+COMMENT ON COLUMN "totp_secrets"."secret" IS 'base32, only in use once the user confirmed it and is_mfa_enabled is set';
+
+COMMENT ON COLUMN "totp_secrets"."last_used_step" IS 'time step of the last code accepted, codes of this step or before are replays';
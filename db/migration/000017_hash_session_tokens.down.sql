@@ -0,0 +1,4 @@
+-- The tokens can't be recovered from their hashes, so their sessions end
+UPDATE "sessions" SET "is_blocked" = true;
+
+ALTER TABLE "sessions" RENAME COLUMN "refresh_token_hash" TO "refresh_token";
@@ -0,0 +1,6 @@
+ALTER TABLE "sessions" RENAME COLUMN "refresh_token" TO "refresh_token_hash";
+
+-- Sessions opened before keep working
+UPDATE "sessions" SET "refresh_token_hash" = encode(sha256("refresh_token_hash"::bytea), 'hex');
+
+COMMENT ON COLUMN "sessions"."refresh_token_hash" IS 'sha256 of the refresh token';
@@ -0,0 +1,3 @@
+ALTER TABLE "login_attempts" ADD COLUMN "previous_failed_at" timestamptz;
+
+COMMENT ON COLUMN "login_attempts"."previous_failed_at" IS 'last_failed_at before the last failure, restored if it is taken back';
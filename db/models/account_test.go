@@ -38,6 +38,7 @@ func TestCreateAccount(t *testing.T) {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.False(t, account.IsFrozen)
 	require.NotZero(t, account.CreatedAt)
 }
 
@@ -120,6 +121,20 @@ func TestUpdateAccount(t *testing.T) {
 	require.Equal(t, arg.Balance, updatedAccount.Balance)
 }
 
+func TestUpdateAccountFrozen(t *testing.T) {
+	account := createRandomAccount(t, nil)
+	frozenAccount, err := testQueries.UpdateAccountFrozen(context.Background(), UpdateAccountFrozenParams{
+		ID:       account.ID,
+		IsFrozen: true,
+	})
+	require.NoError(t, err)
+	require.True(t, frozenAccount.IsFrozen)
+	require.Equal(t, account.Balance, frozenAccount.Balance)
+
+	_, err = testQueries.UpdateAccountFrozen(context.Background(), UpdateAccountFrozenParams{ID: 0, IsFrozen: true})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestDeleteAccount(t *testing.T) {
 	account := createRandomAccount(t, nil)
 	err := testQueries.DeleteAccount(context.Background(), account.ID)
@@ -5,27 +5,48 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
 )
 
 const createEntry = `-- name: CreateEntry :one
-INSERT INTO entries(account_id, amount)
-VALUES ($1, $2)
-RETURNING id, account_id, amount, created_at
+INSERT INTO entries(account_id, amount, type, external_reference, transfer_id, memo, balance_after)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID         int64          `json:"account_id"`
+	Amount            int64          `json:"amount"`
+	Type              EntryType      `json:"type"`
+	ExternalReference string         `json:"external_reference"`
+	TransferID        util.NullInt64 `json:"transfer_id"`
+	Memo              string         `json:"memo"`
+	BalanceAfter      int64          `json:"balance_after"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.Type,
+		arg.ExternalReference,
+		arg.TransferID,
+		arg.Memo,
+		arg.BalanceAfter,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Type,
+		&i.ExternalReference,
+		&i.TransferID,
+		&i.Memo,
+		&i.BalanceAfter,
 	)
 	return i, err
 }
@@ -40,8 +61,39 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const getBalanceBefore = `-- name: GetBalanceBefore :one
+SELECT COALESCE(
+  (
+    SELECT entries.balance_after FROM entries
+    WHERE entries.account_id = $1::bigint AND entries.created_at < $2::timestamptz
+    ORDER BY entries.id DESC
+    LIMIT 1
+  ),
+  (
+    SELECT entries.balance_after - entries.amount FROM entries
+    WHERE entries.account_id = $1::bigint
+    ORDER BY entries.id
+    LIMIT 1
+  ),
+  (SELECT accounts.balance FROM accounts WHERE accounts.id = $1::bigint)
+)::bigint AS balance
+`
+
+type GetBalanceBeforeParams struct {
+	AccountID  int64     `json:"account_id"`
+	BeforeTime time.Time `json:"before_time"`
+}
+
+// The balance_after of the last entry before the time, else what the account was opened with
+func (q *Queries) GetBalanceBefore(ctx context.Context, arg GetBalanceBeforeParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getBalanceBefore, arg.AccountID, arg.BeforeTime)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after FROM entries
 WHERE id = $1
 LIMIT 1
 `
@@ -54,12 +106,17 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Type,
+		&i.ExternalReference,
+		&i.TransferID,
+		&i.Memo,
+		&i.BalanceAfter,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -86,6 +143,209 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Type,
+			&i.ExternalReference,
+			&i.TransferID,
+			&i.Memo,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+SELECT id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after FROM entries
+WHERE account_id = $1 AND id > $3
+ORDER BY id
+LIMIT $2
+`
+
+type ListEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	AfterID   int64 `json:"after_id"`
+}
+
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter, arg.AccountID, arg.Limit, arg.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Type,
+			&i.ExternalReference,
+			&i.TransferID,
+			&i.Memo,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesBefore = `-- name: ListEntriesBefore :many
+SELECT id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after FROM entries
+WHERE account_id = $1 AND id < $3
+ORDER BY id DESC
+LIMIT $2
+`
+
+type ListEntriesBeforeParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	BeforeID  int64 `json:"before_id"`
+}
+
+func (q *Queries) ListEntriesBefore(ctx context.Context, arg ListEntriesBeforeParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesBefore, arg.AccountID, arg.Limit, arg.BeforeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Type,
+			&i.ExternalReference,
+			&i.TransferID,
+			&i.Memo,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStatementEntries = `-- name: ListStatementEntries :many
+SELECT entries.id, entries.type, entries.amount, entries.balance_after, entries.memo, entries.external_reference,
+  entries.transfer_id, entries.created_at,
+  COALESCE(
+    CASE WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id
+    ELSE transfers.from_account_id END,
+    0
+  )::bigint AS counterparty_account_id
+FROM entries
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+WHERE entries.account_id = $1
+  AND entries.created_at >= $2
+  AND entries.created_at < $3
+ORDER BY entries.id
+`
+
+type ListStatementEntriesParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+type ListStatementEntriesRow struct {
+	ID                    int64          `json:"id"`
+	Type                  EntryType      `json:"type"`
+	Amount                int64          `json:"amount"`
+	BalanceAfter          int64          `json:"balance_after"`
+	Memo                  string         `json:"memo"`
+	ExternalReference     string         `json:"external_reference"`
+	TransferID            util.NullInt64 `json:"transfer_id"`
+	CreatedAt             time.Time      `json:"created_at"`
+	CounterpartyAccountID int64          `json:"counterparty_account_id"`
+}
+
+func (q *Queries) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStatementEntries, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStatementEntriesRow{}
+	for rows.Next() {
+		var i ListStatementEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Amount,
+			&i.BalanceAfter,
+			&i.Memo,
+			&i.ExternalReference,
+			&i.TransferID,
+			&i.CreatedAt,
+			&i.CounterpartyAccountID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferEntries = `-- name: ListTransferEntries :many
+SELECT id, account_id, amount, created_at, type, external_reference, transfer_id, memo, balance_after FROM entries
+WHERE transfer_id = $1::bigint
+ORDER BY id
+`
+
+func (q *Queries) ListTransferEntries(ctx context.Context, transferID int64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntries, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Type,
+			&i.ExternalReference,
+			&i.TransferID,
+			&i.Memo,
+			&i.BalanceAfter,
 		); err != nil {
 			return nil, err
 		}
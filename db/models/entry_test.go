@@ -13,8 +13,11 @@ import (
 func TestCreateEntry(t *testing.T) {
 	account := createRandomAccount(t, nil)
 	arg := CreateEntryParams{
-		AccountID: account.ID,
-		Amount:    -50,
+		AccountID:    account.ID,
+		Amount:       -50,
+		Type:         EntryTypeTransfer,
+		Memo:         util.RandomString(10),
+		BalanceAfter: account.Balance - 50,
 	}
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
 	require.NoError(t, err)
@@ -23,6 +26,9 @@ func TestCreateEntry(t *testing.T) {
 	require.WithinDuration(t, account.CreatedAt, entry.CreatedAt, time.Second)
 	require.Equal(t, arg.AccountID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.Memo, entry.Memo)
+	require.Equal(t, arg.BalanceAfter, entry.BalanceAfter)
+	require.False(t, entry.TransferID.Valid)
 }
 
 func TestGetEntry(t *testing.T) {
@@ -30,6 +36,7 @@ func TestGetEntry(t *testing.T) {
 	entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    util.RandomMoney(),
+		Type:      EntryTypeTransfer,
 	})
 	require.NoError(t, err)
 
@@ -45,6 +52,7 @@ func TestListEntries(t *testing.T) {
 	arg1 := CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    0,
+		Type:      EntryTypeTransfer,
 	}
 	for i := 0; i < 15; i++ {
 		arg1.Amount++
@@ -71,6 +79,7 @@ func TestDeleteEntry(t *testing.T) {
 	entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    util.RandomMoney(),
+		Type:      EntryTypeTransfer,
 	})
 	require.NoError(t, err)
 	err = testQueries.DeleteEntry(context.Background(), entry.ID)
@@ -79,3 +88,34 @@ func TestDeleteEntry(t *testing.T) {
 	require.Empty(t, deletedEntry)
 	require.ErrorIs(t, sql.ErrNoRows, err)
 }
+
+func TestListEntriesKeyset(t *testing.T) {
+	account := createRandomAccount(t, nil)
+	entries := make([]Entry, 5)
+	for i := range entries {
+		var err error
+		entries[i], err = testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    int64(i + 1),
+			Type:      EntryTypeTransfer,
+		})
+		require.NoError(t, err)
+	}
+
+	after, err := testQueries.ListEntriesAfter(context.Background(), ListEntriesAfterParams{
+		AccountID: account.ID,
+		Limit:     2,
+		AfterID:   entries[1].ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, entries[2:4], after)
+
+	// Rows before the cursor come back nearest first
+	before, err := testQueries.ListEntriesBefore(context.Background(), ListEntriesBeforeParams{
+		AccountID: account.ID,
+		Limit:     2,
+		BeforeID:  entries[3].ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Entry{entries[2], entries[1]}, before)
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T, expiresAt time.Time) IdempotencyKey {
+	user := createRandomUser(t, nil)
+	arg := CreateIdempotencyKeyParams{
+		Key:         util.RandomString(16),
+		Username:    user.Username,
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   expiresAt,
+	}
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Key, key.Key)
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.Zero(t, key.ResponseStatus)
+	require.Empty(t, key.ResponseBody)
+	require.WithinDuration(t, arg.ExpiresAt, key.ExpiresAt, time.Second)
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	createRandomIdempotencyKey(t, time.Now().Add(time.Hour))
+}
+
+func TestCreateIdempotencyKeyConflict(t *testing.T) {
+	key := createRandomIdempotencyKey(t, time.Now().Add(time.Hour))
+
+	// A live key can't be claimed again
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Key:         key.Key,
+		Username:    key.Username,
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateIdempotencyKeyExpired(t *testing.T) {
+	key := createRandomIdempotencyKey(t, time.Now().Add(-time.Minute))
+
+	// An expired key is taken over by the new request
+	arg := CreateIdempotencyKeyParams{
+		Key:         key.Key,
+		Username:    key.Username,
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	newKey, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, newKey.RequestHash)
+	require.WithinDuration(t, arg.ExpiresAt, newKey.ExpiresAt, time.Second)
+}
+
+func TestUpdateIdempotencyKeyResponse(t *testing.T) {
+	key := createRandomIdempotencyKey(t, time.Now().Add(time.Hour))
+	arg := UpdateIdempotencyKeyResponseParams{
+		Username:       key.Username,
+		Key:            key.Key,
+		ResponseStatus: 200,
+		ResponseBody:   []byte(`{"id":1}`),
+	}
+	err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), arg)
+	require.NoError(t, err)
+
+	updatedKey, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.ResponseStatus, updatedKey.ResponseStatus)
+	require.Equal(t, arg.ResponseBody, updatedKey.ResponseBody)
+}
+
+func TestDeleteIdempotencyKey(t *testing.T) {
+	key := createRandomIdempotencyKey(t, time.Now().Add(time.Hour))
+	err := testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.NoError(t, err)
+
+	deletedKey, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.Empty(t, deletedKey)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	expired := createRandomIdempotencyKey(t, time.Now().Add(-time.Minute))
+	live := createRandomIdempotencyKey(t, time.Now().Add(time.Hour))
+
+	require.NoError(t, testQueries.DeleteExpiredIdempotencyKeys(context.Background()))
+
+	_, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{Username: expired.Username, Key: expired.Key})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{Username: live.Username, Key: live.Key})
+	require.NoError(t, err)
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+// SystemUsername owns the bank's ledger accounts, one per currency, which take the other side of cash
+// movements and currency exchanges. It has no usable password.
+const SystemUsername = "_bank"
+
+// ErrUnbalancedEntries is returned, and the transaction rolled back, when the entries written by a store
+// transaction don't sum to zero in some currency
+type ErrUnbalancedEntries struct {
+	Currency string
+	Sum      int64
+}
+
+func (e *ErrUnbalancedEntries) Error() string {
+	return fmt.Sprintf("entries in %s sum to %d, not zero", e.Currency, e.Sum)
+}
+
+// posting is an entry to write and apply to the balance of its account
+type posting struct {
+	AccountID         int64
+	Amount            int64
+	Type              EntryType
+	ExternalReference string
+}
+
+// ledgerAccountID returns the id of the bank's ledger account in currency, opening it the first time the currency
+// is used, so that supporting a currency doesn't take a migration.
+//
+// Every cash movement and exchange in a currency updates the balance of its ledger account, which serializes them
+// on its row lock until they commit. A higher throughput per currency would take several ledger accounts each.
+func ledgerAccountID(ctx context.Context, q *Queries, currency string) (int64, error) {
+	arg := GetAccountByOwnerAndCurrencyParams{Owner: SystemUsername, Currency: currency}
+	account, err := q.GetAccountByOwnerAndCurrency(ctx, arg)
+	if err == sql.ErrNoRows {
+		// A concurrent transaction opening it too makes this wait for it, and then do nothing
+		if err = q.CreateAccountIfNotExists(ctx, CreateAccountIfNotExistsParams(arg)); err != nil {
+			return 0, fmt.Errorf("cannot open the ledger account in %s: %w", currency, err)
+		}
+		account, err = q.GetAccountByOwnerAndCurrency(ctx, arg)
+	}
+	return account.ID, err
+}
+
+// lockAccounts takes row locks on the accounts of postings in ID order, to prevent deadlock, and returns them by ID
+func lockAccounts(ctx context.Context, q *Queries, postings []posting) (map[int64]Account, error) {
+	ids := make([]int64, 0, len(postings))
+	for _, p := range postings {
+		ids = append(ids, p.AccountID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		if _, ok := accounts[id]; ok {
+			continue
+		}
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// postEntries applies postings to the balances of the locked accounts, updating them in place, and writes
+// their entries. It fails with *ErrUnbalancedEntries unless the entries sum to zero in each currency.
+func postEntries(
+	ctx context.Context,
+	q *Queries,
+	accounts map[int64]Account,
+	postings []posting,
+	transferID util.NullInt64,
+	memo string,
+) ([]Entry, error) {
+	entries := make([]Entry, 0, len(postings))
+	for _, p := range postings {
+		account, err := q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			Amount: p.Amount,
+			ID:     p.AccountID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		accounts[p.AccountID] = account
+
+		entry, err := q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:         p.AccountID,
+			Amount:            p.Amount,
+			Type:              p.Type,
+			ExternalReference: p.ExternalReference,
+			TransferID:        transferID,
+			Memo:              memo,
+			BalanceAfter:      account.Balance,
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, checkBalanced(accounts, entries)
+}
+
+// checkBalanced verifies the entries sum to zero in each currency
+func checkBalanced(accounts map[int64]Account, entries []Entry) error {
+	sums := make(map[string]int64)
+	for _, entry := range entries {
+		sums[accounts[entry.AccountID].Currency] += entry.Amount
+	}
+	currencies := make([]string, 0, len(sums))
+	for currency := range sums {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		if sums[currency] != 0 {
+			return &ErrUnbalancedEntries{Currency: currency, Sum: sums[currency]}
+		}
+	}
+	return nil
+}
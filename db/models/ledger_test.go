@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBalanced(t *testing.T) {
+	accounts := map[int64]Account{
+		1: {ID: 1, Currency: util.USD},
+		2: {ID: 2, Currency: util.USD},
+		3: {ID: 3, Currency: util.EUR},
+	}
+
+	require.NoError(t, checkBalanced(accounts, []Entry{
+		{AccountID: 1, Amount: -10},
+		{AccountID: 2, Amount: 10},
+	}))
+
+	// Amounts in different currencies don't offset each other
+	err := checkBalanced(accounts, []Entry{
+		{AccountID: 1, Amount: -10},
+		{AccountID: 3, Amount: 10},
+	})
+	var unbalancedErr *ErrUnbalancedEntries
+	require.True(t, errors.As(err, &unbalancedErr))
+	require.Equal(t, util.EUR, unbalancedErr.Currency)
+	require.Equal(t, int64(10), unbalancedErr.Sum)
+}
+
+func TestLedgerAccountID(t *testing.T) {
+	// A currency without a ledger account yet gets one the first time it is used, and keeps it
+	currency := strings.ToUpper(util.RandomString(3))
+	id, err := ledgerAccountID(context.Background(), testQueries, currency)
+	require.NoError(t, err)
+	account, err := testQueries.GetAccount(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, SystemUsername, account.Owner)
+	require.Equal(t, currency, account.Currency)
+	require.Zero(t, account.Balance)
+
+	again, err := ledgerAccountID(context.Background(), testQueries, currency)
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+}
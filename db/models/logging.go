@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+// defaultSlowQueryThreshold is used when NewStore isn't given WithSlowQueryThreshold
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// loggingDBTX logs queries slower than threshold with the logger of the query context
+type loggingDBTX struct {
+	DBTX
+	threshold time.Duration
+}
+
+func (db loggingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer db.logSlow(ctx, query, time.Now())
+	return db.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (db loggingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.logSlow(ctx, query, time.Now())
+	return db.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (db loggingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer db.logSlow(ctx, query, time.Now())
+	return db.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+func (db loggingDBTX) logSlow(ctx context.Context, query string, start time.Time) {
+	latency := time.Since(start)
+	if db.threshold <= 0 || latency < db.threshold {
+		return
+	}
+	util.LoggerFromContext(ctx).Warn().
+		Str("query", queryName(query)).
+		Dur("latency", latency).
+		Msg("slow query")
+}
+
+// queryName returns the sqlc query name from its "-- name: GetAccount :one" header,
+// or the first line of the query if there is none
+func queryName(query string) string {
+	line := strings.TrimSpace(strings.SplitN(query, "\n", 2)[0])
+	if !strings.HasPrefix(line, "-- name:") {
+		return line
+	}
+	if fields := strings.Fields(strings.TrimPrefix(line, "-- name:")); len(fields) > 0 {
+		return fields[0]
+	}
+	return line
+}
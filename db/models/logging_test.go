@@ -0,0 +1,46 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepyDBTX takes latency to run any statement
+type sleepyDBTX struct {
+	DBTX
+	latency time.Duration
+}
+
+func (db sleepyDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	time.Sleep(db.latency)
+	return nil, nil
+}
+
+func TestLoggingDBTXSlowQuery(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := util.ContextWithLogger(context.Background(), zerolog.New(&buf))
+
+	db := loggingDBTX{DBTX: sleepyDBTX{latency: 10 * time.Millisecond}, threshold: time.Millisecond}
+	_, err := db.ExecContext(ctx, deleteAccount, 1)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `"query":"DeleteAccount"`)
+	require.Contains(t, buf.String(), `"message":"slow query"`)
+
+	buf.Reset()
+	db.threshold = time.Second
+	_, err = db.ExecContext(ctx, deleteAccount, 1)
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+}
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "SELECT 1", queryName("SELECT 1"))
+}
@@ -0,0 +1,110 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: login_attempt.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const addLoginFailure = `-- name: AddLoginFailure :one
+INSERT INTO login_attempts (key, failures, last_failed_at)
+VALUES ($1, 1, $2)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+      WHEN login_attempts.last_failed_at > $3 THEN login_attempts.failures + 1
+      ELSE 1
+    END,
+    previous_failed_at = CASE
+      WHEN login_attempts.last_failed_at > $3 THEN login_attempts.last_failed_at
+    END,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING key, failures, last_failed_at, previous_failed_at
+`
+
+type AddLoginFailureParams struct {
+	Key          string    `json:"key"`
+	FailedAt     time.Time `json:"failed_at"`
+	ForgetBefore time.Time `json:"forget_before"`
+}
+
+// Failures older than forget_before are forgotten, counting starts over
+func (q *Queries) AddLoginFailure(ctx context.Context, arg AddLoginFailureParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, addLoginFailure, arg.Key, arg.FailedAt, arg.ForgetBefore)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.PreviousFailedAt,
+	)
+	return i, err
+}
+
+const deleteLoginAttempt = `-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginAttempt, key)
+	return err
+}
+
+const deleteStaleLoginAttempts = `-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failed_at <= $1
+`
+
+func (q *Queries) DeleteStaleLoginAttempts(ctx context.Context, lastFailedAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteStaleLoginAttempts, lastFailedAt)
+	return err
+}
+
+const getLoginAttempt = `-- name: GetLoginAttempt :one
+SELECT key, failures, last_failed_at, previous_failed_at FROM login_attempts
+WHERE key = $1 AND last_failed_at > $2
+`
+
+type GetLoginAttemptParams struct {
+	Key          string    `json:"key"`
+	ForgetBefore time.Time `json:"forget_before"`
+}
+
+func (q *Queries) GetLoginAttempt(ctx context.Context, arg GetLoginAttemptParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, getLoginAttempt, arg.Key, arg.ForgetBefore)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailedAt,
+		&i.PreviousFailedAt,
+	)
+	return i, err
+}
+
+const removeLoginFailure = `-- name: RemoveLoginFailure :exec
+UPDATE login_attempts
+SET failures = failures - 1,
+    last_failed_at = CASE
+      WHEN last_failed_at = $1 THEN COALESCE(previous_failed_at, last_failed_at)
+      ELSE last_failed_at
+    END,
+    previous_failed_at = CASE
+      WHEN last_failed_at = $1 THEN NULL
+      ELSE previous_failed_at
+    END
+WHERE key = $2 AND failures > 0
+`
+
+type RemoveLoginFailureParams struct {
+	FailedAt time.Time `json:"failed_at"`
+	Key      string    `json:"key"`
+}
+
+// Unless another failure was added after the one at failed_at, the time of the failure before it is restored
+func (q *Queries) RemoveLoginFailure(ctx context.Context, arg RemoveLoginFailureParams) error {
+	_, err := q.db.ExecContext(ctx, removeLoginFailure, arg.FailedAt, arg.Key)
+	return err
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddLoginFailure(t *testing.T) {
+	key := "user:" + util.RandomName()
+	now := time.Now()
+
+	for i := int32(1); i <= 2; i++ {
+		attempt, err := testQueries.AddLoginFailure(context.Background(), AddLoginFailureParams{
+			Key:          key,
+			FailedAt:     now,
+			ForgetBefore: now.Add(-time.Minute),
+		})
+		require.NoError(t, err)
+		require.Equal(t, i, attempt.Failures)
+	}
+
+	attempt, err := testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: key, ForgetBefore: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), attempt.Failures)
+	require.WithinDuration(t, now, attempt.LastFailedAt, time.Second)
+
+	// Once the failures are forgotten, counting starts over
+	_, err = testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: key, ForgetBefore: now})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	later := now.Add(2 * time.Minute)
+	attempt, err = testQueries.AddLoginFailure(context.Background(), AddLoginFailureParams{
+		Key:          key,
+		FailedAt:     later,
+		ForgetBefore: later.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), attempt.Failures)
+
+	// Taking back the last failure restores the time of the one before
+	last, err := testQueries.AddLoginFailure(context.Background(), AddLoginFailureParams{
+		Key:          key,
+		FailedAt:     later.Add(30 * time.Second),
+		ForgetBefore: later.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), last.Failures)
+	arg := RemoveLoginFailureParams{Key: key, FailedAt: last.LastFailedAt}
+	require.NoError(t, testQueries.RemoveLoginFailure(context.Background(), arg))
+	attempt, err = testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: key, ForgetBefore: now})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), attempt.Failures)
+	require.WithinDuration(t, later, attempt.LastFailedAt, time.Second)
+
+	// Failures taken back don't go below zero, nor change the time of the last one without one before it
+	for i := 0; i < 2; i++ {
+		require.NoError(t, testQueries.RemoveLoginFailure(context.Background(), RemoveLoginFailureParams{Key: key, FailedAt: later}))
+	}
+	attempt, err = testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: key, ForgetBefore: now})
+	require.NoError(t, err)
+	require.Zero(t, attempt.Failures)
+	require.WithinDuration(t, later, attempt.LastFailedAt, time.Second)
+
+	require.NoError(t, testQueries.DeleteLoginAttempt(context.Background(), key))
+	_, err = testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: key, ForgetBefore: now.Add(-time.Minute)})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteStaleLoginAttempts(t *testing.T) {
+	stale, live := "ip:"+util.RandomName(), "ip:"+util.RandomName()
+	now := time.Now()
+	for key, failedAt := range map[string]time.Time{stale: now.Add(-time.Hour), live: now} {
+		_, err := testQueries.AddLoginFailure(context.Background(), AddLoginFailureParams{
+			Key:          key,
+			FailedAt:     failedAt,
+			ForgetBefore: failedAt.Add(-time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, testQueries.DeleteStaleLoginAttempts(context.Background(), now.Add(-time.Minute)))
+
+	// Even a query remembering everything no longer finds the stale failures
+	_, err := testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: stale, ForgetBefore: time.Time{}})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.GetLoginAttempt(context.Background(), GetLoginAttemptParams{Key: live, ForgetBefore: time.Time{}})
+	require.NoError(t, err)
+}
@@ -3,15 +3,109 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/harrychopra/go-api/util"
+)
+
+type EntryType string
+
+const (
+	EntryTypeTransfer   EntryType = "transfer"
+	EntryTypeDeposit    EntryType = "deposit"
+	EntryTypeWithdrawal EntryType = "withdrawal"
+	EntryTypeExchange   EntryType = "exchange"
+	EntryTypeFee        EntryType = "fee"
+	EntryTypeAdjustment EntryType = "adjustment"
 )
 
+func (e *EntryType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EntryType(s)
+	case string:
+		*e = EntryType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EntryType: %T", src)
+	}
+	return nil
+}
+
+type ScheduledTransferRunStatus string
+
+const (
+	ScheduledTransferRunStatusPending   ScheduledTransferRunStatus = "pending"
+	ScheduledTransferRunStatusSucceeded ScheduledTransferRunStatus = "succeeded"
+	ScheduledTransferRunStatusFailed    ScheduledTransferRunStatus = "failed"
+)
+
+func (e *ScheduledTransferRunStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledTransferRunStatus(s)
+	case string:
+		*e = ScheduledTransferRunStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledTransferRunStatus: %T", src)
+	}
+	return nil
+}
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferStatusActive    ScheduledTransferStatus = "active"
+	ScheduledTransferStatusPaused    ScheduledTransferStatus = "paused"
+	ScheduledTransferStatusCompleted ScheduledTransferStatus = "completed"
+	ScheduledTransferStatusCancelled ScheduledTransferStatus = "cancelled"
+)
+
+func (e *ScheduledTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledTransferStatus(s)
+	case string:
+		*e = ScheduledTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "dead"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
 type Account struct {
 	ID        int64     `json:"id"`
 	Owner     string    `json:"owner"`
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero the balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// frozen accounts can neither send nor receive money
+	IsFrozen bool `json:"is_frozen"`
 }
 
 type Entry struct {
@@ -20,6 +114,112 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	Type      EntryType `json:"type"`
+	// reference of the cash movement outside the bank, e.g. a teller receipt, empty for transfers
+	ExternalReference string `json:"external_reference"`
+	// transfer the entry is a leg of, if any
+	TransferID util.NullInt64 `json:"transfer_id"`
+	Memo       string         `json:"memo"`
+	// balance of the account once the entry was applied
+	BalanceAfter int64 `json:"balance_after"`
+}
+
+type IdempotencyKey struct {
+	Key      string `json:"key"`
+	Username string `json:"username"`
+	// sha256 of method, path and body
+	RequestHash string `json:"request_hash"`
+	// 0 while the original request is in flight
+	ResponseStatus int32     `json:"response_status"`
+	ResponseBody   []byte    `json:"response_body"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type LoginAttempt struct {
+	// username or client IP the failures are counted for, prefixed by its kind
+	Key          string    `json:"key"`
+	Failures     int32     `json:"failures"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	// last_failed_at before the last failure, restored if it is taken back
+	PreviousFailedAt sql.NullTime `json:"previous_failed_at"`
+}
+
+type OutboxEvent struct {
+	ID int64 `json:"id"`
+	// user whose webhooks receive the event
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// when deliveries were created for the webhooks of the owner
+	DispatchedAt sql.NullTime `json:"dispatched_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	HashedCode string    `json:"hashed_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RevokedToken struct {
+	// ID of the revoked token payload
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo"`
+	// cron expression, descriptor or @every interval, empty for a single transfer
+	Schedule string `json:"schedule"`
+	// time of the next run while active, of the last one otherwise
+	NextRunAt time.Time               `json:"next_run_at"`
+	Status    ScheduledTransferStatus `json:"status"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// next_run_at of the scheduled transfer when the run was claimed
+	ScheduledAt time.Time                  `json:"scheduled_at"`
+	Status      ScheduledTransferRunStatus `json:"status"`
+	TransferID  util.NullInt64             `json:"transfer_id"`
+	// why the transfer failed, empty unless failed
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Session struct {
+	// ID of the refresh token payload
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// sha256 of the refresh token
+	RefreshTokenHash string    `json:"refresh_token_hash"`
+	UserAgent        string    `json:"user_agent"`
+	ClientIp         string    `json:"client_ip"`
+	IsBlocked        bool      `json:"is_blocked"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type TotpSecret struct {
+	Username string `json:"username"`
+	// base32, only in use once the user confirmed it and is_mfa_enabled is set
+	Secret string `json:"secret"`
+	// time step of the last code accepted, codes of this step or before are replays
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -29,6 +229,12 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited, in the to account currency
+	ToAmount int64 `json:"to_amount"`
+	// units of the to currency bought by one unit of the from currency
+	ExchangeRate string `json:"exchange_rate"`
+	// rounding applied to the converted amount
+	Rounding string `json:"rounding"`
 }
 
 type User struct {
@@ -38,4 +244,47 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	IsMfaEnabled      bool      `json:"is_mfa_enabled"`
+}
+
+type UserTokenRevocation struct {
+	Username string `json:"username"`
+	// tokens issued at or before this time are revoked
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// address the code was sent to, only verified if still the address of the user
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+}
+
+type Webhook struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// key of the HMAC signature of the deliveries
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID        int64                 `json:"id"`
+	WebhookID int64                 `json:"webhook_id"`
+	EventID   int64                 `json:"event_id"`
+	Status    WebhookDeliveryStatus `json:"status"`
+	Attempts  int32                 `json:"attempts"`
+	// when a pending delivery is next attempted, or its attempt lease ends
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	LastError     string       `json:"last_error"`
+	DeliveredAt   sql.NullTime `json:"delivered_at"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
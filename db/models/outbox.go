@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Types of the events written to the outbox
+const (
+	EventAccountCreated  = "account.created"
+	EventTransferCreated = "transfer.created"
+)
+
+// writeOutboxEvent records an event of eventType for each of owners, in the transaction of q, so that it is
+// only delivered if the change it describes commits. Duplicate owners get a single event.
+func writeOutboxEvent(ctx context.Context, q *Queries, eventType string, payload interface{}, owners ...string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	written := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		// Ledger accounts have no webhooks
+		if written[owner] || owner == SystemUsername {
+			continue
+		}
+		if _, err := q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+			Owner:     owner,
+			EventType: eventType,
+			Payload:   data,
+		}); err != nil {
+			return err
+		}
+		written[owner] = true
+	}
+	return nil
+}
+
+// CreateAccountTx creates an account and its account.created event
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if account, err = q.CreateAccount(ctx, arg); err != nil {
+			return err
+		}
+		return writeOutboxEvent(ctx, q, EventAccountCreated, account, account.Owner)
+	})
+	return account, err
+}
+
+// DispatchOutboxTx creates a pending delivery of up to limit undispatched outbox events to each webhook of their
+// owners, skipping events locked by another dispatcher. It returns the number of events dispatched.
+func (store *SQLStore) DispatchOutboxTx(ctx context.Context, limit int32) (int, error) {
+	var dispatched int
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		events, err := q.ListUndispatchedOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if _, err := q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+				EventID: event.ID,
+				Owner:   event.Owner,
+			}); err != nil {
+				return err
+			}
+			if err := q.MarkOutboxEventDispatched(ctx, event.ID); err != nil {
+				return err
+			}
+		}
+		dispatched = len(events)
+		return nil
+	})
+	return dispatched, err
+}
+
+// ClaimWebhookDeliveriesTxParams is the input of ClaimWebhookDeliveriesTx
+type ClaimWebhookDeliveriesTxParams struct {
+	Now   time.Time
+	Limit int32
+	// LeaseUntil is when claimed deliveries whose outcome was not recorded are retried, by any dispatcher
+	LeaseUntil time.Time
+}
+
+// ClaimWebhookDeliveriesTx claims up to Limit pending deliveries due at Now, skipping those locked by another
+// dispatcher, and counts the attempt about to be made. Attempts is the count including it. As a dispatcher
+// stopping before it records an outcome leaves the delivery to be retried, events are delivered at least once.
+func (store *SQLStore) ClaimWebhookDeliveriesTx(ctx context.Context, arg ClaimWebhookDeliveriesTxParams) (
+	[]ListDueWebhookDeliveriesRow, error) {
+	var claimed []ListDueWebhookDeliveriesRow
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if claimed, err = q.ListDueWebhookDeliveries(ctx, ListDueWebhookDeliveriesParams{
+			Now:      arg.Now,
+			RowLimit: arg.Limit,
+		}); err != nil {
+			return err
+		}
+		for i := range claimed {
+			if err := q.StartWebhookDeliveryAttempt(ctx, StartWebhookDeliveryAttemptParams{
+				ID:         claimed[i].ID,
+				LeaseUntil: arg.LeaseUntil,
+			}); err != nil {
+				return err
+			}
+			claimed[i].Attempts++
+		}
+		return nil
+	})
+	return claimed, err
+}
@@ -0,0 +1,279 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events(owner, event_type, payload)
+VALUES ($1, $2, $3)
+RETURNING id, owner, event_type, payload, created_at, dispatched_at
+`
+
+type CreateOutboxEventParams struct {
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error) {
+	row := q.db.QueryRowContext(ctx, createOutboxEvent, arg.Owner, arg.EventType, arg.Payload)
+	var i OutboxEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.EventType,
+		&i.Payload,
+		&i.CreatedAt,
+		&i.DispatchedAt,
+	)
+	return i, err
+}
+
+const createWebhookDeliveries = `-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries(webhook_id, event_id)
+SELECT webhooks.id, $1::bigint
+FROM webhooks
+WHERE webhooks.owner = $2
+ON CONFLICT DO NOTHING
+`
+
+type CreateWebhookDeliveriesParams struct {
+	EventID int64  `json:"event_id"`
+	Owner   string `json:"owner"`
+}
+
+// One delivery of the event to each webhook of its owner
+func (q *Queries) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createWebhookDeliveries, arg.EventID, arg.Owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listDueWebhookDeliveries = `-- name: ListDueWebhookDeliveries :many
+SELECT
+  webhook_deliveries.id,
+  webhook_deliveries.webhook_id,
+  webhook_deliveries.attempts,
+  webhooks.url,
+  webhooks.secret,
+  outbox_events.id AS event_id,
+  outbox_events.event_type,
+  outbox_events.payload,
+  outbox_events.created_at AS event_created_at
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+JOIN outbox_events ON outbox_events.id = webhook_deliveries.event_id
+WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= $1
+ORDER BY webhook_deliveries.next_attempt_at
+LIMIT $2
+FOR UPDATE OF webhook_deliveries SKIP LOCKED
+`
+
+type ListDueWebhookDeliveriesParams struct {
+	Now      time.Time `json:"now"`
+	RowLimit int32     `json:"row_limit"`
+}
+
+type ListDueWebhookDeliveriesRow struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Attempts       int32           `json:"attempts"`
+	Url            string          `json:"url"`
+	Secret         string          `json:"secret"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	EventCreatedAt time.Time       `json:"event_created_at"`
+}
+
+func (q *Queries) ListDueWebhookDeliveries(ctx context.Context, arg ListDueWebhookDeliveriesParams) ([]ListDueWebhookDeliveriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDueWebhookDeliveries, arg.Now, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDueWebhookDeliveriesRow{}
+	for rows.Next() {
+		var i ListDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Attempts,
+			&i.Url,
+			&i.Secret,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.EventCreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUndispatchedOutboxEvents = `-- name: ListUndispatchedOutboxEvents :many
+SELECT id, owner, event_type, payload, created_at, dispatched_at FROM outbox_events
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+// Events locked by another dispatcher are skipped rather than waited for
+func (q *Queries) ListUndispatchedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listUndispatchedOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OutboxEvent{}
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.EventType,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.DispatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_id, status, attempts, next_attempt_at, last_error, delivered_at, created_at FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY id DESC
+LIMIT $2
+`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID int64 `json:"webhook_id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, arg.WebhookID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.EventID,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventDispatched = `-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+SET dispatched_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventDispatched(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventDispatched, id)
+	return err
+}
+
+const startWebhookDeliveryAttempt = `-- name: StartWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id = $1
+`
+
+type StartWebhookDeliveryAttemptParams struct {
+	ID         int64     `json:"id"`
+	LeaseUntil time.Time `json:"lease_until"`
+}
+
+// Leases the delivery until lease_until, after which another dispatcher may retry it
+func (q *Queries) StartWebhookDeliveryAttempt(ctx context.Context, arg StartWebhookDeliveryAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, startWebhookDeliveryAttempt, arg.ID, arg.LeaseUntil)
+	return err
+}
+
+const updateWebhookDelivery = `-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $2, next_attempt_at = $3, last_error = $4, delivered_at = $5
+WHERE id = $1
+RETURNING id, webhook_id, event_id, status, attempts, next_attempt_at, last_error, delivered_at, created_at
+`
+
+type UpdateWebhookDeliveryParams struct {
+	ID            int64                 `json:"id"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	LastError     string                `json:"last_error"`
+	DeliveredAt   sql.NullTime          `json:"delivered_at"`
+}
+
+func (q *Queries) UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookDelivery,
+		arg.ID,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.LastError,
+		arg.DeliveredAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.EventID,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
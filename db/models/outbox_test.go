@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, owner string) Webhook {
+	arg := CreateWebhookParams{
+		Owner:  owner,
+		Url:    "https://example.com/" + util.RandomString(8),
+		Secret: util.RandomString(64),
+	}
+	webhook, err := testQueries.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, webhook.Owner)
+	require.Equal(t, arg.Url, webhook.Url)
+	require.Equal(t, arg.Secret, webhook.Secret)
+	return webhook
+}
+
+// dispatchOutbox fans out every pending event of the outbox
+func dispatchOutbox(t *testing.T) {
+	for {
+		n, err := testStore.DispatchOutboxTx(context.Background(), 100)
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func TestTransferTxOutbox(t *testing.T) {
+	account1 := createFundedAccount(t, 100)
+	account2 := createRandomAccount(t, &CreateAccountParams{Currency: account1.Currency})
+	webhook1 := createRandomWebhook(t, account1.Owner)
+	webhook2 := createRandomWebhook(t, account2.Owner)
+
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	dispatchOutbox(t)
+
+	// Each side of the transfer gets the event on its webhook
+	for _, webhook := range []Webhook{webhook1, webhook2} {
+		deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+			WebhookID: webhook.ID,
+			Limit:     10,
+		})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, WebhookDeliveryStatusPending, deliveries[0].Status)
+		require.Zero(t, deliveries[0].Attempts)
+	}
+
+	claimed, err := testStore.ClaimWebhookDeliveriesTx(context.Background(), ClaimWebhookDeliveriesTxParams{
+		Now:        time.Now(),
+		Limit:      1000,
+		LeaseUntil: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	var found bool
+	for _, delivery := range claimed {
+		if delivery.WebhookID != webhook1.ID {
+			continue
+		}
+		found = true
+		require.Equal(t, int32(1), delivery.Attempts)
+		require.Equal(t, webhook1.Url, delivery.Url)
+		require.Equal(t, EventTransferCreated, delivery.EventType)
+		var transfer Transfer
+		require.NoError(t, json.Unmarshal(delivery.Payload, &transfer))
+		require.Equal(t, result.Transfer.ID, transfer.ID)
+	}
+	require.True(t, found)
+
+	// Leased deliveries aren't claimed again until the lease ends
+	claimed, err = testStore.ClaimWebhookDeliveriesTx(context.Background(), ClaimWebhookDeliveriesTxParams{
+		Now:        time.Now(),
+		Limit:      1000,
+		LeaseUntil: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	for _, delivery := range claimed {
+		require.NotEqual(t, webhook1.ID, delivery.WebhookID)
+	}
+}
+
+func TestCreateAccountTxOutbox(t *testing.T) {
+	user := createRandomUser(t, nil)
+	webhook := createRandomWebhook(t, user.Username)
+
+	account, err := testStore.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, account.Owner)
+	dispatchOutbox(t)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	// Deleting the webhook drops its deliveries
+	require.NoError(t, testQueries.DeleteWebhook(context.Background(), webhook.ID))
+	deliveries, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
@@ -0,0 +1,86 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: revocation.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRevokedToken = `-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens(id, username, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING
+`
+
+type CreateRevokedTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createRevokedToken, arg.ID, arg.Username, arg.ExpiresAt)
+	return err
+}
+
+const deleteExpiredTokenRevocations = `-- name: DeleteExpiredTokenRevocations :exec
+WITH deleted_tokens AS (
+  DELETE FROM revoked_tokens
+  WHERE revoked_tokens.expires_at <= now()
+)
+DELETE FROM user_token_revocations
+WHERE user_token_revocations.expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredTokenRevocations(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredTokenRevocations)
+	return err
+}
+
+const isTokenRevoked = `-- name: IsTokenRevoked :one
+SELECT (EXISTS (
+  SELECT 1 FROM revoked_tokens
+  WHERE revoked_tokens.id = $1 AND revoked_tokens.expires_at > now()
+) OR EXISTS (
+  SELECT 1 FROM user_token_revocations
+  WHERE user_token_revocations.username = $2
+  AND user_token_revocations.revoked_before >= $3
+  AND user_token_revocations.expires_at > now()
+))::bool AS revoked
+`
+
+type IsTokenRevokedParams struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+func (q *Queries) IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isTokenRevoked, arg.ID, arg.Username, arg.IssuedAt)
+	var revoked bool
+	err := row.Scan(&revoked)
+	return revoked, err
+}
+
+const upsertUserTokenRevocation = `-- name: UpsertUserTokenRevocation :exec
+INSERT INTO user_token_revocations(username, revoked_before, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (username) DO UPDATE
+SET revoked_before = EXCLUDED.revoked_before,
+    expires_at = EXCLUDED.expires_at
+`
+
+type UpsertUserTokenRevocationParams struct {
+	Username      string    `json:"username"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UpsertUserTokenRevocation(ctx context.Context, arg UpsertUserTokenRevocationParams) error {
+	_, err := q.db.ExecContext(ctx, upsertUserTokenRevocation, arg.Username, arg.RevokedBefore, arg.ExpiresAt)
+	return err
+}
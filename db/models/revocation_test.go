@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokedToken(t *testing.T) {
+	user := createRandomUser(t, nil)
+	arg := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	}
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        arg.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestUserTokenRevocation(t *testing.T) {
+	user := createRandomUser(t, nil)
+	revokedBefore := time.Now()
+	err := testQueries.UpsertUserTokenRevocation(context.Background(), UpsertUserTokenRevocationParams{
+		Username:      user.Username,
+		RevokedBefore: revokedBefore,
+		ExpiresAt:     revokedBefore.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// Tokens issued before the revocation are revoked
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: revokedBefore.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// Tokens issued after it are not
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: revokedBefore.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestDeleteExpiredTokenRevocations(t *testing.T) {
+	user := createRandomUser(t, nil)
+	arg := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	}
+	err := testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        arg.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	err = testQueries.DeleteExpiredTokenRevocations(context.Background())
+	require.NoError(t, err)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,376 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers(owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner         string    `json:"owner"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Memo          string    `json:"memo"`
+	Schedule      string    `json:"schedule"`
+	NextRunAt     time.Time `json:"next_run_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.Memo,
+		arg.Schedule,
+		arg.NextRunAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Memo,
+		&i.Schedule,
+		&i.NextRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs(scheduled_transfer_id, scheduled_at)
+VALUES ($1, $2)
+RETURNING id, scheduled_transfer_id, scheduled_at, status, transfer_id, error, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64     `json:"scheduled_transfer_id"`
+	ScheduledAt         time.Time `json:"scheduled_at"`
+}
+
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransferRun, arg.ScheduledTransferID, arg.ScheduledAt)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.ScheduledAt,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at FROM scheduled_transfers
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Memo,
+		&i.Schedule,
+		&i.NextRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listDueScheduledTransfers = `-- name: ListDueScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= $2
+ORDER BY next_run_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+type ListDueScheduledTransfersParams struct {
+	Limit int32     `json:"limit"`
+	Now   time.Time `json:"now"`
+}
+
+// Rows claimed by another worker are skipped rather than waited for
+func (q *Queries) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listDueScheduledTransfers, arg.Limit, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Memo,
+			&i.Schedule,
+			&i.NextRunAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+SELECT id, scheduled_transfer_id, scheduled_at, status, transfer_id, error, created_at FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	Limit               int32 `json:"limit"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransferRuns, arg.ScheduledTransferID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.ScheduledAt,
+			&i.Status,
+			&i.TransferID,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfersAfter = `-- name: ListScheduledTransfersAfter :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at FROM scheduled_transfers
+WHERE owner = $1 AND id > $3
+ORDER BY id
+LIMIT $2
+`
+
+type ListScheduledTransfersAfterParams struct {
+	Owner   string `json:"owner"`
+	Limit   int32  `json:"limit"`
+	AfterID int64  `json:"after_id"`
+}
+
+func (q *Queries) ListScheduledTransfersAfter(ctx context.Context, arg ListScheduledTransfersAfterParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfersAfter, arg.Owner, arg.Limit, arg.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Memo,
+			&i.Schedule,
+			&i.NextRunAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfersBefore = `-- name: ListScheduledTransfersBefore :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at FROM scheduled_transfers
+WHERE owner = $1 AND id < $3
+ORDER BY id DESC
+LIMIT $2
+`
+
+type ListScheduledTransfersBeforeParams struct {
+	Owner    string `json:"owner"`
+	Limit    int32  `json:"limit"`
+	BeforeID int64  `json:"before_id"`
+}
+
+func (q *Queries) ListScheduledTransfersBefore(ctx context.Context, arg ListScheduledTransfersBeforeParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfersBefore, arg.Owner, arg.Limit, arg.BeforeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Memo,
+			&i.Schedule,
+			&i.NextRunAt,
+			&i.Status,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $2, memo = $3, schedule = $4, next_run_at = $5, status = $6
+WHERE id = $1 AND next_run_at = $7 AND status = $8
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at, status, created_at
+`
+
+type UpdateScheduledTransferParams struct {
+	ID            int64                   `json:"id"`
+	Amount        int64                   `json:"amount"`
+	Memo          string                  `json:"memo"`
+	Schedule      string                  `json:"schedule"`
+	NextRunAt     time.Time               `json:"next_run_at"`
+	Status        ScheduledTransferStatus `json:"status"`
+	ReadNextRunAt time.Time               `json:"read_next_run_at"`
+	ReadStatus    ScheduledTransferStatus `json:"read_status"`
+}
+
+// Only if no run was claimed and the status wasn't changed since the row was read, no rows otherwise
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransfer,
+		arg.ID,
+		arg.Amount,
+		arg.Memo,
+		arg.Schedule,
+		arg.NextRunAt,
+		arg.Status,
+		arg.ReadNextRunAt,
+		arg.ReadStatus,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Memo,
+		&i.Schedule,
+		&i.NextRunAt,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateScheduledTransferRun = `-- name: UpdateScheduledTransferRun :one
+UPDATE scheduled_transfer_runs
+SET status = $2, transfer_id = $3, error = $4
+WHERE id = $1
+RETURNING id, scheduled_transfer_id, scheduled_at, status, transfer_id, error, created_at
+`
+
+type UpdateScheduledTransferRunParams struct {
+	ID         int64                      `json:"id"`
+	Status     ScheduledTransferRunStatus `json:"status"`
+	TransferID util.NullInt64             `json:"transfer_id"`
+	Error      string                     `json:"error"`
+}
+
+func (q *Queries) UpdateScheduledTransferRun(ctx context.Context, arg UpdateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransferRun,
+		arg.ID,
+		arg.Status,
+		arg.TransferID,
+		arg.Error,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.ScheduledAt,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, schedule string, nextRunAt time.Time) ScheduledTransfer {
+	account1 := createRandomAccount(t, nil)
+	account2 := createRandomAccount(t, &CreateAccountParams{Currency: account1.Currency})
+	arg := CreateScheduledTransferParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Currency:      account1.Currency,
+		Schedule:      schedule,
+		NextRunAt:     nextRunAt,
+	}
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.Schedule, scheduledTransfer.Schedule)
+	require.Equal(t, ScheduledTransferStatusActive, scheduledTransfer.Status)
+	require.WithinDuration(t, nextRunAt, scheduledTransfer.NextRunAt, time.Second)
+	return scheduledTransfer
+}
+
+func TestClaimScheduledTransfersTx(t *testing.T) {
+	now := time.Now()
+	single := createRandomScheduledTransfer(t, "", now.Add(-time.Minute))
+	// Missed runs are skipped over
+	recurring := createRandomScheduledTransfer(t, "@every 1h", now.Add(-3*time.Hour-time.Minute))
+	future := createRandomScheduledTransfer(t, "", now.Add(time.Hour))
+
+	// Concurrent workers claim each due run once
+	n := 3
+	errs := make(chan error)
+	results := make(chan []ClaimedScheduledTransfer)
+	for i := 0; i < n; i++ {
+		go func() {
+			claimed, err := testStore.ClaimScheduledTransfersTx(context.Background(), ClaimScheduledTransfersTxParams{
+				Now:   now,
+				Limit: 100,
+			})
+			errs <- err
+			results <- claimed
+		}()
+	}
+	runs := make(map[int64]ScheduledTransferRun)
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		for _, claim := range <-results {
+			_, ok := runs[claim.ScheduledTransfer.ID]
+			require.False(t, ok)
+			runs[claim.ScheduledTransfer.ID] = claim.Run
+		}
+	}
+	require.Contains(t, runs, single.ID)
+	require.Contains(t, runs, recurring.ID)
+	require.NotContains(t, runs, future.ID)
+	require.Equal(t, ScheduledTransferRunStatusPending, runs[single.ID].Status)
+	require.WithinDuration(t, single.NextRunAt, runs[single.ID].ScheduledAt, time.Second)
+
+	got, err := testQueries.GetScheduledTransfer(context.Background(), single.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusCompleted, got.Status)
+
+	got, err = testQueries.GetScheduledTransfer(context.Background(), recurring.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, got.Status)
+	require.True(t, got.NextRunAt.After(now))
+	require.WithinDuration(t, now.Add(time.Hour), got.NextRunAt, time.Hour)
+
+	got, err = testQueries.GetScheduledTransfer(context.Background(), future.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, got.Status)
+}
+
+func TestUpdateScheduledTransferStale(t *testing.T) {
+	read := createRandomScheduledTransfer(t, "@every 1h", time.Now().Add(-time.Minute))
+	_, err := testStore.ClaimScheduledTransfersTx(context.Background(), ClaimScheduledTransfersTxParams{
+		Now:   time.Now(),
+		Limit: 100,
+	})
+	require.NoError(t, err)
+
+	// The run claimed since the row was read isn't undone
+	arg := UpdateScheduledTransferParams{
+		ID:            read.ID,
+		Amount:        20,
+		Memo:          read.Memo,
+		Schedule:      read.Schedule,
+		NextRunAt:     read.NextRunAt,
+		Status:        read.Status,
+		ReadNextRunAt: read.NextRunAt,
+		ReadStatus:    read.Status,
+	}
+	_, err = testQueries.UpdateScheduledTransfer(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	current, err := testQueries.GetScheduledTransfer(context.Background(), read.ID)
+	require.NoError(t, err)
+	require.True(t, current.NextRunAt.After(read.NextRunAt))
+	arg.NextRunAt = current.NextRunAt
+	arg.ReadNextRunAt = current.NextRunAt
+	updated, err := testQueries.UpdateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), updated.Amount)
+}
+
+func TestNextScheduledRun(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	next, status := nextScheduledRun(ScheduledTransfer{NextRunAt: now}, now)
+	require.Equal(t, ScheduledTransferStatusCompleted, status)
+	require.Equal(t, now, next)
+
+	next, status = nextScheduledRun(ScheduledTransfer{Schedule: "@monthly", NextRunAt: now}, now)
+	require.Equal(t, ScheduledTransferStatusActive, status)
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), next)
+
+	// Runs missed before now are skipped
+	missed := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	next, status = nextScheduledRun(ScheduledTransfer{Schedule: "@monthly", NextRunAt: missed}, now)
+	require.Equal(t, ScheduledTransferStatusActive, status)
+	require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), next)
+
+	_, status = nextScheduledRun(ScheduledTransfer{Schedule: "not a schedule", NextRunAt: now}, now)
+	require.Equal(t, ScheduledTransferStatusPaused, status)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+// ClaimScheduledTransfersTxParams is the input of ClaimScheduledTransfersTx
+type ClaimScheduledTransfersTxParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+// ClaimedScheduledTransfer is a due scheduled transfer, as it was before it was claimed, and its pending run
+type ClaimedScheduledTransfer struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// ClaimScheduledTransfersTx locks up to Limit active scheduled transfers due at Now, skipping those locked by
+// another worker, records a pending run for each and moves them on to their next run. As the claim commits before
+// the transfers are made, each run is made at most once: a worker stopping before it records the outcome of a run
+// leaves it pending.
+func (store *SQLStore) ClaimScheduledTransfersTx(ctx context.Context, arg ClaimScheduledTransfersTxParams) (
+	[]ClaimedScheduledTransfer, error) {
+	var claimed []ClaimedScheduledTransfer
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		due, err := q.ListDueScheduledTransfers(ctx, ListDueScheduledTransfersParams{
+			Now:   arg.Now,
+			Limit: arg.Limit,
+		})
+		if err != nil {
+			return err
+		}
+		claimed = make([]ClaimedScheduledTransfer, 0, len(due))
+		for _, scheduledTransfer := range due {
+			run, err := q.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+				ScheduledTransferID: scheduledTransfer.ID,
+				ScheduledAt:         scheduledTransfer.NextRunAt,
+			})
+			if err != nil {
+				return err
+			}
+			nextRunAt, status := nextScheduledRun(scheduledTransfer, arg.Now)
+			if _, err = q.UpdateScheduledTransfer(ctx, UpdateScheduledTransferParams{
+				ID:            scheduledTransfer.ID,
+				Amount:        scheduledTransfer.Amount,
+				Memo:          scheduledTransfer.Memo,
+				Schedule:      scheduledTransfer.Schedule,
+				NextRunAt:     nextRunAt,
+				Status:        status,
+				ReadNextRunAt: scheduledTransfer.NextRunAt,
+				ReadStatus:    scheduledTransfer.Status,
+			}); err != nil {
+				return err
+			}
+			claimed = append(claimed, ClaimedScheduledTransfer{ScheduledTransfer: scheduledTransfer, Run: run})
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// nextScheduledRun returns when a scheduled transfer being run at now runs next, and its status then. Runs missed
+// while no worker was up are skipped rather than caught up on.
+func nextScheduledRun(scheduledTransfer ScheduledTransfer, now time.Time) (time.Time, ScheduledTransferStatus) {
+	if len(scheduledTransfer.Schedule) == 0 {
+		return scheduledTransfer.NextRunAt, ScheduledTransferStatusCompleted
+	}
+	schedule, err := util.ParseSchedule(scheduledTransfer.Schedule)
+	if err != nil {
+		// Schedules are validated when set, so this one must have been written around the API
+		return scheduledTransfer.NextRunAt, ScheduledTransferStatusPaused
+	}
+	next := schedule.Next(scheduledTransfer.NextRunAt)
+	if !next.After(now) {
+		next = schedule.Next(now)
+	}
+	if next.IsZero() {
+		return scheduledTransfer.NextRunAt, ScheduledTransferStatusCompleted
+	}
+	return next, ScheduledTransferStatusActive
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t, nil)
+	arg := CreateSessionParams{
+		ID:               uuid.New(),
+		Username:         user.Username,
+		RefreshTokenHash: util.RandomString(64),
+		UserAgent:        util.RandomString(8),
+		ClientIp:         "127.0.0.1",
+		IsBlocked:        false,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshTokenHash, session.RefreshTokenHash)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	sessionA := createRandomSession(t)
+	sessionB, err := testQueries.GetSession(context.Background(), sessionA.ID)
+	require.NoError(t, err)
+	require.Equal(t, sessionA.ID, sessionB.ID)
+	require.Equal(t, sessionA.Username, sessionB.Username)
+	require.Equal(t, sessionA.RefreshTokenHash, sessionB.RefreshTokenHash)
+	require.WithinDuration(t, sessionA.ExpiresAt, sessionB.ExpiresAt, time.Second)
+}
+
+func TestBlockSession(t *testing.T) {
+	session := createRandomSession(t)
+	err := testQueries.BlockSession(context.Background(), session.ID)
+	require.NoError(t, err)
+
+	blockedSession, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blockedSession.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	session := createRandomSession(t)
+	err := testQueries.BlockUserSessions(context.Background(), session.Username)
+	require.NoError(t, err)
+
+	blockedSession, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, blockedSession.IsBlocked)
+}
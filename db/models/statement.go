@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const defaultStatementBatchSize = 500
+
+// StatementTxParams is the input of StatementTx
+type StatementTxParams struct {
+	AccountID int64
+	From      time.Time // Inclusive
+	To        time.Time // Exclusive
+	BatchSize int32     // Entries fetched from the cursor at a time, 500 if zero
+}
+
+// StatementHandler receives a statement as StatementTx reads it
+type StatementHandler interface {
+	// Begin is called once, before any entry, with the balance of the account at From
+	Begin(account Account, openingBalance int64) error
+	Entry(entry ListStatementEntriesRow) error
+	// End is called once, after the last entry, with the balance of the account after it
+	End(closingBalance int64) error
+}
+
+// StatementTx reads the entries of an account created in [From, To) through a server-side cursor, handing them
+// to handler one at a time, so that only a batch of them is in memory. The transaction is read-only and repeatable
+// read, so the balances agree with the entries whatever is written meanwhile.
+func (store *SQLStore) StatementTx(ctx context.Context, arg StatementTxParams, handler StatementHandler) error {
+	if arg.BatchSize <= 0 {
+		arg.BatchSize = defaultStatementBatchSize
+	}
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	return store.execTxOptions(ctx, opts, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		balance, err := q.GetBalanceBefore(ctx, GetBalanceBeforeParams{
+			AccountID:  arg.AccountID,
+			BeforeTime: arg.From,
+		})
+		if err != nil {
+			return err
+		}
+		if err := handler.Begin(account, balance); err != nil {
+			return err
+		}
+
+		// The cursor is closed with the transaction
+		if _, err := q.db.ExecContext(ctx, "DECLARE statement_entries NO SCROLL CURSOR FOR "+listStatementEntries,
+			arg.AccountID, arg.From, arg.To); err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM statement_entries", arg.BatchSize)
+		for {
+			n, last, err := fetchStatementEntries(ctx, q, fetch, handler)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				balance = last.BalanceAfter
+			}
+			if n < int(arg.BatchSize) {
+				break
+			}
+		}
+		return handler.End(balance)
+	})
+}
+
+// fetchStatementEntries hands the next batch of the cursor to handler, returning its size and last entry
+func fetchStatementEntries(ctx context.Context, q *Queries, fetch string, handler StatementHandler) (
+	n int, last ListStatementEntriesRow, err error) {
+	rows, err := q.db.QueryContext(ctx, fetch)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err = rows.Scan(
+			&last.ID,
+			&last.Type,
+			&last.Amount,
+			&last.BalanceAfter,
+			&last.Memo,
+			&last.ExternalReference,
+			&last.TransferID,
+			&last.CreatedAt,
+			&last.CounterpartyAccountID,
+		); err != nil {
+			return
+		}
+		if err = handler.Entry(last); err != nil {
+			return
+		}
+		n++
+	}
+	err = rows.Err()
+	return
+}
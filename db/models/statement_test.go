@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedStatement is a StatementHandler keeping what it was handed
+type recordedStatement struct {
+	account        Account
+	openingBalance int64
+	entries        []ListStatementEntriesRow
+	closingBalance int64
+}
+
+func (statement *recordedStatement) Begin(account Account, openingBalance int64) error {
+	statement.account, statement.openingBalance = account, openingBalance
+	return nil
+}
+
+func (statement *recordedStatement) Entry(entry ListStatementEntriesRow) error {
+	statement.entries = append(statement.entries, entry)
+	return nil
+}
+
+func (statement *recordedStatement) End(closingBalance int64) error {
+	statement.closingBalance = closingBalance
+	return nil
+}
+
+func TestStatementTx(t *testing.T) {
+	account := createFundedAccount(t, 10)
+	for i := 0; i < 3; i++ {
+		_, err := testStore.DepositTx(context.Background(), CashTxParams{
+			AccountID:         account.ID,
+			Amount:            5,
+			ExternalReference: util.RandomString(12),
+		})
+		require.NoError(t, err)
+	}
+
+	// A batch of one fetches each entry from the cursor on its own
+	var statement recordedStatement
+	err := testStore.StatementTx(context.Background(), StatementTxParams{
+		AccountID: account.ID,
+		From:      time.Now().Add(-time.Hour),
+		To:        time.Now().Add(time.Hour),
+		BatchSize: 1,
+	}, &statement)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, statement.account.ID)
+	require.Equal(t, int64(10), statement.openingBalance)
+	require.Len(t, statement.entries, 3)
+	require.Equal(t, int64(15), statement.entries[0].BalanceAfter)
+	require.Equal(t, int64(25), statement.closingBalance)
+
+	// Before the entries, the statement only has the opening balance
+	statement = recordedStatement{}
+	err = testStore.StatementTx(context.Background(), StatementTxParams{
+		AccountID: account.ID,
+		From:      time.Now().Add(-2 * time.Hour),
+		To:        time.Now().Add(-time.Hour),
+	}, &statement)
+	require.NoError(t, err)
+	require.Empty(t, statement.entries)
+	require.Equal(t, int64(10), statement.openingBalance)
+	require.Equal(t, int64(10), statement.closingBalance)
+}
@@ -4,52 +4,141 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
 )
 
+// ErrInsufficientFunds is returned by TransferTx and WithdrawTx when the debited account cannot cover the amount
+type ErrInsufficientFunds struct {
+	AccountID int64
+	Available int64 // Balance plus overdraft limit at the time of the debit
+	Amount    int64
+}
+
+func (e *ErrInsufficientFunds) Error() string {
+	return fmt.Sprintf("account [%d] has insufficient funds: available %d, requested %d",
+		e.AccountID, e.Available, e.Amount)
+}
+
+// Store runs queries and the transactions moving money. Every transaction writes balanced entries:
+// they sum to zero in each currency, or it fails with *ErrUnbalancedEntries. Account creation and transfers
+// write their events to the outbox in the same transaction.
 type Store interface {
 	Querier
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error)
+	DepositTx(ctx context.Context, arg CashTxParams) (CashTxResult, error)
+	WithdrawTx(ctx context.Context, arg CashTxParams) (CashTxResult, error)
+	AdjustmentTx(ctx context.Context, arg AdjustmentTxParams) (Entry, error)
+	StatementTx(ctx context.Context, arg StatementTxParams, handler StatementHandler) error
+	ClaimScheduledTransfersTx(ctx context.Context, arg ClaimScheduledTransfersTxParams) ([]ClaimedScheduledTransfer, error)
+	DispatchOutboxTx(ctx context.Context, limit int32) (int, error)
+	ClaimWebhookDeliveriesTx(ctx context.Context, arg ClaimWebhookDeliveriesTxParams) ([]ListDueWebhookDeliveriesRow, error)
+	Ping(ctx context.Context) error
 }
 
 // SQLStore provides required query and transaction methods
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	db                 *sql.DB
+	slowQueryThreshold time.Duration
+	rollbackHook       func()
+}
+
+// StoreOption configures the SQLStore returned by NewStore
+type StoreOption func(*SQLStore)
+
+// WithSlowQueryThreshold logs queries slower than threshold at warn level. Zero disables slow query logging.
+func WithSlowQueryThreshold(threshold time.Duration) StoreOption {
+	return func(store *SQLStore) {
+		store.slowQueryThreshold = threshold
+	}
+}
+
+// WithRollbackHook calls hook after each transaction rolled back by the store
+func WithRollbackHook(hook func()) StoreOption {
+	return func(store *SQLStore) {
+		store.rollbackHook = hook
+	}
 }
 
 // NewStore returns a new Store object for data access
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
-		Queries: New(db),
-		db:      db,
+func NewStore(db *sql.DB, opts ...StoreOption) Store {
+	store := &SQLStore{
+		db:                 db,
+		slowQueryThreshold: defaultSlowQueryThreshold,
+	}
+	for _, opt := range opts {
+		opt(store)
 	}
+	store.Queries = New(store.logging(db))
+	return store
+}
+
+// logging wraps db so that slow queries are logged with the request logger
+func (store *SQLStore) logging(db DBTX) DBTX {
+	return loggingDBTX{DBTX: db, threshold: store.slowQueryThreshold}
+}
+
+// Ping verifies the connection to the database is alive
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
 }
 
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	return store.execTxOptions(ctx, nil, fn)
+}
+
+// execTxOptions runs fn in a transaction started with opts
+func (store *SQLStore) execTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
 	// Begin the transaction
-	tx, err := store.db.BeginTx(ctx, nil)
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
 	// For each query within the transaction
-	q := New(tx)
+	q := New(store.logging(tx))
 
 	// If the transaction fails, rollback the transaction
 	if err = fn(q); err != nil {
-		if rbErr := tx.Rollback(); err != nil {
-			return fmt.Errorf("tx error: %v, rollback error: %v", err, rbErr)
+		logger := util.LoggerFromContext(ctx)
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.Error().Err(err).AnErr("rollback_error", rbErr).Msg("transaction rollback failed")
+			return fmt.Errorf("tx error: %w, rollback error: %v", err, rbErr)
+		}
+		logger.Warn().Err(err).Msg("transaction rolled back")
+		if store.rollbackHook != nil {
+			store.rollbackHook()
 		}
-		return fmt.Errorf("tx error: %v", err)
+		return fmt.Errorf("tx error: %w", err)
 	}
 	return tx.Commit()
 }
 
 // Input for transfer transaction
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Memo          string `json:"memo"` // Recorded on both entries
+}
+
+// Input for a transfer transaction between accounts of different currencies
+type ExchangeTransferTxParams struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`    // Debited, in the from account currency
+	ToAmount      int64  `json:"to_amount"` // Credited, in the to account currency
+	ExchangeRate  string `json:"exchange_rate"`
+	Rounding      string `json:"rounding"`
+	Memo          string `json:"memo"` // Recorded on every entry of the transfer
 }
 
 // TransferTxResult struct contains result of each operation in the transaction
@@ -63,59 +152,201 @@ type TransferTxResult struct {
 
 // TransferTX performs all money transfer operations within the transfer transaction
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	return store.ExchangeTransferTx(ctx, ExchangeTransferTxParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.Amount,
+		ExchangeRate:  "1",
+		Rounding:      "none",
+		Memo:          arg.Memo,
+	})
+}
+
+// ExchangeTransferTx debits Amount from the from account and credits the converted ToAmount to the to account,
+// recording the rate used on the transfer. Between currencies, the bank's ledger accounts take the other side
+// of each leg, so that the entries of each currency still sum to zero.
+func (store *SQLStore) ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
-		var err error
-		// a. A transfer record
-		if result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg)); err != nil {
+		fromAccount, err := q.GetAccount(ctx, arg.FromAccountID)
+		if err != nil {
 			return err
 		}
-		// b. Entry (from) record
-		if result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		}); err != nil {
+		toAccount, err := q.GetAccount(ctx, arg.ToAccountID)
+		if err != nil {
 			return err
 		}
-		// c. Entry (to) record
-		if result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		postings := []posting{
+			{AccountID: arg.FromAccountID, Amount: -arg.Amount, Type: EntryTypeTransfer},
+			{AccountID: arg.ToAccountID, Amount: arg.ToAmount, Type: EntryTypeTransfer},
+		}
+		if fromAccount.Currency != toAccount.Currency {
+			fromLedger, err := ledgerAccountID(ctx, q, fromAccount.Currency)
+			if err != nil {
+				return err
+			}
+			toLedger, err := ledgerAccountID(ctx, q, toAccount.Currency)
+			if err != nil {
+				return err
+			}
+			postings = append(postings,
+				posting{AccountID: fromLedger, Amount: arg.Amount, Type: EntryTypeExchange},
+				posting{AccountID: toLedger, Amount: -arg.ToAmount, Type: EntryTypeExchange},
+			)
+		}
+
+		// Lock every account before reading the from balance, so a concurrent transfer can't spend it
+		accounts, err := lockAccounts(ctx, q, postings)
+		if err != nil {
+			return err
+		}
+		if available := accounts[arg.FromAccountID].Balance + accounts[arg.FromAccountID].OverdraftLimit; available < arg.Amount {
+			return &ErrInsufficientFunds{
+				AccountID: arg.FromAccountID,
+				Available: available,
+				Amount:    arg.Amount,
+			}
+		}
+		if result.Transfer, err = q.CreateExchangeTransfer(ctx, CreateExchangeTransferParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      arg.ToAmount,
+			ExchangeRate:  arg.ExchangeRate,
+			Rounding:      arg.Rounding,
 		}); err != nil {
 			return err
 		}
-		// d. & e. Account Balance update
-		// Before concurrent row updates, process each row operation by ID to prevent deadlock
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = updateBalance(
-				ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = updateBalance(
-				ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		entries, err := postEntries(ctx, q, accounts, postings, util.NewNullInt64(result.Transfer.ID), arg.Memo)
+		if err != nil {
+			return err
 		}
+		result.FromEntry, result.ToEntry = entries[0], entries[1]
+		result.FromAccount, result.ToAccount = accounts[arg.FromAccountID], accounts[arg.ToAccountID]
+		return writeOutboxEvent(ctx, q, EventTransferCreated, result.Transfer,
+			result.FromAccount.Owner, result.ToAccount.Owner)
+	})
+	return result, err
+}
+
+// Input for a deposit or withdrawal transaction, moving cash in or out of the bank
+type CashTxParams struct {
+	AccountID         int64  `json:"account_id"`
+	Amount            int64  `json:"amount"`             // Always positive
+	ExternalReference string `json:"external_reference"` // Unique per entry type
+	Memo              string `json:"memo"`
+}
+
+// CashTxResult contains the entry and the updated account of a deposit or withdrawal
+type CashTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx credits Amount to the account
+func (store *SQLStore) DepositTx(ctx context.Context, arg CashTxParams) (CashTxResult, error) {
+	return store.cashTx(ctx, EntryTypeDeposit, arg.AccountID, arg.Amount, arg.ExternalReference, arg.Memo)
+}
+
+// WithdrawTx debits Amount from the account, failing with *ErrInsufficientFunds if its balance
+// and overdraft limit can't cover it
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg CashTxParams) (CashTxResult, error) {
+	return store.cashTx(ctx, EntryTypeWithdrawal, arg.AccountID, -arg.Amount, arg.ExternalReference, arg.Memo)
+}
+
+// cashTx records an entry of entryType for amount, negative for debits, against the bank's ledger account
+// in the same currency
+func (store *SQLStore) cashTx(
+	ctx context.Context,
+	entryType EntryType,
+	accountID, amount int64,
+	externalReference, memo string,
+) (CashTxResult, error) {
+	var result CashTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		ledgerID, err := ledgerAccountID(ctx, q, account.Currency)
 		if err != nil {
 			return err
 		}
+		postings := []posting{
+			{AccountID: accountID, Amount: amount, Type: entryType, ExternalReference: externalReference},
+			// The reference is unique per entry type, so it's only kept on the customer side
+			{AccountID: ledgerID, Amount: -amount, Type: entryType},
+		}
+		accounts, err := lockAccounts(ctx, q, postings)
+		if err != nil {
+			return err
+		}
+		if available := accounts[accountID].Balance + accounts[accountID].OverdraftLimit; amount < 0 && available < -amount {
+			return &ErrInsufficientFunds{
+				AccountID: accountID,
+				Available: available,
+				Amount:    -amount,
+			}
+		}
+		entries, err := postEntries(ctx, q, accounts, postings, util.NullInt64{}, memo)
+		if err != nil {
+			return err
+		}
+		result.Entry, result.Account = entries[0], accounts[accountID]
 		return nil
 	})
 	return result, err
 }
 
-// updateBalance performs the adjustment of balance amount for two accounts
-func updateBalance(ctx context.Context, q *Queries, accountID1, amount1, accountID2, amount2 int64) (
-	account1, account2 Account, err error) {
-	if account1, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
-		Amount: amount1,
-		ID:     accountID1,
-	}); err != nil {
-		return
-	}
-	if account2, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
-		Amount: amount2,
-		ID:     accountID2,
-	}); err != nil {
-		return
-	}
-	return
+// Input for an adjustment transaction, correcting the entries of an account whose balance drifted from them
+type AdjustmentTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Amount    int64  `json:"amount"` // Balance minus the sum of the entries of the account
+	Memo      string `json:"memo"`
+}
+
+// AdjustmentTx records an adjustment entry for Amount, without changing the balance of the account, which
+// already accounts for it. The bank's ledger account in the same currency takes the other side.
+func (store *SQLStore) AdjustmentTx(ctx context.Context, arg AdjustmentTxParams) (Entry, error) {
+	var entry Entry
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if account.Owner == SystemUsername {
+			return fmt.Errorf("account [%d] is a ledger account, it has no other side to adjust against", account.ID)
+		}
+		ledgerID, err := ledgerAccountID(ctx, q, account.Currency)
+		if err != nil {
+			return err
+		}
+		ledgerPosting := posting{AccountID: ledgerID, Amount: -arg.Amount, Type: EntryTypeAdjustment}
+		accounts, err := lockAccounts(ctx, q, []posting{
+			{AccountID: arg.AccountID, Amount: arg.Amount, Type: EntryTypeAdjustment},
+			ledgerPosting,
+		})
+		if err != nil {
+			return err
+		}
+		if entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:    arg.AccountID,
+			Amount:       arg.Amount,
+			Type:         EntryTypeAdjustment,
+			Memo:         arg.Memo,
+			BalanceAfter: accounts[arg.AccountID].Balance,
+		}); err != nil {
+			return err
+		}
+		ledgerEntries, err := postEntries(ctx, q, accounts, []posting{ledgerPosting}, util.NullInt64{}, arg.Memo)
+		if err != nil {
+			return err
+		}
+		return checkBalanced(accounts, append(ledgerEntries, entry))
+	})
+	return entry, err
 }
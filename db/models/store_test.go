@@ -2,18 +2,28 @@ package db
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/harrychopra/go-api/util"
 	"github.com/stretchr/testify/require"
 )
 
-func TestTransferTx(t *testing.T) {
-	account1 := createRandomAccount(t, nil)
-	account2 := createRandomAccount(t, nil)
+// createFundedAccount creates a random account holding the input balance
+func createFundedAccount(t *testing.T, balance int64) Account {
+	return createRandomAccount(t, &CreateAccountParams{
+		Balance:  balance,
+		Currency: util.RandomCurrency(),
+	})
+}
 
+func TestTransferTx(t *testing.T) {
 	n := 5
 	amount := int64(10)
 
+	account1 := createFundedAccount(t, int64(n)*amount)
+	account2 := createRandomAccount(t, nil)
+
 	errs := make(chan error)
 	results := make(chan TransferTxResult)
 	// Concurrent txs will attempt to access EXCLUSIVE LOCK ordered by row ids
@@ -80,15 +90,21 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toAccount)
 		require.Equal(t, account2.ID, toAccount.ID)
 		require.Equal(t, account2.Balance+(amount*(int64(i)+1)), toAccount.Balance)
+
+		// Entries are linked to the transfer and carry the balance they left
+		require.Equal(t, util.NewNullInt64(transfer.ID), fromEntry.TransferID)
+		require.Equal(t, util.NewNullInt64(transfer.ID), toEntry.TransferID)
+		require.Equal(t, fromAccount.Balance, fromEntry.BalanceAfter)
+		require.Equal(t, toAccount.Balance, toEntry.BalanceAfter)
 	}
 }
 
 func TestTransferTxDeadlock(t *testing.T) {
-	accountA := createRandomAccount(t, nil)
-	accountB := createRandomAccount(t, nil)
-
 	n := 10
 	amount := int64(10)
+
+	accountA := createFundedAccount(t, int64(n)*amount)
+	accountB := createFundedAccount(t, int64(n)*amount)
 	errs := make(chan error)
 
 	for i := 0; i < n; i++ {
@@ -125,3 +141,186 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, accountA.Balance, updatedAccountA.Balance)
 	require.Equal(t, accountB.Balance, updatedAccountB.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	account1 := createFundedAccount(t, 10)
+	account2 := createRandomAccount(t, nil)
+
+	_, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        11,
+	})
+	var fundsErr *ErrInsufficientFunds
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, account1.ID, fundsErr.AccountID)
+	require.Equal(t, int64(10), fundsErr.Available)
+	require.Equal(t, int64(11), fundsErr.Amount)
+
+	// The rolled back transaction must leave both balances untouched
+	updatedAccount1, err := testStore.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testStore.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	account1 := createFundedAccount(t, 10)
+	account2 := createRandomAccount(t, nil)
+
+	account1, err := testStore.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 20,
+	})
+	require.NoError(t, err)
+
+	// Balance may go down to -OverdraftLimit but no further
+	result, err := testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        30,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-20), result.FromAccount.Balance)
+
+	_, err = testStore.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	var fundsErr *ErrInsufficientFunds
+	require.True(t, errors.As(err, &fundsErr))
+	require.Zero(t, fundsErr.Available)
+}
+
+func TestExchangeTransferTx(t *testing.T) {
+	account1 := createRandomAccount(t, &CreateAccountParams{Balance: 1000, Currency: util.USD})
+	account2 := createRandomAccount(t, &CreateAccountParams{Balance: 0, Currency: util.EUR})
+
+	arg := ExchangeTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		ToAmount:      920,
+		ExchangeRate:  "0.92000000",
+		Rounding:      util.RoundingHalfEven,
+		Memo:          "holiday",
+	}
+	result, err := testStore.ExchangeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, transfer.ExchangeRate)
+	require.Equal(t, arg.Rounding, transfer.Rounding)
+
+	// Each side moves in its own currency
+	require.Equal(t, -arg.Amount, result.FromEntry.Amount)
+	require.Equal(t, arg.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-arg.Amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+arg.ToAmount, result.ToAccount.Balance)
+
+	// The ledger accounts take the other side of each leg, so each currency balances
+	entries, err := testStore.ListTransferEntries(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	sums := make(map[int64]int64)
+	for _, entry := range entries {
+		require.Equal(t, arg.Memo, entry.Memo)
+		if entry.Type == EntryTypeExchange {
+			account, err := testStore.GetAccount(context.Background(), entry.AccountID)
+			require.NoError(t, err)
+			require.Equal(t, SystemUsername, account.Owner)
+		}
+		sums[entry.AccountID] += entry.Amount
+	}
+	usdLedger, err := testStore.GetAccountByOwnerAndCurrency(context.Background(), GetAccountByOwnerAndCurrencyParams{
+		Owner:    SystemUsername,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, -sums[account1.ID], sums[usdLedger.ID])
+}
+
+func TestDepositTx(t *testing.T) {
+	account := createFundedAccount(t, 10)
+
+	arg := CashTxParams{
+		AccountID:         account.ID,
+		Amount:            25,
+		ExternalReference: util.RandomString(12),
+	}
+	result, err := testStore.DepositTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, EntryTypeDeposit, result.Entry.Type)
+	require.Equal(t, arg.Amount, result.Entry.Amount)
+	require.Equal(t, arg.ExternalReference, result.Entry.ExternalReference)
+	require.Equal(t, int64(35), result.Account.Balance)
+	require.Equal(t, int64(35), result.Entry.BalanceAfter)
+	require.False(t, result.Entry.TransferID.Valid)
+
+	// An external reference can only be recorded once per entry type
+	_, err = testStore.DepositTx(context.Background(), arg)
+	require.Error(t, err)
+
+	updatedAccount, err := testStore.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(35), updatedAccount.Balance)
+}
+
+func TestWithdrawTx(t *testing.T) {
+	account := createFundedAccount(t, 10)
+
+	result, err := testStore.WithdrawTx(context.Background(), CashTxParams{
+		AccountID:         account.ID,
+		Amount:            4,
+		ExternalReference: util.RandomString(12),
+	})
+	require.NoError(t, err)
+	require.Equal(t, EntryTypeWithdrawal, result.Entry.Type)
+	require.Equal(t, int64(-4), result.Entry.Amount)
+	require.Equal(t, int64(6), result.Account.Balance)
+
+	_, err = testStore.WithdrawTx(context.Background(), CashTxParams{
+		AccountID:         account.ID,
+		Amount:            7,
+		ExternalReference: util.RandomString(12),
+	})
+	var fundsErr *ErrInsufficientFunds
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, int64(6), fundsErr.Available)
+	require.Equal(t, int64(7), fundsErr.Amount)
+
+	updatedAccount, err := testStore.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), updatedAccount.Balance)
+}
+
+func TestAdjustmentTx(t *testing.T) {
+	// Opened with a balance no entry accounts for
+	account := createRandomAccount(t, &CreateAccountParams{Balance: 40, Currency: util.USD})
+
+	entry, err := testStore.AdjustmentTx(context.Background(), AdjustmentTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance,
+		Memo:      "opening balance",
+	})
+	require.NoError(t, err)
+	require.Equal(t, EntryTypeAdjustment, entry.Type)
+	require.Equal(t, account.Balance, entry.Amount)
+	require.Equal(t, account.Balance, entry.BalanceAfter)
+
+	sums, err := testStore.ListAccountEntrySums(context.Background(), ListAccountEntrySumsParams{
+		AfterID: account.ID - 1,
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	require.Len(t, sums, 1)
+	require.Equal(t, account.ID, sums[0].ID)
+	require.Equal(t, account.Balance, sums[0].Balance)
+	require.Equal(t, sums[0].Balance, sums[0].EntriesSum)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/harrychopra/go-api/token"
+)
+
+// revocationEvictionInterval is the minimum time between two sweeps of expired token revocations
+const revocationEvictionInterval = time.Minute
+
+// TokenRevocationStore is a token.RevocationStore shared by every node using the same database
+type TokenRevocationStore struct {
+	store Querier
+	// maxTokenDuration bounds how long a user revocation has to be kept
+	maxTokenDuration time.Duration
+
+	mu           sync.Mutex
+	lastEviction time.Time
+}
+
+// NewTokenRevocationStore creates a new TokenRevocationStore.
+// maxTokenDuration must be the longest duration any token is issued for.
+func NewTokenRevocationStore(store Querier, maxTokenDuration time.Duration) token.RevocationStore {
+	return &TokenRevocationStore{
+		store:            store,
+		maxTokenDuration: maxTokenDuration,
+		lastEviction:     time.Now(),
+	}
+}
+
+// RevokeToken invalidates a single token until it expires
+func (store *TokenRevocationStore) RevokeToken(ctx context.Context, payload *token.Payload) error {
+	if err := store.store.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	}); err != nil {
+		return err
+	}
+	return store.evictExpired(ctx)
+}
+
+// RevokeUser invalidates every token issued to the user up to now
+func (store *TokenRevocationStore) RevokeUser(ctx context.Context, username string) error {
+	now := time.Now()
+	if err := store.store.UpsertUserTokenRevocation(ctx, UpsertUserTokenRevocationParams{
+		Username:      username,
+		RevokedBefore: now,
+		ExpiresAt:     now.Add(store.maxTokenDuration),
+	}); err != nil {
+		return err
+	}
+	return store.evictExpired(ctx)
+}
+
+// IsRevoked checks if the token has been revoked
+func (store *TokenRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return store.store.IsTokenRevoked(ctx, IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+}
+
+// evictExpired deletes the revocations of tokens that have expired anyway
+func (store *TokenRevocationStore) evictExpired(ctx context.Context) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	if now.Sub(store.lastEviction) < revocationEvictionInterval {
+		return nil
+	}
+	if err := store.store.DeleteExpiredTokenRevocations(ctx); err != nil {
+		return err
+	}
+	store.lastEviction = now
+	return nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRevocationStore(t *testing.T) {
+	store := NewTokenRevocationStore(testQueries, time.Hour)
+	user := createRandomUser(t, nil)
+	other := createRandomUser(t, nil)
+
+	payload1, err := token.NewPayload(user.Username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := token.NewPayload(user.Username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := token.NewPayload(other.Username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	// A single token
+	require.NoError(t, store.RevokeToken(context.Background(), payload1))
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// Every token of the user issued so far, and not those of other users
+	require.NoError(t, store.RevokeUser(context.Background(), user.Username))
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,194 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: totp.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (username, hashed_code)
+VALUES ($1, $2)
+RETURNING id, username, hashed_code, is_used, created_at
+`
+
+type CreateRecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, createRecoveryCode, arg.Username, arg.HashedCode)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :one
+UPDATE users
+SET is_mfa_enabled = true
+WHERE username = $1 AND NOT is_mfa_enabled
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, is_mfa_enabled
+`
+
+func (q *Queries) EnableUserMFA(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserMFA, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+	)
+	return i, err
+}
+
+const getTOTPSecret = `-- name: GetTOTPSecret :one
+SELECT username, secret, last_used_step, created_at FROM totp_secrets
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetTOTPSecret(ctx context.Context, username string) (TotpSecret, error) {
+	row := q.db.QueryRowContext(ctx, getTOTPSecret, username)
+	var i TotpSecret
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listUnusedRecoveryCodes = `-- name: ListUnusedRecoveryCodes :many
+SELECT id, username, hashed_code, is_used, created_at FROM recovery_codes
+WHERE username = $1 AND NOT is_used
+ORDER BY id
+`
+
+func (q *Queries) ListUnusedRecoveryCodes(ctx context.Context, username string) ([]RecoveryCode, error) {
+	rows, err := q.db.QueryContext(ctx, listUnusedRecoveryCodes, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RecoveryCode{}
+	for rows.Next() {
+		var i RecoveryCode
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.HashedCode,
+			&i.IsUsed,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertTOTPSecret = `-- name: UpsertTOTPSecret :one
+INSERT INTO totp_secrets (username, secret)
+SELECT u.username, $1 FROM users u
+WHERE u.username = $2 AND NOT u.is_mfa_enabled
+FOR SHARE
+ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    last_used_step = 0,
+    created_at = now()
+RETURNING username, secret, last_used_step, created_at
+`
+
+type UpsertTOTPSecretParams struct {
+	Secret   string `json:"secret"`
+	Username string `json:"username"`
+}
+
+// Enrolling again replaces a secret that was never confirmed. Fails with no rows once two-factor authentication is
+// enabled, the user row being locked so that a concurrent confirmation is seen.
+func (q *Queries) UpsertTOTPSecret(ctx context.Context, arg UpsertTOTPSecretParams) (TotpSecret, error) {
+	row := q.db.QueryRowContext(ctx, upsertTOTPSecret, arg.Secret, arg.Username)
+	var i TotpSecret
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET is_used = true
+WHERE id = $1 AND NOT is_used
+RETURNING id, username, hashed_code, is_used, created_at
+`
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, id int64) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, useRecoveryCode, id)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE totp_secrets
+SET last_used_step = $1
+WHERE username = $2 AND last_used_step < $1
+RETURNING username, secret, last_used_step, created_at
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+// Fails with no rows for a step already used, so that each code logs in once
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (TotpSecret, error) {
+	row := q.db.QueryRowContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i TotpSecret
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
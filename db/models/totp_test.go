@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createTOTPSecret(t *testing.T, username string) TotpSecret {
+	secret, err := testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username: username,
+		Secret:   util.RandomString(32),
+	})
+	require.NoError(t, err)
+	return secret
+}
+
+func TestUpsertTOTPSecret(t *testing.T) {
+	user := createRandomUser(t, nil)
+	first := createTOTPSecret(t, user.Username)
+	require.Zero(t, first.LastUsedStep)
+
+	_, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+
+	// Enrolling again replaces the secret and its steps
+	second := createTOTPSecret(t, user.Username)
+	require.NotEqual(t, first.Secret, second.Secret)
+	require.Zero(t, second.LastUsedStep)
+
+	got, err := testQueries.GetTOTPSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, second.Secret, got.Secret)
+
+	// Once two-factor authentication is enabled, the secret is kept
+	_, err = testQueries.EnableUserMFA(context.Background(), user.Username)
+	require.NoError(t, err)
+	_, err = testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	got, err = testQueries.GetTOTPSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, second.Secret, got.Secret)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	user := createRandomUser(t, nil)
+	createTOTPSecret(t, user.Username)
+
+	secret, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), secret.LastUsedStep)
+
+	// Codes of the same step or before are replays
+	for _, step := range []int64{100, 99} {
+		_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: step})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 101})
+	require.NoError(t, err)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	user := createRandomUser(t, nil)
+	code1, err := testQueries.CreateRecoveryCode(context.Background(), CreateRecoveryCodeParams{Username: user.Username, HashedCode: util.RandomString(60)})
+	require.NoError(t, err)
+	code2, err := testQueries.CreateRecoveryCode(context.Background(), CreateRecoveryCodeParams{Username: user.Username, HashedCode: util.RandomString(60)})
+	require.NoError(t, err)
+
+	used, err := testQueries.UseRecoveryCode(context.Background(), code1.ID)
+	require.NoError(t, err)
+	require.True(t, used.IsUsed)
+
+	// Codes are single-use
+	_, err = testQueries.UseRecoveryCode(context.Background(), code1.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	unused, err := testQueries.ListUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, unused, 1)
+	require.Equal(t, code2.ID, unused[0].ID)
+}
@@ -7,10 +7,48 @@ import (
 	"context"
 )
 
+const createExchangeTransfer = `-- name: CreateExchangeTransfer :one
+INSERT INTO transfers(from_account_id, to_account_id, amount, to_amount, exchange_rate, rounding)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding
+`
+
+type CreateExchangeTransferParams struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	ToAmount      int64  `json:"to_amount"`
+	ExchangeRate  string `json:"exchange_rate"`
+	Rounding      string `json:"rounding"`
+}
+
+func (q *Queries) CreateExchangeTransfer(ctx context.Context, arg CreateExchangeTransferParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, createExchangeTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.Rounding,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Rounding,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
-INSERT INTO transfers(from_account_id, to_account_id, amount)
-VALUES ($1, $2, $3)
-RETURNING id, from_account_id, to_account_id, amount, created_at
+INSERT INTO transfers(from_account_id, to_account_id, amount, to_amount)
+VALUES ($1, $2, $3, $3)
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding
 `
 
 type CreateTransferParams struct {
@@ -28,6 +66,9 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Rounding,
 	)
 	return i, err
 }
@@ -43,7 +84,7 @@ func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding FROM transfers
 WHERE id = $1
 LIMIT 1
 `
@@ -57,12 +98,80 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.Rounding,
 	)
 	return i, err
 }
 
+const listTransferEntryCounts = `-- name: ListTransferEntryCounts :many
+SELECT transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.to_amount,
+  COUNT(entries.id) AS entry_count,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.from_account_id AND entries.amount = -transfers.amount
+  ) AS from_entry_count,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.to_account_id AND entries.amount = transfers.to_amount
+  ) AS to_entry_count
+FROM transfers
+LEFT JOIN entries ON entries.transfer_id = transfers.id AND entries.type = 'transfer'
+WHERE transfers.id > $2
+GROUP BY transfers.id
+ORDER BY transfers.id
+LIMIT $1
+`
+
+type ListTransferEntryCountsParams struct {
+	Limit   int32 `json:"limit"`
+	AfterID int64 `json:"after_id"`
+}
+
+type ListTransferEntryCountsRow struct {
+	ID             int64 `json:"id"`
+	FromAccountID  int64 `json:"from_account_id"`
+	ToAccountID    int64 `json:"to_account_id"`
+	Amount         int64 `json:"amount"`
+	ToAmount       int64 `json:"to_amount"`
+	EntryCount     int64 `json:"entry_count"`
+	FromEntryCount int64 `json:"from_entry_count"`
+	ToEntryCount   int64 `json:"to_entry_count"`
+}
+
+func (q *Queries) ListTransferEntryCounts(ctx context.Context, arg ListTransferEntryCountsParams) ([]ListTransferEntryCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntryCounts, arg.Limit, arg.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryCountsRow{}
+	for rows.Next() {
+		var i ListTransferEntryCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.ToAmount,
+			&i.EntryCount,
+			&i.FromEntryCount,
+			&i.ToEntryCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding FROM transfers
 WHERE 
 from_account_id = $1 
 OR
@@ -99,6 +208,101 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Rounding,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersAfter = `-- name: ListTransfersAfter :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListTransfersAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	AfterID   int64 `json:"after_id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListTransfersAfter(ctx context.Context, arg ListTransfersAfterParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersAfter, arg.AccountID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Rounding,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersBefore = `-- name: ListTransfersBefore :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rounding FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+AND id < $2
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListTransfersBeforeParams struct {
+	AccountID int64 `json:"account_id"`
+	BeforeID  int64 `json:"before_id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListTransfersBefore(ctx context.Context, arg ListTransfersBeforeParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersBefore, arg.AccountID, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.Rounding,
 		); err != nil {
 			return nil, err
 		}
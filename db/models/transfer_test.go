@@ -27,6 +27,9 @@ func TestCreateTransfer(t *testing.T) {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.Amount, transfer.ToAmount)
+	require.Equal(t, "1.00000000", transfer.ExchangeRate)
+	require.Equal(t, "none", transfer.Rounding)
 }
 
 func TestGetTransfer(t *testing.T) {
@@ -101,3 +104,40 @@ func TestDeleteTransfer(t *testing.T) {
 	require.Empty(t, deletedTransfer)
 	require.ErrorIs(t, sql.ErrNoRows, err)
 }
+
+func TestListTransfersKeyset(t *testing.T) {
+	account1 := createRandomAccount(t, nil)
+	account2 := createRandomAccount(t, nil)
+
+	// Alternate directions: both sent and received transfers belong to account1's history
+	transfers := make([]Transfer, 4)
+	for i := range transfers {
+		arg := CreateTransferParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        int64(i + 1),
+		}
+		if i%2 == 1 {
+			arg.FromAccountID, arg.ToAccountID = arg.ToAccountID, arg.FromAccountID
+		}
+		var err error
+		transfers[i], err = testQueries.CreateTransfer(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	after, err := testQueries.ListTransfersAfter(context.Background(), ListTransfersAfterParams{
+		AccountID: account1.ID,
+		AfterID:   transfers[0].ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, transfers[1:], after)
+
+	before, err := testQueries.ListTransfersBefore(context.Background(), ListTransfersBeforeParams{
+		AccountID: account1.ID,
+		BeforeID:  transfers[3].ID,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{transfers[2], transfers[1]}, before)
+}
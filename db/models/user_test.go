@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -37,6 +38,7 @@ func TestCreateUser(t *testing.T) {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, util.CustomerRole, user.Role)
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
 }
@@ -52,3 +54,119 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, userA.Email, userA.Email)
 	require.WithinDuration(t, userA.CreatedAt, userB.CreatedAt, time.Second)
 }
+
+func TestGetUserPasswordChangedAt(t *testing.T) {
+	user := createRandomUser(t, nil)
+	changedAt := time.Now()
+	_, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:          user.Username,
+		HashedPassword:    sql.NullString{String: user.HashedPassword, Valid: true},
+		PasswordChangedAt: changedAt,
+	})
+	require.NoError(t, err)
+
+	passwordChangedAt, err := testQueries.GetUserPasswordChangedAt(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, changedAt, passwordChangedAt, time.Second)
+
+	_, err = testQueries.GetUserPasswordChangedAt(context.Background(), util.RandomName())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	user := createRandomUser(t, nil)
+	updatedUser, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user.Username,
+		Role:     util.TellerRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TellerRole, updatedUser.Role)
+
+	// The role column only takes the roles known to the app
+	_, err = testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user.Username,
+		Role:     "owner",
+	})
+	require.Error(t, err)
+}
+
+func TestListUsers(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		createRandomUser(t, nil)
+	}
+	users, err := testQueries.ListUsers(context.Background(), ListUsersParams{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	nextUsers, err := testQueries.ListUsers(context.Background(), ListUsersParams{
+		Limit:         3,
+		AfterUsername: users[1].Username,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, nextUsers)
+	require.Equal(t, users[2].Username, nextUsers[0].Username)
+	for _, user := range nextUsers {
+		require.Greater(t, user.Username, users[1].Username)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	user := createRandomUser(t, nil)
+	_, err := testQueries.UpdateUserEmailVerified(context.Background(), UpdateUserEmailVerifiedParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	// Unset fields are left unchanged
+	newName := util.RandomName()
+	updated, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: user.Username,
+		FullName: sql.NullString{String: newName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.Equal(t, user.HashedPassword, updated.HashedPassword)
+	require.True(t, updated.IsEmailVerified)
+
+	// A new email address isn't verified
+	newEmail := util.RandomEmail()
+	updated, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: user.Username,
+		Email:    sql.NullString{String: newEmail, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, updated.Email)
+	require.Equal(t, newName, updated.FullName)
+	require.False(t, updated.IsEmailVerified)
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	user := createRandomUser(t, nil)
+	gotUser, err := testQueries.GetUserByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, gotUser.Username)
+
+	_, err = testQueries.GetUserByEmail(context.Background(), util.RandomEmail())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResetUserPassword(t *testing.T) {
+	user := createRandomUser(t, nil)
+	requestedAt := time.Now()
+	arg := ResetUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    util.RandomString(60),
+		PasswordChangedAt: requestedAt.Add(time.Second),
+		RequestedAt:       requestedAt,
+	}
+	updated, err := testQueries.ResetUserPassword(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.HashedPassword, updated.HashedPassword)
+	require.WithinDuration(t, arg.PasswordChangedAt, updated.PasswordChangedAt, time.Millisecond)
+
+	// The password changed since the reset was requested
+	_, err = testQueries.ResetUserPassword(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CreateUserTxParams is the input of CreateUserTx
+type CreateUserTxParams struct {
+	CreateUserParams
+	// SecretCode and ExpiredAt are those of the code verifying the email address of the user
+	SecretCode string
+	ExpiredAt  time.Time
+}
+
+// CreateUserTxResult is the user created by CreateUserTx and the code verifying its email address
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user, whose email address is not verified yet, and a single-use code verifying it
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if result.User, err = q.CreateUser(ctx, arg.CreateUserParams); err != nil {
+			return err
+		}
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   result.User.Username,
+			Email:      result.User.Email,
+			SecretCode: arg.SecretCode,
+			ExpiredAt:  arg.ExpiredAt,
+		})
+		return err
+	})
+	return result, err
+}
+
+// VerifyEmailTxParams is the input of VerifyEmailTx
+type VerifyEmailTxParams struct {
+	EmailID    int64
+	SecretCode string
+}
+
+// VerifyEmailTxResult is the verified user and its used code
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx uses a verification code, marking the email address it was sent to as verified. It fails with
+// sql.ErrNoRows if the code is unknown, used or expired, or if the user changed address since.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		}); err != nil {
+			return err
+		}
+		result.User, err = q.UpdateUserEmailVerified(ctx, UpdateUserEmailVerifiedParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		return err
+	})
+	return result, err
+}
+
+// UpdateUserTxParams is the input of UpdateUserTx
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	// SecretCode and ExpiredAt are those of the code verifying the new email address, if it changes
+	SecretCode string
+	ExpiredAt  time.Time
+}
+
+// UpdateUserTxResult is the user updated by UpdateUserTx, and the code verifying its new email address if it changed
+type UpdateUserTxResult struct {
+	User        User         `json:"user"`
+	VerifyEmail *VerifyEmail `json:"verify_email"`
+}
+
+// UpdateUserTx updates a user. A new email address is not verified, so a single-use code verifying it is created.
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		old, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		if result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams); err != nil {
+			return err
+		}
+		if result.User.Email != old.Email {
+			verifyEmail, err := q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+				Username:   result.User.Username,
+				Email:      result.User.Email,
+				SecretCode: arg.SecretCode,
+				ExpiredAt:  arg.ExpiredAt,
+			})
+			if err != nil {
+				return err
+			}
+			result.VerifyEmail = &verifyEmail
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ConfirmTOTPTxParams is the input of ConfirmTOTPTx
+type ConfirmTOTPTxParams struct {
+	Username string
+	// Step is the time step of the code confirming the secret, which then can't be used to log in
+	Step                int64
+	HashedRecoveryCodes []string
+}
+
+// ConfirmTOTPTxResult is the user with two-factor authentication enabled and its new recovery codes
+type ConfirmTOTPTxResult struct {
+	User          User           `json:"user"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// ConfirmTOTPTx enables two-factor authentication with the enrolled TOTP secret of the user, replacing its
+// recovery codes. It fails with sql.ErrNoRows if it is already enabled.
+func (store *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (ConfirmTOTPTxResult, error) {
+	var result ConfirmTOTPTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		if result.User, err = q.EnableUserMFA(ctx, arg.Username); err != nil {
+			return err
+		}
+		if _, err = q.UseTOTPStep(ctx, UseTOTPStepParams{Username: arg.Username, Step: arg.Step}); err != nil {
+			return err
+		}
+		if err = q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			code, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{Username: arg.Username, HashedCode: hashedCode})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, code)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createUserTx(t *testing.T, expiredAt time.Time) (CreateUserTxResult, error) {
+	hashedPassword, err := util.HashedPassword(util.RandomString(8))
+	require.NoError(t, err)
+	return testStore.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomName(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomName(),
+			Email:          util.RandomEmail(),
+		},
+		SecretCode: util.RandomString(64),
+		ExpiredAt:  expiredAt,
+	})
+}
+
+func TestCreateUserTx(t *testing.T) {
+	result, err := createUserTx(t, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.User.Username, result.VerifyEmail.Username)
+	require.Equal(t, result.User.Email, result.VerifyEmail.Email)
+	require.False(t, result.VerifyEmail.IsUsed)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	created, err := createUserTx(t, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	arg := VerifyEmailTxParams{
+		EmailID:    created.VerifyEmail.ID,
+		SecretCode: created.VerifyEmail.SecretCode,
+	}
+
+	// A wrong code doesn't verify the user
+	_, err = testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: arg.EmailID, SecretCode: util.RandomString(64)})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := testStore.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	// Codes are single-use
+	_, err = testStore.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Nor are expired codes accepted
+	expired, err := createUserTx(t, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    expired.VerifyEmail.ID,
+		SecretCode: expired.VerifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	created, err := createUserTx(t, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Keeping the address doesn't create a code
+	result, err := testStore.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{Username: created.User.Username, FullName: sql.NullString{String: util.RandomName(), Valid: true}},
+		SecretCode:       util.RandomString(64),
+		ExpiredAt:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Nil(t, result.VerifyEmail)
+
+	result, err = testStore.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{Username: created.User.Username, Email: sql.NullString{String: util.RandomEmail(), Valid: true}},
+		SecretCode:       util.RandomString(64),
+		ExpiredAt:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.VerifyEmail)
+	require.Equal(t, result.User.Email, result.VerifyEmail.Email)
+
+	// The code sent to the old address no longer verifies the user
+	_, err = testStore.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    created.VerifyEmail.ID,
+		SecretCode: created.VerifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestConfirmTOTPTx(t *testing.T) {
+	user := createRandomUser(t, nil)
+	createTOTPSecret(t, user.Username)
+	_, err := testQueries.CreateRecoveryCode(context.Background(), CreateRecoveryCodeParams{Username: user.Username, HashedCode: util.RandomString(60)})
+	require.NoError(t, err)
+
+	arg := ConfirmTOTPTxParams{
+		Username:            user.Username,
+		Step:                100,
+		HashedRecoveryCodes: []string{util.RandomString(60), util.RandomString(60)},
+	}
+	result, err := testStore.ConfirmTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.User.IsMfaEnabled)
+	require.Len(t, result.RecoveryCodes, 2)
+
+	// The old recovery codes are replaced
+	unused, err := testQueries.ListUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, result.RecoveryCodes, unused)
+
+	// The confirming code can't log in
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testStore.ConfirmTOTPTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,97 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: webhook.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks(owner, url, secret)
+VALUES ($1, $2, $3)
+RETURNING id, owner, url, secret, created_at
+`
+
+type CreateWebhookParams struct {
+	Owner  string `json:"owner"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook, arg.Owner, arg.Url, arg.Secret)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhook, id)
+	return err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, owner, url, secret, created_at FROM webhooks
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Url,
+		&i.Secret,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listWebhooks = `-- name: ListWebhooks :many
+SELECT id, owner, url, secret, created_at FROM webhooks
+WHERE owner = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebhooks(ctx context.Context, owner string) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhooks, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Url,
+			&i.Secret,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
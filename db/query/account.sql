@@ -8,6 +8,22 @@ SELECT * FROM accounts
 WHERE id = $1
 LIMIT 1;
 
+-- name: GetAccountByOwnerAndCurrency :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2
+LIMIT 1;
+
+-- name: CreateAccountIfNotExists :exec
+INSERT INTO accounts(owner, balance, currency)
+VALUES ($1, 0, $2)
+ON CONFLICT (owner, currency) DO NOTHING;
+
+-- name: GetAccountForUpdate :one
+SELECT * FROM accounts
+WHERE id = $1
+LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -15,6 +31,28 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsAfter :many
+SELECT * FROM accounts
+WHERE owner = $1 AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT $2;
+
+-- name: ListAccountsBefore :many
+SELECT * FROM accounts
+WHERE owner = $1 AND id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT $2;
+
+-- name: ListAccountEntrySums :many
+SELECT accounts.id, accounts.owner, accounts.currency, accounts.balance,
+  COALESCE(SUM(entries.amount), 0)::bigint AS entries_sum
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+WHERE accounts.id > sqlc.arg(after_id)
+GROUP BY accounts.id
+ORDER BY accounts.id
+LIMIT $1;
+
 -- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
@@ -27,6 +65,18 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;  
 
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateAccountFrozen :one
+UPDATE accounts
+SET is_frozen = $2
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
\ No newline at end of file
@@ -1,6 +1,6 @@
 -- name: CreateEntry :one
-INSERT INTO entries(account_id, amount)
-VALUES ($1, $2)
+INSERT INTO entries(account_id, amount, type, external_reference, transfer_id, memo, balance_after)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetEntry :one
@@ -15,6 +15,56 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListTransferEntries :many
+SELECT * FROM entries
+WHERE transfer_id = sqlc.arg(transfer_id)::bigint
+ORDER BY id;
+
+-- name: ListEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = $1 AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT $2;
+
+-- name: ListEntriesBefore :many
+SELECT * FROM entries
+WHERE account_id = $1 AND id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT $2;
+
 -- name: DeleteEntry :exec
 DELETE FROM entries
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetBalanceBefore :one
+-- The balance_after of the last entry before the time, else what the account was opened with
+SELECT COALESCE(
+  (
+    SELECT entries.balance_after FROM entries
+    WHERE entries.account_id = sqlc.arg(account_id)::bigint AND entries.created_at < sqlc.arg(before_time)::timestamptz
+    ORDER BY entries.id DESC
+    LIMIT 1
+  ),
+  (
+    SELECT entries.balance_after - entries.amount FROM entries
+    WHERE entries.account_id = sqlc.arg(account_id)::bigint
+    ORDER BY entries.id
+    LIMIT 1
+  ),
+  (SELECT accounts.balance FROM accounts WHERE accounts.id = sqlc.arg(account_id)::bigint)
+)::bigint AS balance;
+
+-- name: ListStatementEntries :many
+SELECT entries.id, entries.type, entries.amount, entries.balance_after, entries.memo, entries.external_reference,
+  entries.transfer_id, entries.created_at,
+  COALESCE(
+    CASE WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id
+    ELSE transfers.from_account_id END,
+    0
+  )::bigint AS counterparty_account_id
+FROM entries
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+WHERE entries.account_id = sqlc.arg(account_id)
+  AND entries.created_at >= sqlc.arg(from_time)
+  AND entries.created_at < sqlc.arg(to_time)
+ORDER BY entries.id;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts
+WHERE key = sqlc.arg(key) AND last_failed_at > sqlc.arg(forget_before);
+
+-- name: AddLoginFailure :one
+-- Failures older than forget_before are forgotten, counting starts over
+INSERT INTO login_attempts (key, failures, last_failed_at)
+VALUES (sqlc.arg(key), 1, sqlc.arg(failed_at))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+      WHEN login_attempts.last_failed_at > sqlc.arg(forget_before) THEN login_attempts.failures + 1
+      ELSE 1
+    END,
+    previous_failed_at = CASE
+      WHEN login_attempts.last_failed_at > sqlc.arg(forget_before) THEN login_attempts.last_failed_at
+    END,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+-- name: RemoveLoginFailure :exec
+-- Unless another failure was added after the one at failed_at, the time of the failure before it is restored
+UPDATE login_attempts
+SET failures = failures - 1,
+    last_failed_at = CASE
+      WHEN last_failed_at = sqlc.arg(failed_at) THEN COALESCE(previous_failed_at, last_failed_at)
+      ELSE last_failed_at
+    END,
+    previous_failed_at = CASE
+      WHEN last_failed_at = sqlc.arg(failed_at) THEN NULL
+      ELSE previous_failed_at
+    END
+WHERE key = sqlc.arg(key) AND failures > 0;
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE key = $1;
+
+-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failed_at <= $1;
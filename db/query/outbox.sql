@@ -0,0 +1,62 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events(owner, event_type, payload)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListUndispatchedOutboxEvents :many
+-- Events locked by another dispatcher are skipped rather than waited for
+SELECT * FROM outbox_events
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+SET dispatched_at = now()
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveries :execrows
+-- One delivery of the event to each webhook of its owner
+INSERT INTO webhook_deliveries(webhook_id, event_id)
+SELECT webhooks.id, sqlc.arg(event_id)::bigint
+FROM webhooks
+WHERE webhooks.owner = sqlc.arg(owner)
+ON CONFLICT DO NOTHING;
+
+-- name: ListDueWebhookDeliveries :many
+SELECT
+  webhook_deliveries.id,
+  webhook_deliveries.webhook_id,
+  webhook_deliveries.attempts,
+  webhooks.url,
+  webhooks.secret,
+  outbox_events.id AS event_id,
+  outbox_events.event_type,
+  outbox_events.payload,
+  outbox_events.created_at AS event_created_at
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+JOIN outbox_events ON outbox_events.id = webhook_deliveries.event_id
+WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= sqlc.arg(now)
+ORDER BY webhook_deliveries.next_attempt_at
+LIMIT sqlc.arg(row_limit)
+FOR UPDATE OF webhook_deliveries SKIP LOCKED;
+
+-- name: StartWebhookDeliveryAttempt :exec
+-- Leases the delivery until lease_until, after which another dispatcher may retry it
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = sqlc.arg(lease_until)
+WHERE id = $1;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $2, next_attempt_at = $3, last_error = $4, delivered_at = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY id DESC
+LIMIT $2;
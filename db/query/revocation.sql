@@ -0,0 +1,30 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens(id, username, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: UpsertUserTokenRevocation :exec
+INSERT INTO user_token_revocations(username, revoked_before, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (username) DO UPDATE
+SET revoked_before = EXCLUDED.revoked_before,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: IsTokenRevoked :one
+SELECT (EXISTS (
+  SELECT 1 FROM revoked_tokens
+  WHERE revoked_tokens.id = sqlc.arg(id) AND revoked_tokens.expires_at > now()
+) OR EXISTS (
+  SELECT 1 FROM user_token_revocations
+  WHERE user_token_revocations.username = sqlc.arg(username)
+  AND user_token_revocations.revoked_before >= sqlc.arg(issued_at)
+  AND user_token_revocations.expires_at > now()
+))::bool AS revoked;
+
+-- name: DeleteExpiredTokenRevocations :exec
+WITH deleted_tokens AS (
+  DELETE FROM revoked_tokens
+  WHERE revoked_tokens.expires_at <= now()
+)
+DELETE FROM user_token_revocations
+WHERE user_token_revocations.expires_at <= now();
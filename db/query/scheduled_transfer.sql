@@ -0,0 +1,53 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers(owner, from_account_id, to_account_id, amount, currency, memo, schedule, next_run_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListScheduledTransfersAfter :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1 AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT $2;
+
+-- name: ListScheduledTransfersBefore :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1 AND id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT $2;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $2, memo = $3, schedule = $4, next_run_at = $5, status = $6
+-- Only if no run was claimed and the status wasn't changed since the row was read, no rows otherwise
+WHERE id = $1 AND next_run_at = sqlc.arg(read_next_run_at) AND status = sqlc.arg(read_status)
+RETURNING *;
+
+-- name: ListDueScheduledTransfers :many
+-- Rows claimed by another worker are skipped rather than waited for
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs(scheduled_transfer_id, scheduled_at)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: UpdateScheduledTransferRun :one
+UPDATE scheduled_transfer_runs
+SET status = $2, transfer_id = $3, error = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2;
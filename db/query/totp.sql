@@ -0,0 +1,49 @@
+-- name: UpsertTOTPSecret :one
+-- Enrolling again replaces a secret that was never confirmed. Fails with no rows once two-factor authentication is
+-- enabled, the user row being locked so that a concurrent confirmation is seen.
+INSERT INTO totp_secrets (username, secret)
+SELECT u.username, sqlc.arg(secret) FROM users u
+WHERE u.username = sqlc.arg(username) AND NOT u.is_mfa_enabled
+FOR SHARE
+ON CONFLICT (username) DO UPDATE
+SET secret = EXCLUDED.secret,
+    last_used_step = 0,
+    created_at = now()
+RETURNING *;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: UseTOTPStep :one
+-- Fails with no rows for a step already used, so that each code logs in once
+UPDATE totp_secrets
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: EnableUserMFA :one
+UPDATE users
+SET is_mfa_enabled = true
+WHERE username = $1 AND NOT is_mfa_enabled
+RETURNING *;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (username, hashed_code)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE username = $1 AND NOT is_used
+ORDER BY id;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET is_used = true
+WHERE id = $1 AND NOT is_used
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
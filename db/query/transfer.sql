@@ -1,6 +1,11 @@
 -- name: CreateTransfer :one
-INSERT INTO transfers(from_account_id, to_account_id, amount)
-VALUES ($1, $2, $3)
+INSERT INTO transfers(from_account_id, to_account_id, amount, to_amount)
+VALUES ($1, $2, $3, $3)
+RETURNING *;
+
+-- name: CreateExchangeTransfer :one
+INSERT INTO transfers(from_account_id, to_account_id, amount, to_amount, exchange_rate, rounding)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetTransfer :one
@@ -18,6 +23,36 @@ ORDER BY id
 LIMIT $3
 OFFSET $4;
 
+-- name: ListTransfersAfter :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ListTransfersBefore :many
+SELECT * FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+AND id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit');
+
 -- name: DeleteTransfer :exec
 DELETE FROM transfers
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListTransferEntryCounts :many
+SELECT transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.to_amount,
+  COUNT(entries.id) AS entry_count,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.from_account_id AND entries.amount = -transfers.amount
+  ) AS from_entry_count,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.to_account_id AND entries.amount = transfers.to_amount
+  ) AS to_entry_count
+FROM transfers
+LEFT JOIN entries ON entries.transfer_id = transfers.id AND entries.type = 'transfer'
+WHERE transfers.id > sqlc.arg(after_id)
+GROUP BY transfers.id
+ORDER BY transfers.id
+LIMIT $1;
\ No newline at end of file
@@ -6,4 +6,53 @@ RETURNING *;
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: GetUserPasswordChangedAt :one
+-- Checked on each authenticated request, tokens issued before are no longer accepted
+SELECT password_changed_at FROM users
+WHERE username = $1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1
+LIMIT 1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE username > sqlc.arg(after_username)
+ORDER BY username
+LIMIT $1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserEmailVerified :one
+-- The address is only verified if the user still has it
+UPDATE users
+SET is_email_verified = TRUE
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: UpdateUser :one
+-- Only the fields given as non-NULL change. Changing the email address resets its verification, and changing the
+-- password stamps password_changed_at.
+UPDATE users
+SET
+  full_name = COALESCE(@full_name::text, full_name),
+  is_email_verified = is_email_verified AND (@email::text IS NULL OR @email::text = email),
+  email = COALESCE(@email::text, email),
+  hashed_password = COALESCE(@hashed_password::text, hashed_password),
+  password_changed_at = CASE WHEN @hashed_password::text IS NULL THEN password_changed_at ELSE @password_changed_at END
+WHERE username = @username
+RETURNING *;
+
+-- name: ResetUserPassword :one
+-- Only resets the password if it hasn't changed since the reset was requested, so that the reset token is single-use
+UPDATE users
+SET hashed_password = @hashed_password, password_changed_at = @password_changed_at
+WHERE username = @username AND password_changed_at <= @requested_at
+RETURNING *;
@@ -0,0 +1,18 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks(owner, url, secret)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+WHERE owner = $1
+ORDER BY id;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
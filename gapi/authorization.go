@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/harrychopra/go-api/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+// authorizeUser authenticates the bearer access token of the "authorization" metadata with the bank
+func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	values := md.Get(authorizationHeaderKey)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization header is not provided")
+	}
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization header format")
+	}
+	if strings.ToLower(fields[0]) != authorizationTypeBearer {
+		return nil, status.Error(codes.Unauthenticated, "unsupported authorization type")
+	}
+	payload, err := server.bank.Authenticate(ctx, fields[1])
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"errors"
+
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/throttle"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// internalError is sent to clients as a bare internal status, as its cause may tell about the database or the
+// configuration, while the cause is kept for the request log
+type internalError struct {
+	err error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+// GRPCStatus is the status gRPC sends for the error
+func (e *internalError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "internal error")
+}
+
+// serviceError converts an error returned by service.Bank to a gRPC status error
+func serviceError(err error) error {
+	code := grpcCode(err)
+	if code == codes.Internal {
+		return &internalError{err: err}
+	}
+	statusErr := status.New(code, err.Error())
+	var throttledErr *throttle.ErrThrottled
+	if errors.As(err, &throttledErr) {
+		retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(throttledErr.RetryAfter)}
+		if statusDetails, err := statusErr.WithDetails(retryInfo); err == nil {
+			return statusDetails.Err()
+		}
+	}
+	return statusErr.Err()
+}
+
+// grpcCode maps the kind of a service error to a gRPC status code
+func grpcCode(err error) codes.Code {
+	switch service.KindOf(err) {
+	case service.InvalidArgument, service.CurrencyMismatch:
+		return codes.InvalidArgument
+	case service.Unauthenticated:
+		return codes.Unauthenticated
+	case service.Forbidden:
+		return codes.PermissionDenied
+	case service.NotFound:
+		return codes.NotFound
+	case service.Conflict:
+		return codes.AlreadyExists
+	case service.InsufficientFunds, service.FailedPrecondition:
+		return codes.FailedPrecondition
+	case service.TooManyRequests:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
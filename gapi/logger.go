@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const requestIDHeaderKey = "x-request-id"
+
+// validRequestID limits which client supplied request IDs are propagated, so they are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// GrpcLogger assigns or propagates the x-request-id metadata, stores a logger carrying it on the
+// call context and logs one line per call once it has been handled
+func GrpcLogger(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeaderKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !validRequestID.MatchString(requestID) {
+		requestID = uuid.New().String()
+	}
+	logger := log.Logger.With().Str("request_id", requestID).Logger()
+	ctx = util.ContextWithLogger(ctx, logger)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeaderKey, requestID))
+
+	result, err := handler(ctx, req)
+
+	code := status.Code(err)
+	event := logger.Info()
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		event = logger.Error().Err(err)
+	default:
+		event = logger.Warn().Err(err)
+	}
+	event.
+		Str("protocol", "grpc").
+		Str("method", info.FullMethod).
+		Str("status", code.String()).
+		Dur("latency", time.Since(start)).
+		Msg("request")
+	return result, err
+}
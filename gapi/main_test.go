@@ -0,0 +1,125 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// testTokenSymmetricKey is shared by every test server, so that testTokenMaker creates tokens they all accept
+var testTokenSymmetricKey = util.RandomString(32)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:     testTokenSymmetricKey,
+		ACCESS_TOKEN_DURATION: time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		LoginMaxFailures:      4,
+		LoginMaxFailuresPerIP: 20,
+		LoginLockoutDuration:  time.Minute,
+		TOTPIssuer:            "Simple Bank",
+		MFATokenDuration:      time.Minute,
+	}
+	if mockStore, ok := store.(*mock.MockStore); ok {
+		// Every authenticated request checks when the password last changed, which most tests don't care for.
+		// Expectations set beforehand are matched first.
+		mockStore.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes()
+	}
+	fxProvider, err := util.NewFXProvider("", 0)
+	require.NoError(t, err)
+	revocationStore := token.NewMemoryRevocationStore(config.MaxTokenDuration())
+	loginLimiter := throttle.NewLimiter(throttle.NewMemoryStore(config.LoginLockoutDuration), throttle.Options{
+		MaxFailures:      config.LoginMaxFailures,
+		MaxFailuresPerIP: config.LoginMaxFailuresPerIP,
+		LockoutDuration:  config.LoginLockoutDuration,
+	})
+	bank := service.NewBank(config, store, testTokenMaker(t), revocationStore, fxProvider, mail.NewMailer(config), loginLimiter)
+	return NewServer(bank)
+}
+
+// testTokenMaker returns a token maker whose tokens the test servers accept
+func testTokenMaker(t *testing.T) token.Maker {
+	tokenMaker, err := token.NewPasetoMaker(testTokenSymmetricKey)
+	require.NoError(t, err)
+	return tokenMaker
+}
+
+// newTestClient serves server over an in-memory connection and returns a client for it
+func newTestClient(t *testing.T, server *Server) pb.SimpleBankClient {
+	listener := bufconn.Listen(bufSize)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(GrpcLogger))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewSimpleBankClient(conn)
+}
+
+// newContextWithBearerToken returns a context carrying a customer access token for username in its metadata
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, util.CustomerRole, duration)
+	require.NoError(t, err)
+	bearerToken := fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)
+	md := metadata.MD{
+		authorizationHeaderKey: []string{bearerToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func randomUser(t *testing.T) (db.User, string) {
+	password := util.RandomString(8)
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	return db.User{
+		Username:        util.RandomName(),
+		HashedPassword:  hashedPassword,
+		FullName:        util.RandomName(),
+		Email:           util.RandomEmail(),
+		IsEmailVerified: true,
+	}, password
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Currency: util.RandomCurrency(),
+		Balance:  util.RandomMoney(),
+	}
+}
+
+func TestMain(m *testing.M) {
+	// Cleaner test output
+	log.Logger = zerolog.Nop()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,33 @@
+package gapi
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const userAgentHeaderKey = "user-agent"
+
+// clientMetadata describes the client of a call, as recorded on its sessions
+type clientMetadata struct {
+	userAgent string
+	clientIP  string
+}
+
+func extractMetadata(ctx context.Context) clientMetadata {
+	var mtdt clientMetadata
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(userAgentHeaderKey); len(userAgents) > 0 {
+			mtdt.userAgent = userAgents[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		mtdt.clientIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(mtdt.clientIP); err == nil {
+			mtdt.clientIP = host
+		}
+	}
+	return mtdt
+}
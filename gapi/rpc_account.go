@@ -0,0 +1,105 @@
+package gapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	authPayload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("currency", err)})
+	}
+	account, err := server.bank.CreateAccount(ctx, authPayload.Username, req.GetCurrency())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	authPayload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("id", err)})
+	}
+	account, err := server.bank.GetAccount(ctx, service.NewActor(authPayload), req.GetId())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	authPayload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize < 0 || pageSize > maxPageSize {
+		err := errors.New("must be between 1 and 100")
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("page_size", err)})
+	}
+	var afterID int64
+	if len(req.GetPageToken()) > 0 {
+		if afterID, err = decodePageToken(req.GetPageToken()); err != nil {
+			return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("page_token", err)})
+		}
+	}
+	accounts, hasMore, err := server.bank.ListAccounts(ctx, authPayload.Username, service.Page{
+		AfterID: afterID,
+		Size:    pageSize,
+	})
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	resp := &pb.ListAccountsResponse{}
+	if hasMore {
+		resp.NextPageToken = encodePageToken(accounts[len(accounts)-1].ID)
+	}
+	for _, account := range accounts {
+		resp.Accounts = append(resp.Accounts, convertAccount(account))
+	}
+	return resp, nil
+}
+
+// pageToken is the decoded form of the opaque page tokens handed to clients
+type pageToken struct {
+	ID int64 `json:"id"`
+}
+
+func encodePageToken(id int64) string {
+	data, _ := json.Marshal(pageToken{ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("invalid page token")
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID < 1 {
+		return 0, errors.New("invalid page token")
+	}
+	return decoded.ID, nil
+}
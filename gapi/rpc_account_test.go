@@ -0,0 +1,293 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateAccountRequest
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, resp *pb.CreateAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				arg := db.CreateAccountParams{Owner: user.Username, Currency: account.Currency}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, resp.GetAccount().GetId())
+				require.Equal(t, account.Currency, resp.GetAccount().GetCurrency())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "ExpiredToken",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, -time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			req:  &pb.CreateAccountRequest{Currency: "XYZ"},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "EmailNotVerified",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				unverified := user
+				unverified.IsEmailVerified = false
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(unverified, nil)
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name: "DuplicateCurrency",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("tx error: %w", &pq.Error{Code: "23505"}))
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				// The cause stays out of the response
+				require.Equal(t, "internal error", status.Convert(err).Message())
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+			ctx := testCase.buildContext(t, testTokenMaker(t))
+			resp, err := client.CreateAccount(ctx, testCase.req)
+			testCase.checkResponse(t, resp, err)
+		})
+	}
+}
+
+func TestGetAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		req           *pb.GetAccountRequest
+		username      string
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, resp *pb.GetAccountResponse, err error)
+	}{
+		{
+			name:     "OK",
+			req:      &pb.GetAccountRequest{Id: account.ID},
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, resp.GetAccount().GetId())
+				require.Equal(t, account.Owner, resp.GetAccount().GetOwner())
+				require.Equal(t, account.Balance, resp.GetAccount().GetBalance())
+			},
+		},
+		{
+			name:     "NotFound",
+			req:      &pb.GetAccountRequest{Id: account.ID},
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name:     "OtherUsersAccount",
+			req:      &pb.GetAccountRequest{Id: account.ID},
+			username: otherUser.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name:     "PasswordChanged",
+			req:      &pb.GetAccountRequest{Id: account.ID},
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				// The token is issued right after, before the password change
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq(user.Username)).Times(1).
+					Return(time.Now().Add(time.Minute), nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:     "InvalidID",
+			req:      &pb.GetAccountRequest{Id: 0},
+			username: user.Username,
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+			ctx := newContextWithBearerToken(t, testTokenMaker(t), testCase.username, time.Minute)
+			resp, err := client.GetAccount(ctx, testCase.req)
+			testCase.checkResponse(t, resp, err)
+		})
+	}
+}
+
+func TestListAccountsRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	accounts := make([]db.Account, 3)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].ID = int64(i + 1)
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountsAfter(gomock.Any(), gomock.Eq(db.ListAccountsAfterParams{
+				Owner: user.Username,
+				Limit: 3,
+			})).
+			Times(1).
+			Return(accounts, nil),
+		store.EXPECT().
+			ListAccountsAfter(gomock.Any(), gomock.Eq(db.ListAccountsAfterParams{
+				Owner:   user.Username,
+				AfterID: accounts[1].ID,
+				Limit:   3,
+			})).
+			Times(1).
+			Return(accounts[2:], nil),
+	)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	ctx := newContextWithBearerToken(t, testTokenMaker(t), user.Username, time.Minute)
+
+	resp, err := client.ListAccounts(ctx, &pb.ListAccountsRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.GetAccounts(), 2)
+	require.NotEmpty(t, resp.GetNextPageToken())
+
+	resp, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageSize: 2, PageToken: resp.GetNextPageToken()})
+	require.NoError(t, err)
+	require.Len(t, resp.GetAccounts(), 1)
+	require.Equal(t, accounts[2].ID, resp.GetAccounts()[0].GetId())
+	require.Empty(t, resp.GetNextPageToken())
+
+	_, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageToken: util.RandomString(6)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if violations := validateLoginUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+	mtdt := extractMetadata(ctx)
+	result, err := server.bank.LoginUser(ctx, service.LoginUserParams{
+		Username:  req.GetUsername(),
+		Password:  req.GetPassword(),
+		UserAgent: mtdt.userAgent,
+		ClientIP:  mtdt.clientIP,
+	})
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	if len(result.MFAToken) > 0 {
+		return &pb.LoginUserResponse{
+			MfaRequired:       true,
+			MfaToken:          result.MFAToken,
+			MfaTokenExpiresAt: timestamppb.New(result.MFAPayload.ExpiredAt),
+		}, nil
+	}
+	return convertLoginUserResult(result), nil
+}
+
+func (server *Server) LoginUserMFA(ctx context.Context, req *pb.LoginUserMFARequest) (*pb.LoginUserResponse, error) {
+	if violations := validateLoginUserMFARequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+	mtdt := extractMetadata(ctx)
+	result, err := server.bank.LoginUserMFA(ctx, service.LoginUserMFAParams{
+		MFAToken:  req.GetMfaToken(),
+		Code:      req.GetCode(),
+		UserAgent: mtdt.userAgent,
+		ClientIP:  mtdt.clientIP,
+	})
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return convertLoginUserResult(result), nil
+}
+
+func convertLoginUserResult(result service.LoginUserResult) *pb.LoginUserResponse {
+	return &pb.LoginUserResponse{
+		User:                  convertUser(result.User),
+		SessionId:             result.Session.ID.String(),
+		AccessToken:           result.AccessToken,
+		RefreshToken:          result.RefreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(result.AccessPayload.ExpiredAt),
+		RefreshTokenExpiresAt: timestamppb.New(result.RefreshPayload.ExpiredAt),
+	}
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+	return violations
+}
+
+func validateLoginUserMFARequest(req *pb.LoginUserMFARequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateRequired(req.GetMfaToken()); err != nil {
+		violations = append(violations, fieldViolation("mfa_token", err))
+	}
+	if err := validateRequired(req.GetCode()); err != nil {
+		violations = append(violations, fieldViolation("code", err))
+	}
+	return violations
+}
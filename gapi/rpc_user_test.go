@@ -0,0 +1,254 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/totp"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, resp *pb.CreateUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				FullName: user.FullName,
+				Email:    user.Email,
+				Password: password,
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(password, arg.HashedPassword))
+						require.NotEmpty(t, arg.SecretCode)
+						verifyEmail := db.VerifyEmail{ID: 1, Username: user.Username, Email: user.Email, SecretCode: arg.SecretCode}
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, resp.GetUser().GetUsername())
+				require.Equal(t, user.FullName, resp.GetUser().GetFullName())
+				require.Equal(t, user.Email, resp.GetUser().GetEmail())
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				FullName: user.FullName,
+				Email:    user.Email,
+				Password: password,
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, fmt.Errorf("tx error: %w", &pq.Error{Code: "23505"}))
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateUserResponse, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidArguments",
+			req: &pb.CreateUserRequest{
+				Username: "invalid-user#1",
+				FullName: user.FullName,
+				Email:    "invalid-email",
+				Password: "123",
+			},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.CreateUserResponse, err error) {
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+				require.Len(t, st.Details(), 1)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			client := newTestClient(t, newTestServer(t, store))
+			resp, err := client.CreateUser(context.Background(), testCase.req)
+			testCase.checkResponse(t, resp, err)
+		})
+	}
+}
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mock.MockStore)
+		checkResponse func(t *testing.T, resp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Contains(t, arg.UserAgent, "grpc-go")
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, resp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.GetSessionId())
+				require.NotEmpty(t, resp.GetAccessToken())
+				require.NotEmpty(t, resp.GetRefreshToken())
+				require.Equal(t, user.Username, resp.GetUser().GetUsername())
+			},
+		},
+		{
+			name: "UserNotFound",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Equal(t, "incorrect username or password", status.Convert(err).Message())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, resp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, resp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+				// The cause stays out of the response
+				require.Equal(t, "internal error", status.Convert(err).Message())
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			client := newTestClient(t, newTestServer(t, store))
+			resp, err := client.LoginUser(context.Background(), testCase.req)
+			testCase.checkResponse(t, resp, err)
+		})
+	}
+}
+
+func TestLoginUserRPCThrottled(t *testing.T) {
+	user, _ := randomUser(t)
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(3).Return(user, nil)
+	client := newTestClient(t, newTestServer(t, store))
+
+	req := &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"}
+	for i := 0; i < 3; i++ {
+		_, err := client.LoginUser(context.Background(), req)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// Clients are told when to retry
+	_, err := client.LoginUser(context.Background(), req)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	retryInfo, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, time.Second, retryInfo.GetRetryDelay().AsDuration())
+}
+
+func TestLoginUserMFARPC(t *testing.T) {
+	user, password := randomUser(t)
+	user.IsMfaEnabled = true
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(2).Return(user, nil)
+	store.EXPECT().GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpSecret{Secret: secret}, nil)
+	store.EXPECT().
+		UseTOTPStep(gomock.Any(), gomock.Eq(db.UseTOTPStepParams{Username: user.Username, Step: step})).
+		Times(1).
+		Return(db.TotpSecret{LastUsedStep: step}, nil)
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+			return db.Session{ID: arg.ID, Username: arg.Username}, nil
+		})
+	client := newTestClient(t, newTestServer(t, store))
+
+	// The password only gets an MFA token
+	resp, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{Username: user.Username, Password: password})
+	require.NoError(t, err)
+	require.True(t, resp.GetMfaRequired())
+	require.NotEmpty(t, resp.GetMfaToken())
+	require.Empty(t, resp.GetAccessToken())
+
+	_, err = client.LoginUserMFA(context.Background(), &pb.LoginUserMFARequest{MfaToken: resp.GetMfaToken()})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err = client.LoginUserMFA(context.Background(), &pb.LoginUserMFARequest{MfaToken: resp.GetMfaToken(), Code: code})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetAccessToken())
+	require.NotEmpty(t, resp.GetRefreshToken())
+	require.False(t, resp.GetMfaRequired())
+}
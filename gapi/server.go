@@ -0,0 +1,17 @@
+package gapi
+
+import (
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/service"
+)
+
+// Server serves gRPC requests for banking service
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	bank *service.Bank
+}
+
+// NewServer creates a new gRPC server. The bank is shared with the HTTP server and the background workers.
+func NewServer(bank *service.Bank) *Server {
+	return &Server{bank: bank}
+}
@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"github.com/harrychopra/go-api/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const minPasswordLength = 6
+
+// isAlphanumeric matches the "alphanum" binding of the HTTP API
+var isAlphanumeric = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
+
+func validateUsername(value string) error {
+	if !isAlphanumeric(value) {
+		return fmt.Errorf("must contain only letters and digits")
+	}
+	return nil
+}
+
+func validatePassword(value string) error {
+	if len(value) < minPasswordLength {
+		return fmt.Errorf("must be at least %d characters long", minPasswordLength)
+	}
+	return nil
+}
+
+func validateEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+func validateFullName(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("is required")
+	}
+	return nil
+}
+
+func validateRequired(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("is required")
+	}
+	return nil
+}
+
+func validateCurrency(value string) error {
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("is not a supported currency")
+	}
+	return nil
+}
+
+func validateID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive id")
+	}
+	return nil
+}
+
+func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	}
+}
+
+// invalidArgumentError reports the field violations of a request in the status details
+func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
+	badRequest := &errdetails.BadRequest{FieldViolations: violations}
+	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
+	statusDetails, err := statusInvalid.WithDetails(badRequest)
+	if err != nil {
+		return statusInvalid.Err()
+	}
+	return statusDetails.Err()
+}
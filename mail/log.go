@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+// LogMailer doesn't send emails: it appends them to a file, or logs them if it has none. For local use.
+type LogMailer struct {
+	from string
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer returns a mailer writing the emails from the from address to the file at path, or to the log
+// if path is empty
+func NewLogMailer(from, path string) *LogMailer {
+	return &LogMailer{
+		from: from,
+		path: path,
+	}
+}
+
+// Send writes msg to the file, or to the log
+func (mailer *LogMailer) Send(ctx context.Context, msg Message) error {
+	if len(mailer.path) == 0 {
+		util.LoggerFromContext(ctx).Info().
+			Str("to", strings.Join(msg.To, ", ")).
+			Str("subject", msg.Subject).
+			Str("body", msg.Body).
+			Msg("email not sent")
+		return nil
+	}
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	file, err := os.OpenFile(mailer.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer file.Close()
+	// Emails are separated by a blank line
+	if _, err := fmt.Fprintf(file, "%s\r\n\r\n", format(mailer.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to write mail log: %w", err)
+	}
+	return nil
+}
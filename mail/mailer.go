@@ -0,0 +1,48 @@
+// Package mail sends the emails of the bank to its users
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns the mailer picked by config.Mailer: "smtp" sends through config.SMTPAddress, anything else
+// writes the emails to config.MailLogFile, or to the log if it isn't set, for local use
+func NewMailer(config util.Config) Mailer {
+	if config.Mailer == "smtp" {
+		return NewSMTPMailer(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+	return NewLogMailer(config.MailFrom, config.MailLogFile)
+}
+
+// format renders msg sent by from as an RFC 5322 message
+func format(from string, msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	msg := Message{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Vérifiez votre adresse",
+		Body:    "Hello\nWorld",
+	}
+
+	data := string(format("Bank <no-reply@example.com>", msg, date))
+	require.Contains(t, data, "From: Bank <no-reply@example.com>\r\n")
+	require.Contains(t, data, "To: alice@example.com, bob@example.com\r\n")
+	require.Contains(t, data, "Date: Sun, 01 May 2022 10:00:00 +0000\r\n")
+	// Non ASCII subjects are encoded
+	require.Contains(t, data, "Subject: =?utf-8?q?")
+	require.True(t, strings.HasSuffix(data, "\r\n\r\nHello\r\nWorld"))
+}
+
+func TestLogMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer := NewLogMailer("no-reply@example.com", path)
+
+	for i := 0; i < 2; i++ {
+		err := mailer.Send(context.Background(), Message{
+			To:      []string{util.RandomEmail()},
+			Subject: "Verify your email address",
+			Body:    util.RandomString(16),
+		})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "Subject: Verify your email address\r\n"))
+}
+
+func TestNewMailer(t *testing.T) {
+	require.IsType(t, &SMTPMailer{}, NewMailer(util.Config{Mailer: "smtp", SMTPAddress: "localhost:25"}))
+	require.IsType(t, &LogMailer{}, NewMailer(util.Config{Mailer: "log"}))
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP server, authenticating with PLAIN when a username is set
+type SMTPMailer struct {
+	address  string // host:port
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer returns a mailer sending from the from address through the SMTP server at address
+func NewSMTPMailer(address, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		address:  address,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers msg to the SMTP server. ctx is only checked before connecting, net/smtp can't be cancelled.
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if len(mailer.username) > 0 {
+		host, _, err := net.SplitHostPort(mailer.address)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address: %w", err)
+		}
+		auth = smtp.PlainAuth("", mailer.username, mailer.password, host)
+	}
+	// The envelope sender is the bare address of the From header
+	sender := mailer.from
+	if address, err := netmail.ParseAddress(mailer.from); err == nil {
+		sender = address.Address
+	}
+	if err := smtp.SendMail(mailer.address, auth, sender, msg.To, format(mailer.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -1,36 +1,221 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/harrychopra/go-api/api"
 	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/gapi"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/metrics"
+	"github.com/harrychopra/go-api/pb"
+	"github.com/harrychopra/go-api/scheduler"
+	"github.com/harrychopra/go-api/service"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
 	"github.com/harrychopra/go-api/util"
+	"github.com/harrychopra/go-api/webhook"
 )
 
 func main() {
+	// go-api reconcile [--format json|csv] [--fix] checks the ledger instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcile(os.Args[2:]))
+	}
+	os.Exit(serve())
+}
 
+// serve runs the servers and the background workers until a signal or a server failing stops them. It returns the
+// exit code, non-zero if a server failed, for the orchestrator to restart the process.
+func serve() int {
 	config, err := util.LoadConfig(".")
 	if err != nil {
-		log.Fatal("failed to load config: ", err)
+		log.Fatal().Err(err).Msg("failed to load config")
 	}
 
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
-		log.Fatal("failed to connect to db: ", err)
+		log.Fatal().Err(err).Msg("failed to connect to db")
 	}
+	defer conn.Close()
 
-	store := db.NewStore(conn)
+	store := metrics.NewInstrumentedStore(db.NewStore(conn,
+		db.WithSlowQueryThreshold(config.SlowQueryThreshold),
+		db.WithRollbackHook(metrics.ObserveTxRollback),
+	))
 
-	server, err := api.NewServer(config, store)
+	// The servers and the workers share one of each, so that they see the same rates and revocations
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
-		log.Fatal("failaed to create new server object: ", err)
+		log.Fatal().Err(err).Msg("failed to create a token maker")
 	}
+	fxProvider, err := util.NewFXProvider(config.FXRatesFile, config.FXRatesReloadInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create an fx provider")
+	}
+	revocationStore := newRevocationStore(config, store)
+	loginLimiter := throttle.NewLimiter(
+		throttle.NewStore(config.LoginAttemptStore, store, config.LoginLockoutDuration),
+		throttle.Options{
+			MaxFailures:      config.LoginMaxFailures,
+			MaxFailuresPerIP: config.LoginMaxFailuresPerIP,
+			LockoutDuration:  config.LoginLockoutDuration,
+		},
+	)
+	bank := service.NewBank(config, store, tokenMaker, revocationStore, fxProvider, mail.NewMailer(config), loginLimiter)
 
-	if err := server.Start(config.ServerAddress); err != nil {
-		log.Fatal("failed to start server: ", err)
+	server, err := api.NewServer(config, store, bank)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create new server object")
+	}
+
+	grpcServer, grpcListener, err := newGrpcServer(config, gapi.NewServer(bank))
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create gRPC server")
+	}
+
+	// Stop on SIGTERM (sent by the orchestrator) or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	serverErr := make(chan error, 3)
+	go func() {
+		serverErr <- server.Start(config.ServerAddress)
+	}()
+	log.Info().Str("address", config.ServerAddress).Msg("HTTP server started")
+	go func() {
+		serverErr <- grpcServer.Serve(grpcListener)
+	}()
+	log.Info().Str("address", grpcListener.Addr().String()).Msg("gRPC server started")
+	// The metrics aren't for clients, so they are served apart, on an address not exposed to them
+	var metricsServer *http.Server
+	if len(config.MetricsAddress) > 0 {
+		metricsServer = newMetricsServer(config)
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
+				serverErr <- err
+			}
+		}()
+		log.Info().Str("address", config.MetricsAddress).Msg("metrics server started")
+	}
+
+	// Background workers stop with ctx
+	var workers sync.WaitGroup
+	if config.SchedulerInterval > 0 {
+		worker := scheduler.NewWorker(bank, config.SchedulerInterval)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker.Run(ctx)
+		}()
+		log.Info().Dur("interval", config.SchedulerInterval).Msg("scheduler started")
+	}
+	if config.WebhookDispatchInterval > 0 {
+		dispatcher := webhook.NewDispatcher(store, webhook.Options{
+			Interval:    config.WebhookDispatchInterval,
+			Timeout:     config.WebhookTimeout,
+			MaxAttempts: config.WebhookMaxAttempts,
+		})
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(ctx)
+		}()
+		log.Info().Dur("interval", config.WebhookDispatchInterval).Msg("webhook dispatcher started")
+	}
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			log.Error().Err(err).Msg("server failed")
+			exitCode = 1
+		}
+	case <-ctx.Done():
+	}
+
+	log.Info().Dur("timeout", config.ShutdownTimeout).Msg("shutting down servers")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("failed to drain in-flight HTTP requests")
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("failed to stop the metrics server")
+		}
+	}
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		log.Error().Msg("failed to drain in-flight gRPC calls")
+		grpcServer.Stop()
+	}
+	// The select above may have returned on the server failing, so stop the workers as well
+	stop()
+	workersStopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		bank.WaitForMail()
+		close(workersStopped)
+	}()
+	select {
+	case <-workersStopped:
+	case <-shutdownCtx.Done():
+		log.Error().Msg("failed to finish the work in progress of the background workers and mail")
+	}
+	return exitCode
+}
+
+// newRevocationStore returns the token revocation store of config, "postgres" or "memory". It defaults to memory,
+// which only sees revocations made through the same node.
+func newRevocationStore(config util.Config, store db.Store) token.RevocationStore {
+	if config.TokenRevocationStore == "postgres" {
+		return db.NewTokenRevocationStore(store, config.MaxTokenDuration())
+	}
+	return token.NewMemoryRevocationStore(config.MaxTokenDuration())
+}
+
+// newMetricsServer creates the server of /metrics on the internal address of config
+func newMetricsServer(config util.Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	return &http.Server{
+		Addr:         config.MetricsAddress,
+		Handler:      mux,
+		ReadTimeout:  config.HTTPReadTimeout,
+		WriteTimeout: config.HTTPWriteTimeout,
+	}
+}
+
+// newGrpcServer creates the gRPC server of server and the listener it is to serve on
+func newGrpcServer(config util.Config, server *gapi.Server) (*grpc.Server, net.Listener, error) {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(gapi.GrpcLogger))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	// Lets clients such as grpcurl discover the service
+	reflection.Register(grpcServer)
+
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		return nil, nil, err
 	}
+	return grpcServer, listener, nil
 }
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bank"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	storeOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Latency of Store methods by method and result (ok, not_found or error).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	transfersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_created_total",
+		Help:      "Transfers created, by from and to currency.",
+	}, []string{"from_currency", "to_currency"})
+
+	transferAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount_total",
+		Help:      "Amount debited by transfers in minor units, by currency.",
+	}, []string{"currency"})
+
+	cashAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cash_amount_total",
+		Help:      "Amount deposited or withdrawn in minor units, by entry type and currency.",
+	}, []string{"type", "currency"})
+
+	txRollbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_transaction_rollbacks_total",
+		Help:      "Store transactions rolled back.",
+	})
+)
+
+// Handler serves the metrics of the default registry in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records the latency of a handled request. Route is the route template, not the path.
+func ObserveHTTPRequest(method, route string, status int, latency time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(latency.Seconds())
+}
+
+// ObserveTxRollback counts a rolled back store transaction
+func ObserveTxRollback() {
+	txRollbacks.Inc()
+}
+
+// observe records the latency and result of a Store method. It is deferred with the method's error result.
+func observe(method string, start time.Time, err *error) {
+	result := "ok"
+	if *err != nil {
+		result = "error"
+		if errors.Is(*err, sql.ErrNoRows) {
+			result = "not_found"
+		}
+	}
+	storeOperationDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,569 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// instrumentedStore records the latency of every method of the wrapped Store,
+// and counts the transfers, deposits and withdrawals made through it
+type instrumentedStore struct {
+	store db.Store
+}
+
+var _ db.Store = (*instrumentedStore)(nil)
+
+// NewInstrumentedStore returns a Store recording metrics for each call to store
+func NewInstrumentedStore(store db.Store) db.Store {
+	return &instrumentedStore{store: store}
+}
+
+func (store *instrumentedStore) CreateUserTx(ctx context.Context, arg db.CreateUserTxParams) (_ db.CreateUserTxResult, err error) {
+	defer observe("CreateUserTx", time.Now(), &err)
+	return store.store.CreateUserTx(ctx, arg)
+}
+
+func (store *instrumentedStore) VerifyEmailTx(ctx context.Context, arg db.VerifyEmailTxParams) (_ db.VerifyEmailTxResult, err error) {
+	defer observe("VerifyEmailTx", time.Now(), &err)
+	return store.store.VerifyEmailTx(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateUserTx(ctx context.Context, arg db.UpdateUserTxParams) (_ db.UpdateUserTxResult, err error) {
+	defer observe("UpdateUserTx", time.Now(), &err)
+	return store.store.UpdateUserTx(ctx, arg)
+}
+
+func (store *instrumentedStore) ConfirmTOTPTx(ctx context.Context, arg db.ConfirmTOTPTxParams) (_ db.ConfirmTOTPTxResult, err error) {
+	defer observe("ConfirmTOTPTx", time.Now(), &err)
+	return store.store.ConfirmTOTPTx(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateAccountTx(ctx context.Context, arg db.CreateAccountParams) (_ db.Account, err error) {
+	defer observe("CreateAccountTx", time.Now(), &err)
+	return store.store.CreateAccountTx(ctx, arg)
+}
+
+func (store *instrumentedStore) TransferTx(ctx context.Context, arg db.TransferTxParams) (result db.TransferTxResult, err error) {
+	defer observe("TransferTx", time.Now(), &err)
+	if result, err = store.store.TransferTx(ctx, arg); err == nil {
+		observeTransfer(result)
+	}
+	return
+}
+
+func (store *instrumentedStore) ExchangeTransferTx(ctx context.Context, arg db.ExchangeTransferTxParams) (result db.TransferTxResult, err error) {
+	defer observe("ExchangeTransferTx", time.Now(), &err)
+	if result, err = store.store.ExchangeTransferTx(ctx, arg); err == nil {
+		observeTransfer(result)
+	}
+	return
+}
+
+func (store *instrumentedStore) DepositTx(ctx context.Context, arg db.CashTxParams) (result db.CashTxResult, err error) {
+	defer observe("DepositTx", time.Now(), &err)
+	if result, err = store.store.DepositTx(ctx, arg); err == nil {
+		observeCash(result)
+	}
+	return
+}
+
+func (store *instrumentedStore) WithdrawTx(ctx context.Context, arg db.CashTxParams) (result db.CashTxResult, err error) {
+	defer observe("WithdrawTx", time.Now(), &err)
+	if result, err = store.store.WithdrawTx(ctx, arg); err == nil {
+		observeCash(result)
+	}
+	return
+}
+
+func (store *instrumentedStore) AdjustmentTx(ctx context.Context, arg db.AdjustmentTxParams) (_ db.Entry, err error) {
+	defer observe("AdjustmentTx", time.Now(), &err)
+	return store.store.AdjustmentTx(ctx, arg)
+}
+
+func (store *instrumentedStore) StatementTx(ctx context.Context, arg db.StatementTxParams, handler db.StatementHandler) (err error) {
+	defer observe("StatementTx", time.Now(), &err)
+	return store.store.StatementTx(ctx, arg, handler)
+}
+
+func (store *instrumentedStore) ClaimScheduledTransfersTx(ctx context.Context, arg db.ClaimScheduledTransfersTxParams) (_ []db.ClaimedScheduledTransfer, err error) {
+	defer observe("ClaimScheduledTransfersTx", time.Now(), &err)
+	return store.store.ClaimScheduledTransfersTx(ctx, arg)
+}
+
+func (store *instrumentedStore) DispatchOutboxTx(ctx context.Context, limit int32) (_ int, err error) {
+	defer observe("DispatchOutboxTx", time.Now(), &err)
+	return store.store.DispatchOutboxTx(ctx, limit)
+}
+
+func (store *instrumentedStore) ClaimWebhookDeliveriesTx(ctx context.Context, arg db.ClaimWebhookDeliveriesTxParams) (_ []db.ListDueWebhookDeliveriesRow, err error) {
+	defer observe("ClaimWebhookDeliveriesTx", time.Now(), &err)
+	return store.store.ClaimWebhookDeliveriesTx(ctx, arg)
+}
+
+func (store *instrumentedStore) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+	return store.store.Ping(ctx)
+}
+
+// observeTransfer counts a created transfer and its amount in the from account currency
+func observeTransfer(result db.TransferTxResult) {
+	transfersCreated.WithLabelValues(result.FromAccount.Currency, result.ToAccount.Currency).Inc()
+	transferAmount.WithLabelValues(result.FromAccount.Currency).Add(float64(result.Transfer.Amount))
+}
+
+// observeCash adds the amount of a deposit or withdrawal, in the account currency
+func observeCash(result db.CashTxResult) {
+	amount := result.Entry.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	cashAmount.WithLabelValues(string(result.Entry.Type), result.Account.Currency).Add(float64(amount))
+}
+
+func (store *instrumentedStore) AddLoginFailure(ctx context.Context, arg db.AddLoginFailureParams) (_ db.LoginAttempt, err error) {
+	defer observe("AddLoginFailure", time.Now(), &err)
+	return store.store.AddLoginFailure(ctx, arg)
+}
+
+func (store *instrumentedStore) BlockSession(ctx context.Context, id uuid.UUID) (err error) {
+	defer observe("BlockSession", time.Now(), &err)
+	return store.store.BlockSession(ctx, id)
+}
+
+func (store *instrumentedStore) BlockUserSessions(ctx context.Context, username string) (err error) {
+	defer observe("BlockUserSessions", time.Now(), &err)
+	return store.store.BlockUserSessions(ctx, username)
+}
+
+func (store *instrumentedStore) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (_ db.Account, err error) {
+	defer observe("CreateAccount", time.Now(), &err)
+	return store.store.CreateAccount(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateAccountIfNotExists(ctx context.Context, arg db.CreateAccountIfNotExistsParams) (err error) {
+	defer observe("CreateAccountIfNotExists", time.Now(), &err)
+	return store.store.CreateAccountIfNotExists(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (_ db.Entry, err error) {
+	defer observe("CreateEntry", time.Now(), &err)
+	return store.store.CreateEntry(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateExchangeTransfer(ctx context.Context, arg db.CreateExchangeTransferParams) (_ db.Transfer, err error) {
+	defer observe("CreateExchangeTransfer", time.Now(), &err)
+	return store.store.CreateExchangeTransfer(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateIdempotencyKey(ctx context.Context, arg db.CreateIdempotencyKeyParams) (_ db.IdempotencyKey, err error) {
+	defer observe("CreateIdempotencyKey", time.Now(), &err)
+	return store.store.CreateIdempotencyKey(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) (_ db.OutboxEvent, err error) {
+	defer observe("CreateOutboxEvent", time.Now(), &err)
+	return store.store.CreateOutboxEvent(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateRecoveryCode(ctx context.Context, arg db.CreateRecoveryCodeParams) (_ db.RecoveryCode, err error) {
+	defer observe("CreateRecoveryCode", time.Now(), &err)
+	return store.store.CreateRecoveryCode(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateRevokedToken(ctx context.Context, arg db.CreateRevokedTokenParams) (err error) {
+	defer observe("CreateRevokedToken", time.Now(), &err)
+	return store.store.CreateRevokedToken(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateScheduledTransfer(ctx context.Context, arg db.CreateScheduledTransferParams) (_ db.ScheduledTransfer, err error) {
+	defer observe("CreateScheduledTransfer", time.Now(), &err)
+	return store.store.CreateScheduledTransfer(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateScheduledTransferRun(ctx context.Context, arg db.CreateScheduledTransferRunParams) (_ db.ScheduledTransferRun, err error) {
+	defer observe("CreateScheduledTransferRun", time.Now(), &err)
+	return store.store.CreateScheduledTransferRun(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateSession(ctx context.Context, arg db.CreateSessionParams) (_ db.Session, err error) {
+	defer observe("CreateSession", time.Now(), &err)
+	return store.store.CreateSession(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (_ db.Transfer, err error) {
+	defer observe("CreateTransfer", time.Now(), &err)
+	return store.store.CreateTransfer(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (_ db.User, err error) {
+	defer observe("CreateUser", time.Now(), &err)
+	return store.store.CreateUser(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateVerifyEmail(ctx context.Context, arg db.CreateVerifyEmailParams) (_ db.VerifyEmail, err error) {
+	defer observe("CreateVerifyEmail", time.Now(), &err)
+	return store.store.CreateVerifyEmail(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (_ db.Webhook, err error) {
+	defer observe("CreateWebhook", time.Now(), &err)
+	return store.store.CreateWebhook(ctx, arg)
+}
+
+func (store *instrumentedStore) CreateWebhookDeliveries(ctx context.Context, arg db.CreateWebhookDeliveriesParams) (_ int64, err error) {
+	defer observe("CreateWebhookDeliveries", time.Now(), &err)
+	return store.store.CreateWebhookDeliveries(ctx, arg)
+}
+
+func (store *instrumentedStore) DeleteAccount(ctx context.Context, id int64) (err error) {
+	defer observe("DeleteAccount", time.Now(), &err)
+	return store.store.DeleteAccount(ctx, id)
+}
+
+func (store *instrumentedStore) DeleteEntry(ctx context.Context, id int64) (err error) {
+	defer observe("DeleteEntry", time.Now(), &err)
+	return store.store.DeleteEntry(ctx, id)
+}
+
+func (store *instrumentedStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (err error) {
+	defer observe("DeleteExpiredIdempotencyKeys", time.Now(), &err)
+	return store.store.DeleteExpiredIdempotencyKeys(ctx)
+}
+
+func (store *instrumentedStore) DeleteExpiredTokenRevocations(ctx context.Context) (err error) {
+	defer observe("DeleteExpiredTokenRevocations", time.Now(), &err)
+	return store.store.DeleteExpiredTokenRevocations(ctx)
+}
+
+func (store *instrumentedStore) DeleteIdempotencyKey(ctx context.Context, arg db.DeleteIdempotencyKeyParams) (err error) {
+	defer observe("DeleteIdempotencyKey", time.Now(), &err)
+	return store.store.DeleteIdempotencyKey(ctx, arg)
+}
+
+func (store *instrumentedStore) DeleteLoginAttempt(ctx context.Context, key string) (err error) {
+	defer observe("DeleteLoginAttempt", time.Now(), &err)
+	return store.store.DeleteLoginAttempt(ctx, key)
+}
+
+func (store *instrumentedStore) DeleteRecoveryCodes(ctx context.Context, username string) (err error) {
+	defer observe("DeleteRecoveryCodes", time.Now(), &err)
+	return store.store.DeleteRecoveryCodes(ctx, username)
+}
+
+func (store *instrumentedStore) DeleteStaleLoginAttempts(ctx context.Context, lastFailedAt time.Time) (err error) {
+	defer observe("DeleteStaleLoginAttempts", time.Now(), &err)
+	return store.store.DeleteStaleLoginAttempts(ctx, lastFailedAt)
+}
+
+func (store *instrumentedStore) DeleteTransfer(ctx context.Context, id int64) (err error) {
+	defer observe("DeleteTransfer", time.Now(), &err)
+	return store.store.DeleteTransfer(ctx, id)
+}
+
+func (store *instrumentedStore) DeleteWebhook(ctx context.Context, id int64) (err error) {
+	defer observe("DeleteWebhook", time.Now(), &err)
+	return store.store.DeleteWebhook(ctx, id)
+}
+
+func (store *instrumentedStore) EnableUserMFA(ctx context.Context, username string) (_ db.User, err error) {
+	defer observe("EnableUserMFA", time.Now(), &err)
+	return store.store.EnableUserMFA(ctx, username)
+}
+
+func (store *instrumentedStore) GetAccount(ctx context.Context, id int64) (_ db.Account, err error) {
+	defer observe("GetAccount", time.Now(), &err)
+	return store.store.GetAccount(ctx, id)
+}
+
+func (store *instrumentedStore) GetAccountByOwnerAndCurrency(ctx context.Context, arg db.GetAccountByOwnerAndCurrencyParams) (_ db.Account, err error) {
+	defer observe("GetAccountByOwnerAndCurrency", time.Now(), &err)
+	return store.store.GetAccountByOwnerAndCurrency(ctx, arg)
+}
+
+func (store *instrumentedStore) GetAccountForUpdate(ctx context.Context, id int64) (_ db.Account, err error) {
+	defer observe("GetAccountForUpdate", time.Now(), &err)
+	return store.store.GetAccountForUpdate(ctx, id)
+}
+
+func (store *instrumentedStore) GetBalanceBefore(ctx context.Context, arg db.GetBalanceBeforeParams) (_ int64, err error) {
+	defer observe("GetBalanceBefore", time.Now(), &err)
+	return store.store.GetBalanceBefore(ctx, arg)
+}
+
+func (store *instrumentedStore) GetEntry(ctx context.Context, id int64) (_ db.Entry, err error) {
+	defer observe("GetEntry", time.Now(), &err)
+	return store.store.GetEntry(ctx, id)
+}
+
+func (store *instrumentedStore) GetIdempotencyKey(ctx context.Context, arg db.GetIdempotencyKeyParams) (_ db.IdempotencyKey, err error) {
+	defer observe("GetIdempotencyKey", time.Now(), &err)
+	return store.store.GetIdempotencyKey(ctx, arg)
+}
+
+func (store *instrumentedStore) GetLoginAttempt(ctx context.Context, arg db.GetLoginAttemptParams) (_ db.LoginAttempt, err error) {
+	defer observe("GetLoginAttempt", time.Now(), &err)
+	return store.store.GetLoginAttempt(ctx, arg)
+}
+
+func (store *instrumentedStore) GetScheduledTransfer(ctx context.Context, id int64) (_ db.ScheduledTransfer, err error) {
+	defer observe("GetScheduledTransfer", time.Now(), &err)
+	return store.store.GetScheduledTransfer(ctx, id)
+}
+
+func (store *instrumentedStore) GetSession(ctx context.Context, id uuid.UUID) (_ db.Session, err error) {
+	defer observe("GetSession", time.Now(), &err)
+	return store.store.GetSession(ctx, id)
+}
+
+func (store *instrumentedStore) GetTOTPSecret(ctx context.Context, username string) (_ db.TotpSecret, err error) {
+	defer observe("GetTOTPSecret", time.Now(), &err)
+	return store.store.GetTOTPSecret(ctx, username)
+}
+
+func (store *instrumentedStore) GetTransfer(ctx context.Context, id int64) (_ db.Transfer, err error) {
+	defer observe("GetTransfer", time.Now(), &err)
+	return store.store.GetTransfer(ctx, id)
+}
+
+func (store *instrumentedStore) GetUser(ctx context.Context, username string) (_ db.User, err error) {
+	defer observe("GetUser", time.Now(), &err)
+	return store.store.GetUser(ctx, username)
+}
+
+func (store *instrumentedStore) GetUserByEmail(ctx context.Context, email string) (_ db.User, err error) {
+	defer observe("GetUserByEmail", time.Now(), &err)
+	return store.store.GetUserByEmail(ctx, email)
+}
+
+func (store *instrumentedStore) GetUserPasswordChangedAt(ctx context.Context, username string) (_ time.Time, err error) {
+	defer observe("GetUserPasswordChangedAt", time.Now(), &err)
+	return store.store.GetUserPasswordChangedAt(ctx, username)
+}
+
+func (store *instrumentedStore) GetWebhook(ctx context.Context, id int64) (_ db.Webhook, err error) {
+	defer observe("GetWebhook", time.Now(), &err)
+	return store.store.GetWebhook(ctx, id)
+}
+
+func (store *instrumentedStore) IsTokenRevoked(ctx context.Context, arg db.IsTokenRevokedParams) (_ bool, err error) {
+	defer observe("IsTokenRevoked", time.Now(), &err)
+	return store.store.IsTokenRevoked(ctx, arg)
+}
+
+func (store *instrumentedStore) ListAccountEntrySums(ctx context.Context, arg db.ListAccountEntrySumsParams) (_ []db.ListAccountEntrySumsRow, err error) {
+	defer observe("ListAccountEntrySums", time.Now(), &err)
+	return store.store.ListAccountEntrySums(ctx, arg)
+}
+
+func (store *instrumentedStore) ListAccounts(ctx context.Context, arg db.ListAccountsParams) (_ []db.Account, err error) {
+	defer observe("ListAccounts", time.Now(), &err)
+	return store.store.ListAccounts(ctx, arg)
+}
+
+func (store *instrumentedStore) ListAccountsAfter(ctx context.Context, arg db.ListAccountsAfterParams) (_ []db.Account, err error) {
+	defer observe("ListAccountsAfter", time.Now(), &err)
+	return store.store.ListAccountsAfter(ctx, arg)
+}
+
+func (store *instrumentedStore) ListAccountsBefore(ctx context.Context, arg db.ListAccountsBeforeParams) (_ []db.Account, err error) {
+	defer observe("ListAccountsBefore", time.Now(), &err)
+	return store.store.ListAccountsBefore(ctx, arg)
+}
+
+func (store *instrumentedStore) ListDueScheduledTransfers(ctx context.Context, arg db.ListDueScheduledTransfersParams) (_ []db.ScheduledTransfer, err error) {
+	defer observe("ListDueScheduledTransfers", time.Now(), &err)
+	return store.store.ListDueScheduledTransfers(ctx, arg)
+}
+
+func (store *instrumentedStore) ListDueWebhookDeliveries(ctx context.Context, arg db.ListDueWebhookDeliveriesParams) (_ []db.ListDueWebhookDeliveriesRow, err error) {
+	defer observe("ListDueWebhookDeliveries", time.Now(), &err)
+	return store.store.ListDueWebhookDeliveries(ctx, arg)
+}
+
+func (store *instrumentedStore) ListEntries(ctx context.Context, arg db.ListEntriesParams) (_ []db.Entry, err error) {
+	defer observe("ListEntries", time.Now(), &err)
+	return store.store.ListEntries(ctx, arg)
+}
+
+func (store *instrumentedStore) ListEntriesAfter(ctx context.Context, arg db.ListEntriesAfterParams) (_ []db.Entry, err error) {
+	defer observe("ListEntriesAfter", time.Now(), &err)
+	return store.store.ListEntriesAfter(ctx, arg)
+}
+
+func (store *instrumentedStore) ListEntriesBefore(ctx context.Context, arg db.ListEntriesBeforeParams) (_ []db.Entry, err error) {
+	defer observe("ListEntriesBefore", time.Now(), &err)
+	return store.store.ListEntriesBefore(ctx, arg)
+}
+
+func (store *instrumentedStore) ListScheduledTransferRuns(ctx context.Context, arg db.ListScheduledTransferRunsParams) (_ []db.ScheduledTransferRun, err error) {
+	defer observe("ListScheduledTransferRuns", time.Now(), &err)
+	return store.store.ListScheduledTransferRuns(ctx, arg)
+}
+
+func (store *instrumentedStore) ListScheduledTransfersAfter(ctx context.Context, arg db.ListScheduledTransfersAfterParams) (_ []db.ScheduledTransfer, err error) {
+	defer observe("ListScheduledTransfersAfter", time.Now(), &err)
+	return store.store.ListScheduledTransfersAfter(ctx, arg)
+}
+
+func (store *instrumentedStore) ListScheduledTransfersBefore(ctx context.Context, arg db.ListScheduledTransfersBeforeParams) (_ []db.ScheduledTransfer, err error) {
+	defer observe("ListScheduledTransfersBefore", time.Now(), &err)
+	return store.store.ListScheduledTransfersBefore(ctx, arg)
+}
+
+func (store *instrumentedStore) ListStatementEntries(ctx context.Context, arg db.ListStatementEntriesParams) (_ []db.ListStatementEntriesRow, err error) {
+	defer observe("ListStatementEntries", time.Now(), &err)
+	return store.store.ListStatementEntries(ctx, arg)
+}
+
+func (store *instrumentedStore) ListTransferEntries(ctx context.Context, transferID int64) (_ []db.Entry, err error) {
+	defer observe("ListTransferEntries", time.Now(), &err)
+	return store.store.ListTransferEntries(ctx, transferID)
+}
+
+func (store *instrumentedStore) ListTransferEntryCounts(ctx context.Context, arg db.ListTransferEntryCountsParams) (_ []db.ListTransferEntryCountsRow, err error) {
+	defer observe("ListTransferEntryCounts", time.Now(), &err)
+	return store.store.ListTransferEntryCounts(ctx, arg)
+}
+
+func (store *instrumentedStore) ListTransfers(ctx context.Context, arg db.ListTransfersParams) (_ []db.Transfer, err error) {
+	defer observe("ListTransfers", time.Now(), &err)
+	return store.store.ListTransfers(ctx, arg)
+}
+
+func (store *instrumentedStore) ListTransfersAfter(ctx context.Context, arg db.ListTransfersAfterParams) (_ []db.Transfer, err error) {
+	defer observe("ListTransfersAfter", time.Now(), &err)
+	return store.store.ListTransfersAfter(ctx, arg)
+}
+
+func (store *instrumentedStore) ListTransfersBefore(ctx context.Context, arg db.ListTransfersBeforeParams) (_ []db.Transfer, err error) {
+	defer observe("ListTransfersBefore", time.Now(), &err)
+	return store.store.ListTransfersBefore(ctx, arg)
+}
+
+func (store *instrumentedStore) ListUndispatchedOutboxEvents(ctx context.Context, limit int32) (_ []db.OutboxEvent, err error) {
+	defer observe("ListUndispatchedOutboxEvents", time.Now(), &err)
+	return store.store.ListUndispatchedOutboxEvents(ctx, limit)
+}
+
+func (store *instrumentedStore) ListUnusedRecoveryCodes(ctx context.Context, username string) (_ []db.RecoveryCode, err error) {
+	defer observe("ListUnusedRecoveryCodes", time.Now(), &err)
+	return store.store.ListUnusedRecoveryCodes(ctx, username)
+}
+
+func (store *instrumentedStore) ListUsers(ctx context.Context, arg db.ListUsersParams) (_ []db.User, err error) {
+	defer observe("ListUsers", time.Now(), &err)
+	return store.store.ListUsers(ctx, arg)
+}
+
+func (store *instrumentedStore) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) (_ []db.WebhookDelivery, err error) {
+	defer observe("ListWebhookDeliveries", time.Now(), &err)
+	return store.store.ListWebhookDeliveries(ctx, arg)
+}
+
+func (store *instrumentedStore) ListWebhooks(ctx context.Context, owner string) (_ []db.Webhook, err error) {
+	defer observe("ListWebhooks", time.Now(), &err)
+	return store.store.ListWebhooks(ctx, owner)
+}
+
+func (store *instrumentedStore) MarkOutboxEventDispatched(ctx context.Context, id int64) (err error) {
+	defer observe("MarkOutboxEventDispatched", time.Now(), &err)
+	return store.store.MarkOutboxEventDispatched(ctx, id)
+}
+
+func (store *instrumentedStore) RemoveLoginFailure(ctx context.Context, arg db.RemoveLoginFailureParams) (err error) {
+	defer observe("RemoveLoginFailure", time.Now(), &err)
+	return store.store.RemoveLoginFailure(ctx, arg)
+}
+
+func (store *instrumentedStore) ResetUserPassword(ctx context.Context, arg db.ResetUserPasswordParams) (_ db.User, err error) {
+	defer observe("ResetUserPassword", time.Now(), &err)
+	return store.store.ResetUserPassword(ctx, arg)
+}
+
+func (store *instrumentedStore) StartWebhookDeliveryAttempt(ctx context.Context, arg db.StartWebhookDeliveryAttemptParams) (err error) {
+	defer observe("StartWebhookDeliveryAttempt", time.Now(), &err)
+	return store.store.StartWebhookDeliveryAttempt(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (_ db.Account, err error) {
+	defer observe("UpdateAccount", time.Now(), &err)
+	return store.store.UpdateAccount(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateAccountBalance(ctx context.Context, arg db.UpdateAccountBalanceParams) (_ db.Account, err error) {
+	defer observe("UpdateAccountBalance", time.Now(), &err)
+	return store.store.UpdateAccountBalance(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateAccountFrozen(ctx context.Context, arg db.UpdateAccountFrozenParams) (_ db.Account, err error) {
+	defer observe("UpdateAccountFrozen", time.Now(), &err)
+	return store.store.UpdateAccountFrozen(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateAccountOverdraftLimit(ctx context.Context, arg db.UpdateAccountOverdraftLimitParams) (_ db.Account, err error) {
+	defer observe("UpdateAccountOverdraftLimit", time.Now(), &err)
+	return store.store.UpdateAccountOverdraftLimit(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateIdempotencyKeyResponse(ctx context.Context, arg db.UpdateIdempotencyKeyResponseParams) (err error) {
+	defer observe("UpdateIdempotencyKeyResponse", time.Now(), &err)
+	return store.store.UpdateIdempotencyKeyResponse(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateScheduledTransfer(ctx context.Context, arg db.UpdateScheduledTransferParams) (_ db.ScheduledTransfer, err error) {
+	defer observe("UpdateScheduledTransfer", time.Now(), &err)
+	return store.store.UpdateScheduledTransfer(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateScheduledTransferRun(ctx context.Context, arg db.UpdateScheduledTransferRunParams) (_ db.ScheduledTransferRun, err error) {
+	defer observe("UpdateScheduledTransferRun", time.Now(), &err)
+	return store.store.UpdateScheduledTransferRun(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (_ db.User, err error) {
+	defer observe("UpdateUser", time.Now(), &err)
+	return store.store.UpdateUser(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateUserEmailVerified(ctx context.Context, arg db.UpdateUserEmailVerifiedParams) (_ db.User, err error) {
+	defer observe("UpdateUserEmailVerified", time.Now(), &err)
+	return store.store.UpdateUserEmailVerified(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (_ db.User, err error) {
+	defer observe("UpdateUserRole", time.Now(), &err)
+	return store.store.UpdateUserRole(ctx, arg)
+}
+
+func (store *instrumentedStore) UpdateWebhookDelivery(ctx context.Context, arg db.UpdateWebhookDeliveryParams) (_ db.WebhookDelivery, err error) {
+	defer observe("UpdateWebhookDelivery", time.Now(), &err)
+	return store.store.UpdateWebhookDelivery(ctx, arg)
+}
+
+func (store *instrumentedStore) UpsertTOTPSecret(ctx context.Context, arg db.UpsertTOTPSecretParams) (_ db.TotpSecret, err error) {
+	defer observe("UpsertTOTPSecret", time.Now(), &err)
+	return store.store.UpsertTOTPSecret(ctx, arg)
+}
+
+func (store *instrumentedStore) UpsertUserTokenRevocation(ctx context.Context, arg db.UpsertUserTokenRevocationParams) (err error) {
+	defer observe("UpsertUserTokenRevocation", time.Now(), &err)
+	return store.store.UpsertUserTokenRevocation(ctx, arg)
+}
+
+func (store *instrumentedStore) UseRecoveryCode(ctx context.Context, id int64) (_ db.RecoveryCode, err error) {
+	defer observe("UseRecoveryCode", time.Now(), &err)
+	return store.store.UseRecoveryCode(ctx, id)
+}
+
+func (store *instrumentedStore) UseTOTPStep(ctx context.Context, arg db.UseTOTPStepParams) (_ db.TotpSecret, err error) {
+	defer observe("UseTOTPStep", time.Now(), &err)
+	return store.store.UseTOTPStep(ctx, arg)
+}
+
+func (store *instrumentedStore) UseVerifyEmail(ctx context.Context, arg db.UseVerifyEmailParams) (_ db.VerifyEmail, err error) {
+	defer observe("UseVerifyEmail", time.Now(), &err)
+	return store.store.UseVerifyEmail(ctx, arg)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedStoreTransferTx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+
+	result := db.TransferTxResult{
+		Transfer:    db.Transfer{Amount: 250},
+		FromAccount: db.Account{Currency: util.USD},
+		ToAccount:   db.Account{Currency: util.EUR},
+	}
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(result, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrConnDone)
+
+	created := testutil.ToFloat64(transfersCreated.WithLabelValues(util.USD, util.EUR))
+	amount := testutil.ToFloat64(transferAmount.WithLabelValues(util.USD))
+
+	instrumented := NewInstrumentedStore(store)
+	_, err := instrumented.TransferTx(context.Background(), db.TransferTxParams{})
+	require.NoError(t, err)
+	_, err = instrumented.TransferTx(context.Background(), db.TransferTxParams{})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	// Only the successful transfer is counted
+	require.Equal(t, created+1, testutil.ToFloat64(transfersCreated.WithLabelValues(util.USD, util.EUR)))
+	require.Equal(t, amount+250, testutil.ToFloat64(transferAmount.WithLabelValues(util.USD)))
+}
+
+func TestInstrumentedStoreCashTx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+
+	account := db.Account{Currency: util.GBP}
+	store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CashTxResult{
+		Account: account,
+		Entry:   db.Entry{Type: db.EntryTypeDeposit, Amount: 100},
+	}, nil)
+	store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CashTxResult{
+		Account: account,
+		Entry:   db.Entry{Type: db.EntryTypeWithdrawal, Amount: -40},
+	}, nil)
+
+	deposited := testutil.ToFloat64(cashAmount.WithLabelValues("deposit", util.GBP))
+	withdrawn := testutil.ToFloat64(cashAmount.WithLabelValues("withdrawal", util.GBP))
+
+	instrumented := NewInstrumentedStore(store)
+	_, err := instrumented.DepositTx(context.Background(), db.CashTxParams{})
+	require.NoError(t, err)
+	_, err = instrumented.WithdrawTx(context.Background(), db.CashTxParams{})
+	require.NoError(t, err)
+
+	// Withdrawals are counted as positive amounts
+	require.Equal(t, deposited+100, testutil.ToFloat64(cashAmount.WithLabelValues("deposit", util.GBP)))
+	require.Equal(t, withdrawn+40, testutil.ToFloat64(cashAmount.WithLabelValues("withdrawal", util.GBP)))
+}
+
+func TestInstrumentedStoreObservesResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrConnDone)
+
+	instrumented := NewInstrumentedStore(store)
+	for i := 0; i < 3; i++ {
+		instrumented.GetAccount(context.Background(), 1)
+	}
+
+	for _, result := range []string{"ok", "not_found", "error"} {
+		var metric dto.Metric
+		histogram := storeOperationDuration.WithLabelValues("GetAccount", result).(prometheus.Histogram)
+		require.NoError(t, histogram.Write(&metric))
+		require.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount(), result)
+	}
+}
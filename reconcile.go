@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/reconcile"
+	"github.com/harrychopra/go-api/util"
+)
+
+// Exit codes of the reconcile subcommand
+const (
+	exitReconciled = 0
+	exitDrift      = 1 // Discrepancies remain, the report lists them
+	exitFailed     = 2
+)
+
+// runReconcile checks the ledger, writes the report to stdout and returns the exit code
+func runReconcile(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	format := flags.String("format", "json", "report format, json or csv")
+	fix := flags.Bool("fix", false, "write adjustment entries for accounts whose balance drifted from their entries")
+	batchSize := flags.Int("batch-size", 500, "accounts or transfers read per query")
+	if err := flags.Parse(args); err != nil {
+		return exitFailed
+	}
+	writeReport := map[string]func(reconcile.Report) error{
+		"json": func(report reconcile.Report) error { return report.WriteJSON(os.Stdout) },
+		"csv":  func(report reconcile.Report) error { return report.WriteCSV(os.Stdout) },
+	}[*format]
+	if writeReport == nil {
+		fmt.Fprintf(os.Stderr, "unsupported report format %q\n", *format)
+		return exitFailed
+	}
+
+	config, err := util.LoadConfig(".")
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load config")
+		return exitFailed
+	}
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to connect to db")
+		return exitFailed
+	}
+	defer conn.Close()
+
+	report, err := reconcile.Run(context.Background(), db.NewStore(conn), reconcile.Options{
+		Fix:       *fix,
+		BatchSize: int32(*batchSize),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("reconciliation failed")
+		return exitFailed
+	}
+	if err := writeReport(report); err != nil {
+		log.Error().Err(err).Msg("failed to write report")
+		return exitFailed
+	}
+	log.Info().
+		Int64("accounts", report.AccountsChecked).
+		Int64("transfers", report.TransfersChecked).
+		Int("discrepancies", len(report.Discrepancies)).
+		Int("unresolved", report.Unresolved()).
+		Msg("reconciliation finished")
+	if report.Unresolved() > 0 {
+		return exitDrift
+	}
+	return exitReconciled
+}
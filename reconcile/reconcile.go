@@ -0,0 +1,153 @@
+// Package reconcile verifies the cached balances of accounts and the entries of transfers against the ledger
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// Kinds of discrepancy
+const (
+	// The balance of an account differs from the sum of its entries
+	BalanceDrift = "balance_drift"
+	// A transfer doesn't have exactly one entry debiting its from account and one crediting its to account
+	TransferEntries = "transfer_entries"
+)
+
+const defaultBatchSize = 500
+
+// Discrepancy is a single finding of a reconciliation run
+type Discrepancy struct {
+	Kind       string `json:"kind"`
+	AccountID  int64  `json:"account_id,omitempty"`
+	TransferID int64  `json:"transfer_id,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Balance    int64  `json:"balance"`
+	EntriesSum int64  `json:"entries_sum"`
+	// Balance minus the sum of the entries, zero for transfer discrepancies
+	Drift  int64  `json:"drift"`
+	Detail string `json:"detail"`
+	// Set once an adjustment entry was written for the drift
+	Fixed bool `json:"fixed"`
+}
+
+// Report is the outcome of a reconciliation run
+type Report struct {
+	AccountsChecked  int64         `json:"accounts_checked"`
+	TransfersChecked int64         `json:"transfers_checked"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+}
+
+// Unresolved returns the number of discrepancies that weren't fixed
+func (report Report) Unresolved() int {
+	n := 0
+	for _, discrepancy := range report.Discrepancies {
+		if !discrepancy.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// Options configure a reconciliation run
+type Options struct {
+	// Fix writes an adjustment entry for each account whose balance drifted, except the bank's ledger accounts
+	Fix bool
+	// BatchSize is the number of accounts or transfers read per query, 500 if zero
+	BatchSize int32
+}
+
+// Run checks every account and every transfer, reading them in batches by id
+func Run(ctx context.Context, store db.Store, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	report := Report{Discrepancies: []Discrepancy{}}
+	if err := checkAccounts(ctx, store, opts, &report); err != nil {
+		return report, err
+	}
+	if err := checkTransfers(ctx, store, opts, &report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func checkAccounts(ctx context.Context, store db.Store, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		accounts, err := store.ListAccountEntrySums(ctx, db.ListAccountEntrySumsParams{
+			AfterID: afterID,
+			Limit:   opts.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list accounts after [%d]: %w", afterID, err)
+		}
+		for _, account := range accounts {
+			report.AccountsChecked++
+			if account.Balance == account.EntriesSum {
+				continue
+			}
+			discrepancy := Discrepancy{
+				Kind:       BalanceDrift,
+				AccountID:  account.ID,
+				Currency:   account.Currency,
+				Balance:    account.Balance,
+				EntriesSum: account.EntriesSum,
+				Drift:      account.Balance - account.EntriesSum,
+				Detail:     fmt.Sprintf("balance %d, entries sum to %d", account.Balance, account.EntriesSum),
+			}
+			switch {
+			case !opts.Fix:
+			case account.Owner == db.SystemUsername:
+				discrepancy.Detail += "; ledger accounts can't be adjusted"
+			default:
+				if _, err := store.AdjustmentTx(ctx, db.AdjustmentTxParams{
+					AccountID: account.ID,
+					Amount:    discrepancy.Drift,
+					Memo:      "reconciliation adjustment",
+				}); err != nil {
+					return fmt.Errorf("adjust account [%d]: %w", account.ID, err)
+				}
+				discrepancy.Fixed = true
+			}
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+		}
+		if len(accounts) < int(opts.BatchSize) {
+			return nil
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
+
+func checkTransfers(ctx context.Context, store db.Store, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		transfers, err := store.ListTransferEntryCounts(ctx, db.ListTransferEntryCountsParams{
+			AfterID: afterID,
+			Limit:   opts.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list transfers after [%d]: %w", afterID, err)
+		}
+		for _, transfer := range transfers {
+			report.TransfersChecked++
+			if transfer.EntryCount == 2 && transfer.FromEntryCount == 1 && transfer.ToEntryCount == 1 {
+				continue
+			}
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:       TransferEntries,
+				TransferID: transfer.ID,
+				Detail: fmt.Sprintf("%d transfer entries, %d debiting account [%d] by %d, %d crediting account [%d] with %d",
+					transfer.EntryCount,
+					transfer.FromEntryCount, transfer.FromAccountID, transfer.Amount,
+					transfer.ToEntryCount, transfer.ToAccountID, transfer.ToAmount),
+			})
+		}
+		if len(transfers) < int(opts.BatchSize) {
+			return nil
+		}
+		afterID = transfers[len(transfers)-1].ID
+	}
+}
@@ -0,0 +1,139 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	balanced := db.ListAccountEntrySumsRow{ID: 1, Owner: util.RandomName(), Currency: util.USD, Balance: 100, EntriesSum: 100}
+	drifted := db.ListAccountEntrySumsRow{ID: 2, Owner: util.RandomName(), Currency: util.USD, Balance: 100, EntriesSum: 70}
+	ledger := db.ListAccountEntrySumsRow{ID: 3, Owner: db.SystemUsername, Currency: util.USD, Balance: -5, EntriesSum: 0}
+	transfer := db.ListTransferEntryCountsRow{ID: 1, FromAccountID: 1, ToAccountID: 2, Amount: 10, ToAmount: 10,
+		EntryCount: 2, FromEntryCount: 1, ToEntryCount: 1}
+	unlinked := db.ListTransferEntryCountsRow{ID: 2, FromAccountID: 1, ToAccountID: 2, Amount: 10, ToAmount: 10,
+		EntryCount: 1, FromEntryCount: 1}
+
+	testCases := []struct {
+		name        string
+		opts        Options
+		buildStubs  func(store *mock.MockStore)
+		checkReport func(t *testing.T, report Report)
+	}{
+		{
+			name: "Reconciled",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountEntrySums(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListAccountEntrySumsRow{balanced}, nil)
+				store.EXPECT().ListTransferEntryCounts(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListTransferEntryCountsRow{transfer}, nil)
+			},
+			checkReport: func(t *testing.T, report Report) {
+				require.Equal(t, int64(1), report.AccountsChecked)
+				require.Equal(t, int64(1), report.TransfersChecked)
+				require.Empty(t, report.Discrepancies)
+				require.Zero(t, report.Unresolved())
+			},
+		},
+		{
+			name: "Drift",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountEntrySums(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListAccountEntrySumsRow{balanced, drifted}, nil)
+				store.EXPECT().ListTransferEntryCounts(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListTransferEntryCountsRow{transfer, unlinked}, nil)
+				store.EXPECT().AdjustmentTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkReport: func(t *testing.T, report Report) {
+				require.Len(t, report.Discrepancies, 2)
+				require.Equal(t, BalanceDrift, report.Discrepancies[0].Kind)
+				require.Equal(t, drifted.ID, report.Discrepancies[0].AccountID)
+				require.Equal(t, int64(30), report.Discrepancies[0].Drift)
+				require.Equal(t, TransferEntries, report.Discrepancies[1].Kind)
+				require.Equal(t, unlinked.ID, report.Discrepancies[1].TransferID)
+				require.Equal(t, 2, report.Unresolved())
+			},
+		},
+		{
+			name: "Fix",
+			opts: Options{Fix: true},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().ListAccountEntrySums(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.ListAccountEntrySumsRow{drifted, ledger}, nil)
+				store.EXPECT().ListTransferEntryCounts(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().
+					AdjustmentTx(gomock.Any(), gomock.Eq(db.AdjustmentTxParams{
+						AccountID: drifted.ID,
+						Amount:    30,
+						Memo:      "reconciliation adjustment",
+					})).
+					Times(1)
+			},
+			checkReport: func(t *testing.T, report Report) {
+				require.Len(t, report.Discrepancies, 2)
+				require.True(t, report.Discrepancies[0].Fixed)
+				// Ledger accounts have no other side to adjust against
+				require.False(t, report.Discrepancies[1].Fixed)
+				require.Equal(t, 1, report.Unresolved())
+			},
+		},
+		{
+			name: "Batches",
+			opts: Options{BatchSize: 2},
+			buildStubs: func(store *mock.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ListAccountEntrySums(gomock.Any(), gomock.Eq(db.ListAccountEntrySumsParams{AfterID: 0, Limit: 2})).
+						Times(1).
+						Return([]db.ListAccountEntrySumsRow{balanced, drifted}, nil),
+					store.EXPECT().
+						ListAccountEntrySums(gomock.Any(), gomock.Eq(db.ListAccountEntrySumsParams{AfterID: drifted.ID, Limit: 2})).
+						Times(1).
+						Return([]db.ListAccountEntrySumsRow{ledger}, nil),
+				)
+				store.EXPECT().ListTransferEntryCounts(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkReport: func(t *testing.T, report Report) {
+				require.Equal(t, int64(3), report.AccountsChecked)
+				require.Len(t, report.Discrepancies, 2)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			report, err := Run(context.Background(), store, testCase.opts)
+			require.NoError(t, err)
+			testCase.checkReport(t, report)
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := Report{Discrepancies: []Discrepancy{
+		{Kind: BalanceDrift, AccountID: 2, Currency: util.USD, Balance: 100, EntriesSum: 70, Drift: 30, Detail: "drift, here"},
+		{Kind: TransferEntries, TransferID: 5, Detail: "1 transfer entries"},
+	}}
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		csvHeader,
+		{BalanceDrift, "2", "", util.USD, "100", "70", "30", "drift, here", "false"},
+		{TransferEntries, "", "5", "", "0", "0", "0", "1 transfer entries", "false"},
+	}, records)
+}
@@ -0,0 +1,52 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// WriteJSON writes the report as an indented JSON document
+func (report Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+var csvHeader = []string{
+	"kind", "account_id", "transfer_id", "currency", "balance", "entries_sum", "drift", "detail", "fixed",
+}
+
+// WriteCSV writes the discrepancies of the report, one per row after a header
+func (report Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, d := range report.Discrepancies {
+		if err := writer.Write([]string{
+			d.Kind,
+			formatID(d.AccountID),
+			formatID(d.TransferID),
+			d.Currency,
+			strconv.FormatInt(d.Balance, 10),
+			strconv.FormatInt(d.EntriesSum, 10),
+			strconv.FormatInt(d.Drift, 10),
+			d.Detail,
+			strconv.FormatBool(d.Fixed),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatID leaves the ids that don't apply to a discrepancy empty
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,67 @@
+// Package scheduler makes the scheduled transfers as they come due
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultBatchSize = 50
+
+// Runner makes the scheduled transfers due at now, up to limit of them. *service.Bank is one.
+type Runner interface {
+	RunScheduledTransfers(ctx context.Context, now time.Time, limit int32) (int, error)
+}
+
+// Worker polls for due scheduled transfers. Any number of workers can share a database, each due transfer
+// is claimed by one of them.
+type Worker struct {
+	runner    Runner
+	interval  time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+// NewWorker returns a worker polling runner every interval
+func NewWorker(runner Runner, interval time.Duration) *Worker {
+	return &Worker{
+		runner:    runner,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+		now:       time.Now,
+	}
+}
+
+// Run polls until ctx is done. A batch in progress is finished rather than cut short, so that the outcome
+// of each transfer made is recorded.
+func (worker *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		worker.runDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs batches until one comes back short of a full batch, or ctx is done
+func (worker *Worker) runDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := worker.runner.RunScheduledTransfers(context.Background(), worker.now(), worker.batchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to run scheduled transfers")
+			return
+		}
+		if n > 0 {
+			log.Info().Int("count", n).Msg("ran scheduled transfers")
+		}
+		if n < int(worker.batchSize) {
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner returns the counts of runs in turn, then zero
+type fakeRunner struct {
+	counts []int
+	err    error
+	calls  int
+	limits []int32
+}
+
+func (runner *fakeRunner) RunScheduledTransfers(ctx context.Context, now time.Time, limit int32) (int, error) {
+	runner.limits = append(runner.limits, limit)
+	runner.calls++
+	if runner.calls > len(runner.counts) {
+		return 0, runner.err
+	}
+	return runner.counts[runner.calls-1], runner.err
+}
+
+func TestWorkerRunDue(t *testing.T) {
+	// Full batches are followed by another right away
+	runner := &fakeRunner{counts: []int{2, 2, 1}}
+	worker := NewWorker(runner, time.Hour)
+	worker.batchSize = 2
+	worker.runDue(context.Background())
+	require.Equal(t, 3, runner.calls)
+	require.Equal(t, []int32{2, 2, 2}, runner.limits)
+
+	// Errors wait for the next tick
+	runner = &fakeRunner{counts: []int{2}, err: errors.New("db down")}
+	worker = NewWorker(runner, time.Hour)
+	worker.batchSize = 2
+	worker.runDue(context.Background())
+	require.Equal(t, 1, runner.calls)
+}
+
+func TestWorkerRunStops(t *testing.T) {
+	runner := &fakeRunner{}
+	worker := NewWorker(runner, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(stopped)
+	}()
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker didn't stop")
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/lib/pq"
+)
+
+// CreateAccount opens an empty account in currency for owner, once it verified its email address
+func (bank *Bank) CreateAccount(ctx context.Context, owner, currency string) (db.Account, error) {
+	if err := bank.checkEmailVerified(ctx, owner); err != nil {
+		return db.Account{}, err
+	}
+	account, err := bank.store.CreateAccountTx(ctx, db.CreateAccountParams{
+		Owner:    owner,
+		Currency: currency,
+		Balance:  0,
+	})
+	if err != nil {
+		// The error of the transaction wraps the one of the query
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code.Name() {
+			// User for this account does not exist
+			case "foreign_key_violation":
+				return account, newError(NotFound, "user [%s] not found", owner)
+			// User for this account already has an account with this currency
+			case "unique_violation":
+				return account, newError(Conflict, "user [%s] already has a %s account", owner, currency)
+			}
+		}
+		return account, internalError(err)
+	}
+	return account, nil
+}
+
+// GetAccount returns the account, if the actor can read it
+func (bank *Bank) GetAccount(ctx context.Context, actor Actor, accountID int64) (db.Account, error) {
+	account, err := bank.getAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+	if !actor.canRead(account.Owner) {
+		return account, newError(Forbidden, "account does not belong to authenticated user")
+	}
+	return account, nil
+}
+
+// ListAccounts returns a page of the owner's accounts, sorted by id, and whether there are more in the page direction
+func (bank *Bank) ListAccounts(ctx context.Context, owner string, page Page) ([]db.Account, bool, error) {
+	var accounts []db.Account
+	var err error
+	if page.BeforeID > 0 {
+		accounts, err = bank.store.ListAccountsBefore(ctx, db.ListAccountsBeforeParams{
+			Owner:    owner,
+			Limit:    page.limit(),
+			BeforeID: page.BeforeID,
+		})
+	} else {
+		accounts, err = bank.store.ListAccountsAfter(ctx, db.ListAccountsAfterParams{
+			Owner:   owner,
+			Limit:   page.limit(),
+			AfterID: page.AfterID,
+		})
+	}
+	if err != nil {
+		return nil, false, internalError(err)
+	}
+	n, hasMore := page.trim(len(accounts))
+	accounts = accounts[:n]
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, hasMore, nil
+}
+
+// ListAccountsByOffset returns the owner's accounts skipping offset rows.
+//
+// Deprecated: use ListAccounts.
+func (bank *Bank) ListAccountsByOffset(ctx context.Context, owner string, limit, offset int32) ([]db.Account, error) {
+	accounts, err := bank.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  owner,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return accounts, nil
+}
+
+// FreezeAccount freezes or unfreezes an account. Callers must restrict it to admins.
+func (bank *Bank) FreezeAccount(ctx context.Context, accountID int64, frozen bool) (db.Account, error) {
+	account, err := bank.store.UpdateAccountFrozen(ctx, db.UpdateAccountFrozenParams{
+		ID:       accountID,
+		IsFrozen: frozen,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, newError(NotFound, "account [%d] not found", accountID)
+		}
+		return account, internalError(err)
+	}
+	return account, nil
+}
+
+// getAccount returns the account, whoever it belongs to
+func (bank *Bank) getAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := bank.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, newError(NotFound, "account [%d] not found", accountID)
+		}
+		return account, internalError(err)
+	}
+	return account, nil
+}
+
+// checkOpen fails unless money can move in and out of the account: it must not be frozen, nor one of
+// the bank's ledger accounts, which only the store posts to
+func checkOpen(account db.Account) error {
+	if account.IsFrozen {
+		return newError(FailedPrecondition, "account [%d] is frozen", account.ID)
+	}
+	if account.Owner == db.SystemUsername {
+		return newError(FailedPrecondition, "account [%d] is a ledger account", account.ID)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+)
+
+// Actor is the authenticated user an operation is performed for
+type Actor struct {
+	Username string
+	Role     string
+}
+
+// NewActor returns the actor authenticated by an access token
+func NewActor(payload *token.Payload) Actor {
+	return Actor{Username: payload.Username, Role: payload.Role}
+}
+
+// canRead checks if the actor may see an account of owner and its history: customers and tellers
+// only see their own accounts, admins see every account
+func (actor Actor) canRead(owner string) bool {
+	return actor.Role == util.AdminRole || actor.Username == owner
+}
+
+// canDebit checks if the actor may move money out of an account of owner. Only owners can, whatever their role.
+func (actor Actor) canDebit(owner string) bool {
+	return actor.Username == owner
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/harrychopra/go-api/token"
+)
+
+// Authenticate verifies an access token, which mustn't be revoked nor issued before its user last changed their
+// password, and returns its payload for the transports to authorize the request with
+func (bank *Bank) Authenticate(ctx context.Context, accessToken string) (*token.Payload, error) {
+	payload, err := bank.tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, &Error{Kind: Unauthenticated, Message: err.Error(), Err: err}
+	}
+	revoked, err := bank.revocationStore.IsRevoked(ctx, payload)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	if revoked {
+		return nil, newError(Unauthenticated, "token has been revoked")
+	}
+	passwordChangedAt, err := bank.store.GetUserPasswordChangedAt(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newError(Unauthenticated, "user no longer exists")
+		}
+		return nil, internalError(err)
+	}
+	if passwordChangedAt.After(payload.IssuedAt) {
+		return nil, newError(Unauthenticated, "token was issued before the password was changed")
+	}
+	return payload, nil
+}
+
+// RenewAccessTokenResult is the output of RenewAccessToken
+type RenewAccessTokenResult struct {
+	AccessToken   string
+	AccessPayload *token.Payload
+}
+
+// RenewAccessToken issues a new access token for the session of a refresh token, as long as the session is neither
+// blocked nor expired
+func (bank *Bank) RenewAccessToken(ctx context.Context, refreshToken string) (RenewAccessTokenResult, error) {
+	var result RenewAccessTokenResult
+	refreshPayload, err := bank.tokenMaker.VerifyPurposeToken(refreshToken, token.PurposeRefresh)
+	if err != nil {
+		return result, &Error{Kind: Unauthenticated, Message: err.Error(), Err: err}
+	}
+	revoked, err := bank.revocationStore.IsRevoked(ctx, refreshPayload)
+	if err != nil {
+		return result, internalError(err)
+	}
+	if revoked {
+		return result, newError(Unauthenticated, "refresh token has been revoked")
+	}
+	session, err := bank.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return result, newError(NotFound, "session not found")
+		}
+		return result, internalError(err)
+	}
+	switch {
+	case session.IsBlocked:
+		return result, newError(Unauthenticated, "session is blocked")
+	case session.Username != refreshPayload.Username:
+		return result, newError(Unauthenticated, "session does not belong to the token's user")
+	case session.RefreshTokenHash != hashToken(refreshToken):
+		return result, newError(Unauthenticated, "mismatched session token")
+	case time.Now().After(session.ExpiresAt):
+		return result, newError(Unauthenticated, "session has expired")
+	}
+	result.AccessToken, result.AccessPayload, err = bank.tokenMaker.CreateToken(
+		refreshPayload.Username,
+		refreshPayload.Role,
+		bank.config.ACCESS_TOKEN_DURATION,
+	)
+	if err != nil {
+		return result, internalError(err)
+	}
+	return result, nil
+}
+
+// LogoutUser revokes the access token of the actor and, if given, ends the session of its refresh token as well
+func (bank *Bank) LogoutUser(ctx context.Context, accessPayload *token.Payload, refreshToken string) error {
+	var refreshPayload *token.Payload
+	if len(refreshToken) > 0 {
+		var err error
+		if refreshPayload, err = bank.tokenMaker.VerifyPurposeToken(refreshToken, token.PurposeRefresh); err != nil {
+			return &Error{Kind: Unauthenticated, Message: err.Error(), Err: err}
+		}
+		if refreshPayload.Username != accessPayload.Username {
+			return newError(Unauthenticated, "refresh token does not belong to authenticated user")
+		}
+	}
+	if err := bank.revocationStore.RevokeToken(ctx, accessPayload); err != nil {
+		return internalError(err)
+	}
+	if refreshPayload != nil {
+		if err := bank.revocationStore.RevokeToken(ctx, refreshPayload); err != nil {
+			return internalError(err)
+		}
+		if err := bank.store.BlockSession(ctx, refreshPayload.ID); err != nil {
+			return internalError(err)
+		}
+	}
+	return nil
+}
+
+// RevokeUserSessions logs a user out everywhere, invalidating all of their tokens and sessions
+func (bank *Bank) RevokeUserSessions(ctx context.Context, username string) error {
+	if _, err := bank.store.GetUser(ctx, username); err != nil {
+		if err == sql.ErrNoRows {
+			return newError(NotFound, "user [%s] not found", username)
+		}
+		return internalError(err)
+	}
+	if err := bank.revokeUser(ctx, username); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// hashToken returns the hex encoded sha256 digest of a token, which sessions keep instead of their refresh token
+func hashToken(rawToken string) string {
+	hash := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// revokeUser invalidates all the tokens and sessions of a user
+func (bank *Bank) revokeUser(ctx context.Context, username string) error {
+	if err := bank.revocationStore.RevokeUser(ctx, username); err != nil {
+		return err
+	}
+	return bank.store.BlockUserSessions(ctx, username)
+}
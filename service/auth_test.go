@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	username := util.RandomName()
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	bank := newTestBank(t, store, nil)
+
+	accessToken, payload, err := bank.tokenMaker.CreateToken(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq(username)).Times(1).Return(time.Time{}, nil),
+		store.EXPECT().
+			GetUserPasswordChangedAt(gomock.Any(), gomock.Eq(username)).
+			Times(1).
+			Return(payload.IssuedAt.Add(time.Second), nil),
+		store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq(username)).Times(1).Return(time.Time{}, sql.ErrNoRows),
+	)
+
+	authenticated, err := bank.Authenticate(context.Background(), accessToken)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, authenticated.ID)
+
+	// Tokens issued before the password changed, or to users since deleted, no longer work
+	_, err = bank.Authenticate(context.Background(), accessToken)
+	require.Equal(t, Unauthenticated, KindOf(err))
+	_, err = bank.Authenticate(context.Background(), accessToken)
+	require.Equal(t, Unauthenticated, KindOf(err))
+
+	// Refresh tokens aren't access tokens
+	refreshToken, _, err := bank.tokenMaker.CreateRefreshToken(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	_, err = bank.Authenticate(context.Background(), refreshToken)
+	require.Equal(t, Unauthenticated, KindOf(err))
+
+	// Revoked tokens are rejected before the store is asked
+	require.NoError(t, bank.LogoutUser(context.Background(), payload, ""))
+	_, err = bank.Authenticate(context.Background(), accessToken)
+	require.Equal(t, Unauthenticated, KindOf(err))
+}
+
+func TestRenewAccessToken(t *testing.T) {
+	username := util.RandomName()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mock.MockStore, session db.Session)
+		check      func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			check: func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, username, result.AccessPayload.Username)
+				_, err = bank.tokenMaker.VerifyToken(result.AccessToken)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mock.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+			},
+			check: func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error) {
+				require.Equal(t, NotFound, KindOf(err))
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildStubs: func(store *mock.MockStore, session db.Session) {
+				session.IsBlocked = true
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			check: func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error) {
+				require.Equal(t, Unauthenticated, KindOf(err))
+			},
+		},
+		{
+			name: "MismatchedToken",
+			buildStubs: func(store *mock.MockStore, session db.Session) {
+				session.RefreshTokenHash = hashToken(util.RandomString(32))
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			check: func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error) {
+				require.Equal(t, Unauthenticated, KindOf(err))
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildStubs: func(store *mock.MockStore, session db.Session) {
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			check: func(t *testing.T, bank *Bank, result RenewAccessTokenResult, err error) {
+				require.Equal(t, Unauthenticated, KindOf(err))
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			bank := newTestBank(t, store, nil)
+
+			refreshToken, refreshPayload, err := bank.tokenMaker.CreateRefreshToken(username, util.CustomerRole, time.Hour)
+			require.NoError(t, err)
+			testCase.buildStubs(store, db.Session{
+				ID:               refreshPayload.ID,
+				Username:         username,
+				RefreshTokenHash: hashToken(refreshToken),
+				ExpiresAt:        refreshPayload.ExpiredAt,
+			})
+
+			result, err := bank.RenewAccessToken(context.Background(), refreshToken)
+			testCase.check(t, bank, result, err)
+		})
+	}
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	username := util.RandomName()
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	bank := newTestBank(t, store, nil)
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq("ghost")).Times(1).Return(db.User{}, sql.ErrNoRows)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.User{Username: username}, nil)
+	store.EXPECT().BlockUserSessions(gomock.Any(), gomock.Eq(username)).Times(1)
+	store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes()
+
+	accessToken, _, err := bank.tokenMaker.CreateToken(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	require.Equal(t, NotFound, KindOf(bank.RevokeUserSessions(context.Background(), "ghost")))
+	require.NoError(t, bank.RevokeUserSessions(context.Background(), username))
+
+	_, err = bank.Authenticate(context.Background(), accessToken)
+	require.Equal(t, Unauthenticated, KindOf(err))
+}
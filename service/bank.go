@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+)
+
+// Bank implements the banking operations shared by the HTTP and gRPC APIs, independent of either transport.
+// Operations on behalf of a user take the authenticated Actor, and every error returned is an *Error.
+type Bank struct {
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	// revocationStore keeps the tokens revoked before their expiry
+	revocationStore token.RevocationStore
+	fxProvider      util.FXProvider
+	mailer          mail.Mailer
+	// loginLimiter throttles the failed logins of LoginUser
+	loginLimiter *throttle.Limiter
+	// mailing tracks the emails being sent in the background
+	mailing sync.WaitGroup
+}
+
+// NewBank creates a Bank on top of store. revocationStore and loginLimiter is shared by every bank of the process, so that failed
+// logins are counted together whichever API they come through, and revocations seen by all of them.
+func NewBank(
+	config util.Config,
+	store db.Store,
+	tokenMaker token.Maker,
+	revocationStore token.RevocationStore,
+	fxProvider util.FXProvider,
+	mailer mail.Mailer,
+	loginLimiter *throttle.Limiter,
+) *Bank {
+	return &Bank{
+		config:          config,
+		store:           store,
+		tokenMaker:      tokenMaker,
+		revocationStore: revocationStore,
+		fxProvider:      fxProvider,
+		mailer:          mailer,
+		loginLimiter:    loginLimiter,
+	}
+}
+
+// WaitForMail waits for the emails being sent in the background, for them not to be lost on shutdown
+func (bank *Bank) WaitForMail() {
+	bank.mailing.Wait()
+}
+
+// sendInBackground sends msg without holding up the caller, logging a failure with the logger of ctx
+func (bank *Bank) sendInBackground(ctx context.Context, msg mail.Message) {
+	logger := util.LoggerFromContext(ctx)
+	bank.mailing.Add(1)
+	go func() {
+		defer bank.mailing.Done()
+		// The request may be over by then
+		if err := bank.mailer.Send(context.Background(), msg); err != nil {
+			logger.Error().Err(err).Strs("to", msg.To).Str("subject", msg.Subject).Msg("failed to send email")
+		}
+	}()
+}
+
+// newSecret returns 32 random bytes in hex, for the secrets of webhooks and the codes sent to users
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
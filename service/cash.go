@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/lib/pq"
+)
+
+// CashParams is the input of Deposit and Withdraw
+type CashParams struct {
+	AccountID         int64
+	Amount            int64  // In Currency
+	Currency          string // Must match the currency of the account
+	ExternalReference string // Reference of the cash movement outside the bank, recorded once per entry type
+	Memo              string
+}
+
+// Deposit credits cash paid in at the bank to an account. Callers must restrict it to tellers and admins.
+func (bank *Bank) Deposit(ctx context.Context, arg CashParams) (db.CashTxResult, error) {
+	return bank.cashTx(ctx, arg, bank.store.DepositTx)
+}
+
+// Withdraw debits cash paid out by the bank from an account. Callers must restrict it to tellers and admins.
+func (bank *Bank) Withdraw(ctx context.Context, arg CashParams) (db.CashTxResult, error) {
+	return bank.cashTx(ctx, arg, bank.store.WithdrawTx)
+}
+
+func (bank *Bank) cashTx(
+	ctx context.Context,
+	arg CashParams,
+	tx func(context.Context, db.CashTxParams) (db.CashTxResult, error),
+) (db.CashTxResult, error) {
+	var result db.CashTxResult
+	account, err := bank.getAccount(ctx, arg.AccountID)
+	if err != nil {
+		return result, err
+	}
+	if account.Currency != arg.Currency {
+		return result, newError(CurrencyMismatch, "account [%d] currency mismatch: %s vs %s",
+			account.ID, account.Currency, arg.Currency)
+	}
+	if err := checkOpen(account); err != nil {
+		return result, err
+	}
+	result, err = tx(ctx, db.CashTxParams{
+		AccountID:         arg.AccountID,
+		Amount:            arg.Amount,
+		ExternalReference: arg.ExternalReference,
+		Memo:              arg.Memo,
+	})
+	if err != nil {
+		var fundsErr *db.ErrInsufficientFunds
+		if errors.As(err, &fundsErr) {
+			return result, &Error{Kind: InsufficientFunds, Err: fundsErr}
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return result, newError(Conflict, "external reference [%s] was already recorded", arg.ExternalReference)
+		}
+		return result, internalError(err)
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// ListEntries returns a page of the entries of an account the actor can read, sorted by id,
+// and whether there are more in the page direction
+func (bank *Bank) ListEntries(ctx context.Context, actor Actor, accountID int64, page Page) ([]db.Entry, bool, error) {
+	if _, err := bank.GetAccount(ctx, actor, accountID); err != nil {
+		return nil, false, err
+	}
+	var entries []db.Entry
+	var err error
+	if page.BeforeID > 0 {
+		entries, err = bank.store.ListEntriesBefore(ctx, db.ListEntriesBeforeParams{
+			AccountID: accountID,
+			Limit:     page.limit(),
+			BeforeID:  page.BeforeID,
+		})
+	} else {
+		entries, err = bank.store.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+			AccountID: accountID,
+			Limit:     page.limit(),
+			AfterID:   page.AfterID,
+		})
+	}
+	if err != nil {
+		return nil, false, internalError(err)
+	}
+	n, hasMore := page.trim(len(entries))
+	entries = entries[:n]
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, hasMore, nil
+}
+
+// ListEntriesByOffset returns the entries of an account the actor can read skipping offset rows.
+//
+// Deprecated: use ListEntries.
+func (bank *Bank) ListEntriesByOffset(ctx context.Context, actor Actor, accountID int64, limit, offset int32) ([]db.Entry, error) {
+	if _, err := bank.GetAccount(ctx, actor, accountID); err != nil {
+		return nil, err
+	}
+	entries, err := bank.store.ListEntries(ctx, db.ListEntriesParams{
+		AccountID: accountID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies the errors returned by Bank, so that each transport can map them to its own status codes
+type ErrorKind int
+
+const (
+	Internal ErrorKind = iota
+	InvalidArgument
+	Unauthenticated
+	Forbidden
+	NotFound
+	Conflict
+	CurrencyMismatch
+	InsufficientFunds
+	FailedPrecondition
+	TooManyRequests
+)
+
+func (kind ErrorKind) String() string {
+	switch kind {
+	case InvalidArgument:
+		return "invalid_argument"
+	case Unauthenticated:
+		return "unauthenticated"
+	case Forbidden:
+		return "forbidden"
+	case NotFound:
+		return "not_found"
+	case Conflict:
+		return "conflict"
+	case CurrencyMismatch:
+		return "currency_mismatch"
+	case InsufficientFunds:
+		return "insufficient_funds"
+	case FailedPrecondition:
+		return "failed_precondition"
+	case TooManyRequests:
+		return "too_many_requests"
+	}
+	return "internal"
+}
+
+// Error is a domain error returned by Bank
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error // Underlying cause, if any
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case len(e.Message) == 0:
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of err, Internal for errors that aren't an *Error
+func KindOf(err error) ErrorKind {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Kind
+	}
+	return Internal
+}
+
+func newError(kind ErrorKind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// internalError wraps an unexpected error, such as a failed query
+func internalError(err error) *Error {
+	return &Error{Kind: Internal, Err: err}
+}
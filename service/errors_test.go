@@ -0,0 +1,23 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	err := newError(NotFound, "account [%d] not found", 1)
+	require.Equal(t, NotFound, KindOf(err))
+	require.Equal(t, "account [1] not found", err.Error())
+
+	// The kind survives wrapping
+	require.Equal(t, NotFound, KindOf(fmt.Errorf("wrapped: %w", err)))
+
+	require.Equal(t, Internal, KindOf(errors.New("unexpected")))
+	require.Equal(t, Internal, KindOf(internalError(sql.ErrConnDone)))
+	require.Equal(t, sql.ErrConnDone.Error(), internalError(sql.ErrConnDone).Error())
+}
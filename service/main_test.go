@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBank(t *testing.T, store db.Store, rates map[string]string) *Bank {
+	config := util.Config{
+		ACCESS_TOKEN_DURATION: time.Minute,
+		RefreshTokenDuration:  time.Hour,
+		EmailVerificationURL:  "http://localhost:8080/users/verify_email",
+		VerifyEmailDuration:   time.Hour,
+		PasswordResetURL:      "http://localhost:3000/reset_password",
+		PasswordResetDuration: time.Minute,
+		LoginMaxFailures:      4,
+		LoginMaxFailuresPerIP: 20,
+		LoginLockoutDuration:  time.Minute,
+		TOTPIssuer:            "Simple Bank",
+		MFATokenDuration:      time.Minute,
+	}
+	tokenMaker, err := token.NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	fxProvider, err := util.NewStaticFXProvider(rates)
+	require.NoError(t, err)
+	loginLimiter := throttle.NewLimiter(throttle.NewMemoryStore(config.LoginLockoutDuration), throttle.Options{
+		MaxFailures:      config.LoginMaxFailures,
+		MaxFailuresPerIP: config.LoginMaxFailuresPerIP,
+		LockoutDuration:  config.LoginLockoutDuration,
+	})
+	revocationStore := token.NewMemoryRevocationStore(config.MaxTokenDuration())
+	return NewBank(config, store, tokenMaker, revocationStore, fxProvider, &fakeMailer{}, loginLimiter)
+}
+
+// fakeMailer records the emails instead of sending them
+type fakeMailer struct {
+	sent []mail.Message
+	err  error
+}
+
+func (mailer *fakeMailer) Send(_ context.Context, msg mail.Message) error {
+	if mailer.err != nil {
+		return mailer.err
+	}
+	mailer.sent = append(mailer.sent, msg)
+	return nil
+}
+
+func randomAccount(owner, currency string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Currency: currency,
+		Balance:  util.RandomMoney(),
+	}
+}
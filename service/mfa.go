@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/totp"
+	"github.com/harrychopra/go-api/util"
+)
+
+// recoveryCodeCount is the number of recovery codes given when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+// TOTPEnrollment is a secret to add to an authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	// URL is the otpauth:// URL of the secret, for a QR code
+	URL string
+}
+
+// EnrollTOTP creates a TOTP secret for the user. Two-factor authentication only starts once ConfirmTOTP gets a code
+// of it, until then enrolling again replaces the secret. The secret of enabled two-factor authentication is kept, the
+// query itself checking so that a concurrent confirmation can't be undone.
+func (bank *Bank) EnrollTOTP(ctx context.Context, username string) (TOTPEnrollment, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return TOTPEnrollment{}, internalError(err)
+	}
+	if _, err := bank.store.UpsertTOTPSecret(ctx, db.UpsertTOTPSecretParams{Username: username, Secret: secret}); err != nil {
+		if err == sql.ErrNoRows {
+			return TOTPEnrollment{}, newError(FailedPrecondition, "two-factor authentication is already enabled")
+		}
+		return TOTPEnrollment{}, internalError(err)
+	}
+	return TOTPEnrollment{Secret: secret, URL: totp.URL(bank.config.TOTPIssuer, username, secret)}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once code shows the user added the enrolled secret to their app.
+// It returns recovery codes, each logging in once without the app. Only their hashes are kept.
+func (bank *Bank) ConfirmTOTP(ctx context.Context, username, code string) ([]string, error) {
+	secret, err := bank.store.GetTOTPSecret(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, newError(FailedPrecondition, "two-factor authentication hasn't been enrolled")
+		}
+		return nil, internalError(err)
+	}
+	step, ok := totp.Validate(secret.Secret, code, time.Now())
+	if !ok {
+		return nil, newError(InvalidArgument, "invalid code")
+	}
+	recoveryCodes := make([]string, recoveryCodeCount)
+	hashedCodes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		if recoveryCodes[i], err = newRecoveryCode(); err != nil {
+			return nil, internalError(err)
+		}
+		if hashedCodes[i], err = util.HashedPassword(normalizeRecoveryCode(recoveryCodes[i])); err != nil {
+			return nil, internalError(err)
+		}
+	}
+	_, err = bank.store.ConfirmTOTPTx(ctx, db.ConfirmTOTPTxParams{
+		Username:            username,
+		Step:                step,
+		HashedRecoveryCodes: hashedCodes,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newError(Conflict, "two-factor authentication is already enabled")
+		}
+		return nil, internalError(err)
+	}
+	return recoveryCodes, nil
+}
+
+// LoginUserMFAParams is the input of LoginUserMFA
+type LoginUserMFAParams struct {
+	MFAToken string
+	// Code is a TOTP code, or one of the recovery codes
+	Code      string
+	UserAgent string
+	ClientIP  string
+}
+
+// LoginUserMFA finishes the login of a user with two-factor authentication, exchanging the MFA token issued by
+// LoginUser and a code for an access token and a refresh token. Wrong codes are throttled like wrong passwords.
+func (bank *Bank) LoginUserMFA(ctx context.Context, arg LoginUserMFAParams) (LoginUserResult, error) {
+	var result LoginUserResult
+	payload, err := bank.tokenMaker.VerifyPurposeToken(arg.MFAToken, token.PurposeMFAPending)
+	if err != nil {
+		return result, &Error{Kind: Unauthenticated, Message: "mfa token is invalid or expired", Err: err}
+	}
+	reservation, err := bank.reserveLogin(ctx, payload.Username, arg.ClientIP)
+	if err != nil {
+		return result, err
+	}
+	user, err := bank.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return result, internalError(err)
+	}
+	ok, err := bank.useSecondFactor(ctx, user.Username, arg.Code)
+	if err != nil {
+		return result, internalError(err)
+	}
+	if !ok {
+		return result, newError(Unauthenticated, "invalid code")
+	}
+	return bank.startSession(ctx, user, reservation, arg.UserAgent, arg.ClientIP)
+}
+
+// useSecondFactor checks a TOTP code or a recovery code of the user. Either is used up, a TOTP code for its time step.
+func (bank *Bank) useSecondFactor(ctx context.Context, username, code string) (bool, error) {
+	if len(code) == totp.Digits {
+		secret, err := bank.store.GetTOTPSecret(ctx, username)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		_, err = bank.store.UseTOTPStep(ctx, db.UseTOTPStepParams{Username: username, Step: step})
+		if err == sql.ErrNoRows {
+			// The code was already used
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	recoveryCodes, err := bank.store.ListUnusedRecoveryCodes(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	code = normalizeRecoveryCode(code)
+	for _, recoveryCode := range recoveryCodes {
+		if util.CheckPassword(code, recoveryCode.HashedCode) != nil {
+			continue
+		}
+		_, err := bank.store.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err == sql.ErrNoRows {
+			// Used by a concurrent login
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, nil
+}
+
+// newRecoveryCode returns 80 random bits as 4 groups of 4 characters, such as "k3nq-7xab-p2mf-za4d"
+func newRecoveryCode() (string, error) {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(random))
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+// normalizeRecoveryCode drops what users may or may not type of the format of recovery codes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/totp"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollTOTP(t *testing.T) {
+	username := util.RandomName()
+	enabledUsername := util.RandomName()
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().
+		UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpsertTOTPSecretParams) (db.TotpSecret, error) {
+			require.Equal(t, username, arg.Username)
+			return db.TotpSecret{Username: arg.Username, Secret: arg.Secret}, nil
+		})
+	// The query turns down users with two-factor authentication enabled
+	store.EXPECT().
+		UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpsertTOTPSecretParams) (db.TotpSecret, error) {
+			require.Equal(t, enabledUsername, arg.Username)
+			return db.TotpSecret{}, sql.ErrNoRows
+		})
+	bank := newTestBank(t, store, nil)
+
+	enrollment, err := bank.EnrollTOTP(context.Background(), username)
+	require.NoError(t, err)
+	link, err := url.Parse(enrollment.URL)
+	require.NoError(t, err)
+	require.Equal(t, enrollment.Secret, link.Query().Get("secret"))
+
+	// Enrolling again would lock out the user's app
+	_, err = bank.EnrollTOTP(context.Background(), enabledUsername)
+	require.Equal(t, FailedPrecondition, KindOf(err))
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	username := util.RandomName()
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().
+		GetTOTPSecret(gomock.Any(), gomock.Eq(username)).
+		AnyTimes().
+		Return(db.TotpSecret{Username: username, Secret: secret}, nil)
+	var hashedCodes []string
+	store.EXPECT().
+		ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ConfirmTOTPTxParams) (db.ConfirmTOTPTxResult, error) {
+			require.Equal(t, username, arg.Username)
+			require.Equal(t, step, arg.Step)
+			hashedCodes = arg.HashedRecoveryCodes
+			return db.ConfirmTOTPTxResult{User: db.User{Username: username, IsMfaEnabled: true}}, nil
+		})
+	bank := newTestBank(t, store, nil)
+
+	// A code of another time is turned down
+	stale, err := totp.Code(secret, totp.Step(time.Now())-10)
+	require.NoError(t, err)
+	_, err = bank.ConfirmTOTP(context.Background(), username, stale)
+	require.Equal(t, InvalidArgument, KindOf(err))
+
+	recoveryCodes, err := bank.ConfirmTOTP(context.Background(), username, code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodeCount)
+	require.Len(t, hashedCodes, recoveryCodeCount)
+	// Only hashes are kept, of the codes however they are typed
+	require.NotEqual(t, recoveryCodes[0], hashedCodes[0])
+	require.NoError(t, util.CheckPassword(normalizeRecoveryCode(recoveryCodes[0]), hashedCodes[0]))
+	require.Equal(t, normalizeRecoveryCode(recoveryCodes[0]), normalizeRecoveryCode(" "+recoveryCodes[0]+" "))
+}
+
+func TestLoginUserMFA(t *testing.T) {
+	password := util.RandomString(8)
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user := db.User{Username: util.RandomName(), HashedPassword: hashedPassword, IsMfaEnabled: true}
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	recoveryCode := "abcd-efgh-ijkl-mnop"
+	hashedRecoveryCode, err := util.HashedPassword(normalizeRecoveryCode(recoveryCode))
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+	store.EXPECT().GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(db.TotpSecret{Secret: secret}, nil)
+	arg := db.UseTOTPStepParams{Username: user.Username, Step: step}
+	gomock.InOrder(
+		store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TotpSecret{LastUsedStep: step}, nil),
+		store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TotpSecret{}, sql.ErrNoRows),
+	)
+	store.EXPECT().
+		ListUnusedRecoveryCodes(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.RecoveryCode{{ID: 3, HashedCode: hashedRecoveryCode}}, nil)
+	store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(int64(3))).Times(1).Return(db.RecoveryCode{ID: 3, IsUsed: true}, nil)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(2).Return(db.Session{}, nil)
+	bank := newTestBank(t, store, nil)
+
+	// The password alone only gets an MFA token, which isn't an access token
+	result, err := bank.LoginUser(context.Background(), LoginUserParams{Username: user.Username, Password: password})
+	require.NoError(t, err)
+	require.Empty(t, result.AccessToken)
+	require.NotEmpty(t, result.MFAToken)
+	_, err = bank.tokenMaker.VerifyToken(result.MFAToken)
+	require.Error(t, err)
+
+	loginMFA := func(mfaToken, code string) (LoginUserResult, error) {
+		return bank.LoginUserMFA(context.Background(), LoginUserMFAParams{MFAToken: mfaToken, Code: code})
+	}
+
+	_, err = loginMFA(util.RandomString(32), code)
+	require.Equal(t, Unauthenticated, KindOf(err))
+
+	loggedIn, err := loginMFA(result.MFAToken, code)
+	require.NoError(t, err)
+	require.NotEmpty(t, loggedIn.AccessToken)
+	require.NotEmpty(t, loggedIn.RefreshToken)
+
+	// Codes can't be replayed
+	_, err = loginMFA(result.MFAToken, code)
+	require.Equal(t, Unauthenticated, KindOf(err))
+
+	// Recovery codes stand in for the app, in any case
+	loggedIn, err = loginMFA(result.MFAToken, "ABCD-EFGH-IJKL-MNOP")
+	require.NoError(t, err)
+	require.NotEmpty(t, loggedIn.AccessToken)
+}
@@ -0,0 +1,22 @@
+package service
+
+// Page selects up to Size rows in id order: the first rows after AfterID,
+// or the last rows before BeforeID when it is set
+type Page struct {
+	AfterID  int64
+	BeforeID int64
+	Size     int32
+}
+
+// limit is the number of rows to query: one more than the page size, to find out if there is another page
+func (page Page) limit() int32 {
+	return page.Size + 1
+}
+
+// trim returns the number of queried rows to keep in the page, and whether more rows are left
+func (page Page) trim(rowCount int) (int, bool) {
+	if rowCount > int(page.Size) {
+		return int(page.Size), true
+	}
+	return rowCount, false
+}
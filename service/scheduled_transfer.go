@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+)
+
+// CreateScheduledTransferParams is the input of CreateScheduledTransfer
+type CreateScheduledTransferParams struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64  // Debited on each run, in Currency
+	Currency      string // Must match the currency of the from account
+	Memo          string
+	// Schedule is a cron expression, a descriptor such as @monthly or @every <duration>, see util.ParseSchedule.
+	// Empty for a single transfer at StartAt.
+	Schedule string
+	// StartAt is when a single transfer runs; recurring transfers first run at the first scheduled time after it.
+	// Now if zero or past.
+	StartAt time.Time
+}
+
+// CreateScheduledTransfer schedules transfers out of an account of the actor
+func (bank *Bank) CreateScheduledTransfer(ctx context.Context, actor Actor, arg CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	var scheduledTransfer db.ScheduledTransfer
+	fromAccount, err := bank.getAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	if fromAccount.Currency != arg.Currency {
+		return scheduledTransfer, newError(CurrencyMismatch, "account [%d] currency mismatch: %s vs %s",
+			fromAccount.ID, fromAccount.Currency, arg.Currency)
+	}
+	if !actor.canDebit(fromAccount.Owner) {
+		return scheduledTransfer, newError(Forbidden, "from account doesn't belong to authenticated user")
+	}
+	toAccount, err := bank.getAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	for _, account := range []db.Account{fromAccount, toAccount} {
+		if err := checkOpen(account); err != nil {
+			return scheduledTransfer, err
+		}
+	}
+	nextRunAt, err := firstScheduledRun(arg.Schedule, arg.StartAt)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	scheduledTransfer, err = bank.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         actor.Username,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Currency:      arg.Currency,
+		Memo:          arg.Memo,
+		Schedule:      arg.Schedule,
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		return scheduledTransfer, internalError(err)
+	}
+	return scheduledTransfer, nil
+}
+
+// GetScheduledTransfer returns the scheduled transfer, if the actor can read it
+func (bank *Bank) GetScheduledTransfer(ctx context.Context, actor Actor, id int64) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := bank.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return scheduledTransfer, newError(NotFound, "scheduled transfer [%d] not found", id)
+		}
+		return scheduledTransfer, internalError(err)
+	}
+	if !actor.canRead(scheduledTransfer.Owner) {
+		return scheduledTransfer, newError(Forbidden, "scheduled transfer doesn't belong to authenticated user")
+	}
+	return scheduledTransfer, nil
+}
+
+// ListScheduledTransfers returns a page of the scheduled transfers of the actor, sorted by id,
+// and whether there are more in the page direction
+func (bank *Bank) ListScheduledTransfers(ctx context.Context, actor Actor, page Page) ([]db.ScheduledTransfer, bool, error) {
+	var scheduledTransfers []db.ScheduledTransfer
+	var err error
+	if page.BeforeID > 0 {
+		scheduledTransfers, err = bank.store.ListScheduledTransfersBefore(ctx, db.ListScheduledTransfersBeforeParams{
+			Owner:    actor.Username,
+			BeforeID: page.BeforeID,
+			Limit:    page.limit(),
+		})
+	} else {
+		scheduledTransfers, err = bank.store.ListScheduledTransfersAfter(ctx, db.ListScheduledTransfersAfterParams{
+			Owner:   actor.Username,
+			AfterID: page.AfterID,
+			Limit:   page.limit(),
+		})
+	}
+	if err != nil {
+		return nil, false, internalError(err)
+	}
+	n, hasMore := page.trim(len(scheduledTransfers))
+	scheduledTransfers = scheduledTransfers[:n]
+	sort.Slice(scheduledTransfers, func(i, j int) bool { return scheduledTransfers[i].ID < scheduledTransfers[j].ID })
+	return scheduledTransfers, hasMore, nil
+}
+
+// UpdateScheduledTransferParams is the input of UpdateScheduledTransfer, nil fields are left unchanged
+type UpdateScheduledTransferParams struct {
+	ID       int64
+	Amount   *int64
+	Memo     *string
+	Schedule *string                     // See CreateScheduledTransferParams, the next run is computed again from now
+	Status   *db.ScheduledTransferStatus // Active or paused
+}
+
+// UpdateScheduledTransfer changes an active or paused scheduled transfer of the actor
+func (bank *Bank) UpdateScheduledTransfer(ctx context.Context, actor Actor, arg UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := bank.modifiableScheduledTransfer(ctx, actor, arg.ID)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	update := db.UpdateScheduledTransferParams{
+		ID:            scheduledTransfer.ID,
+		Amount:        scheduledTransfer.Amount,
+		Memo:          scheduledTransfer.Memo,
+		Schedule:      scheduledTransfer.Schedule,
+		NextRunAt:     scheduledTransfer.NextRunAt,
+		Status:        scheduledTransfer.Status,
+		ReadNextRunAt: scheduledTransfer.NextRunAt,
+		ReadStatus:    scheduledTransfer.Status,
+	}
+	if arg.Amount != nil {
+		update.Amount = *arg.Amount
+	}
+	if arg.Memo != nil {
+		update.Memo = *arg.Memo
+	}
+	if arg.Status != nil {
+		if *arg.Status != db.ScheduledTransferStatusActive && *arg.Status != db.ScheduledTransferStatusPaused {
+			return scheduledTransfer, newError(InvalidArgument, "status must be active or paused")
+		}
+		update.Status = *arg.Status
+	}
+	switch {
+	case arg.Schedule != nil && len(*arg.Schedule) > 0:
+		update.Schedule = *arg.Schedule
+		if update.NextRunAt, err = firstScheduledRun(update.Schedule, time.Time{}); err != nil {
+			return scheduledTransfer, err
+		}
+	case arg.Schedule != nil:
+		// A single transfer at the pending run
+		update.Schedule = ""
+	case scheduledTransfer.Status == db.ScheduledTransferStatusPaused && update.Status == db.ScheduledTransferStatusActive &&
+		len(update.Schedule) > 0 && update.NextRunAt.Before(time.Now()):
+		// Resuming skips the runs missed while paused
+		if update.NextRunAt, err = firstScheduledRun(update.Schedule, time.Time{}); err != nil {
+			return scheduledTransfer, err
+		}
+	}
+	return bank.writeScheduledTransfer(ctx, update)
+}
+
+// CancelScheduledTransfer stops a scheduled transfer of the actor for good, keeping its runs
+func (bank *Bank) CancelScheduledTransfer(ctx context.Context, actor Actor, id int64) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := bank.modifiableScheduledTransfer(ctx, actor, id)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	return bank.writeScheduledTransfer(ctx, db.UpdateScheduledTransferParams{
+		ID:            scheduledTransfer.ID,
+		Amount:        scheduledTransfer.Amount,
+		Memo:          scheduledTransfer.Memo,
+		Schedule:      scheduledTransfer.Schedule,
+		NextRunAt:     scheduledTransfer.NextRunAt,
+		Status:        db.ScheduledTransferStatusCancelled,
+		ReadNextRunAt: scheduledTransfer.NextRunAt,
+		ReadStatus:    scheduledTransfer.Status,
+	})
+}
+
+// writeScheduledTransfer writes back a scheduled transfer read without a lock. The update is refused if a run
+// was claimed or the status changed in between, rather than undoing it with what was read.
+func (bank *Bank) writeScheduledTransfer(ctx context.Context, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := bank.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return scheduledTransfer, newError(Conflict, "scheduled transfer [%d] changed while being updated, try again", arg.ID)
+		}
+		return scheduledTransfer, internalError(err)
+	}
+	return scheduledTransfer, nil
+}
+
+// modifiableScheduledTransfer returns a scheduled transfer the actor can change: their own, still active or paused
+func (bank *Bank) modifiableScheduledTransfer(ctx context.Context, actor Actor, id int64) (db.ScheduledTransfer, error) {
+	scheduledTransfer, err := bank.GetScheduledTransfer(ctx, actor, id)
+	if err != nil {
+		return scheduledTransfer, err
+	}
+	if !actor.canDebit(scheduledTransfer.Owner) {
+		return scheduledTransfer, newError(Forbidden, "scheduled transfer doesn't belong to authenticated user")
+	}
+	if scheduledTransfer.Status != db.ScheduledTransferStatusActive && scheduledTransfer.Status != db.ScheduledTransferStatusPaused {
+		return scheduledTransfer, newError(FailedPrecondition, "scheduled transfer [%d] is %s", id, scheduledTransfer.Status)
+	}
+	return scheduledTransfer, nil
+}
+
+// ListScheduledTransferRuns returns the latest runs of a scheduled transfer the actor can read, newest first
+func (bank *Bank) ListScheduledTransferRuns(ctx context.Context, actor Actor, id int64, limit int32) ([]db.ScheduledTransferRun, error) {
+	if _, err := bank.GetScheduledTransfer(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	runs, err := bank.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: id,
+		Limit:               limit,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return runs, nil
+}
+
+// RunScheduledTransfers makes up to limit scheduled transfers due at now, on behalf of their owners, and records
+// the outcome of each. It returns the number of transfers run, failed ones included. Safe to call from several
+// processes at once, each due run is claimed by one of them.
+func (bank *Bank) RunScheduledTransfers(ctx context.Context, now time.Time, limit int32) (int, error) {
+	claimed, err := bank.store.ClaimScheduledTransfersTx(ctx, db.ClaimScheduledTransfersTxParams{
+		Now:   now,
+		Limit: limit,
+	})
+	if err != nil {
+		return 0, internalError(err)
+	}
+	for _, claim := range claimed {
+		scheduledTransfer := claim.ScheduledTransfer
+		update := db.UpdateScheduledTransferRunParams{
+			ID:     claim.Run.ID,
+			Status: db.ScheduledTransferRunStatusSucceeded,
+		}
+		result, err := bank.CreateTransfer(ctx, Actor{Username: scheduledTransfer.Owner, Role: util.CustomerRole}, CreateTransferParams{
+			FromAccountID: scheduledTransfer.FromAccountID,
+			ToAccountID:   scheduledTransfer.ToAccountID,
+			Amount:        scheduledTransfer.Amount,
+			Currency:      scheduledTransfer.Currency,
+			Memo:          scheduledTransfer.Memo,
+		})
+		if err != nil {
+			util.LoggerFromContext(ctx).Warn().Err(err).
+				Int64("scheduled_transfer_id", scheduledTransfer.ID).
+				Msg("scheduled transfer failed")
+			update.Status = db.ScheduledTransferRunStatusFailed
+			update.Error = runError(err)
+		} else {
+			update.TransferID = util.NewNullInt64(result.Transfer.ID)
+		}
+		if _, err := bank.store.UpdateScheduledTransferRun(ctx, update); err != nil {
+			// The run stays pending, the transfers of the other claimed runs are still made
+			util.LoggerFromContext(ctx).Error().Err(err).
+				Int64("scheduled_transfer_id", scheduledTransfer.ID).
+				Int64("run_id", claim.Run.ID).
+				Msg("failed to record scheduled transfer run")
+		}
+	}
+	return len(claimed), nil
+}
+
+// runError describes why a run failed to the owner of the scheduled transfer, leaving internal causes out
+func runError(err error) string {
+	if serviceErr, ok := err.(*Error); ok && serviceErr.Kind != Internal {
+		if len(serviceErr.Message) > 0 {
+			return serviceErr.Message
+		}
+		return serviceErr.Error()
+	}
+	return "internal error"
+}
+
+// firstScheduledRun returns the next run of a new schedule: at start for a single transfer, else the first
+// scheduled time after it. start is now if zero or past.
+func firstScheduledRun(spec string, start time.Time) (time.Time, error) {
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	if len(spec) == 0 {
+		return start, nil
+	}
+	schedule, err := util.ParseSchedule(spec)
+	if err != nil {
+		return time.Time{}, newError(InvalidArgument, "invalid schedule: %v", err)
+	}
+	return schedule.Next(start), nil
+}
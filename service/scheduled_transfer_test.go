@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScheduledTransfers(t *testing.T) {
+	owner := util.RandomName()
+	account1 := randomAccount(owner, util.USD)
+	account2 := randomAccount(util.RandomName(), util.USD)
+	account2.ID = account1.ID + 1
+	frozen := randomAccount(util.RandomName(), util.USD)
+	frozen.ID = account1.ID + 2
+	frozen.IsFrozen = true
+	now := time.Now()
+
+	claimed := []db.ClaimedScheduledTransfer{
+		{
+			ScheduledTransfer: db.ScheduledTransfer{ID: 1, Owner: owner, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD, Memo: "rent"},
+			Run:               db.ScheduledTransferRun{ID: 11, ScheduledTransferID: 1},
+		},
+		{
+			ScheduledTransfer: db.ScheduledTransfer{ID: 2, Owner: owner, FromAccountID: account1.ID, ToAccountID: frozen.ID, Amount: 10, Currency: util.USD},
+			Run:               db.ScheduledTransferRun{ID: 12, ScheduledTransferID: 2},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimScheduledTransfersTx(gomock.Any(), gomock.Eq(db.ClaimScheduledTransfersTxParams{Now: now, Limit: 10})).
+		Times(1).
+		Return(claimed, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(2).Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(frozen.ID)).Times(1).Return(frozen, nil)
+	// The frozen account fails the second transfer before its owner is checked
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(db.User{Username: owner, IsEmailVerified: true}, nil)
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+			Memo:          "rent",
+		})).
+		Times(1).
+		Return(db.TransferTxResult{Transfer: db.Transfer{ID: 99}}, nil)
+	store.EXPECT().
+		UpdateScheduledTransferRun(gomock.Any(), gomock.Eq(db.UpdateScheduledTransferRunParams{
+			ID:         11,
+			Status:     db.ScheduledTransferRunStatusSucceeded,
+			TransferID: util.NewNullInt64(99),
+		})).
+		Times(1).
+		// Failing to record a run doesn't drop the other claimed runs
+		Return(db.ScheduledTransferRun{}, sql.ErrConnDone)
+	store.EXPECT().
+		UpdateScheduledTransferRun(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+			require.Equal(t, int64(12), arg.ID)
+			require.Equal(t, db.ScheduledTransferRunStatusFailed, arg.Status)
+			require.False(t, arg.TransferID.Valid)
+			require.Contains(t, arg.Error, "frozen")
+			return db.ScheduledTransferRun{}, nil
+		})
+
+	bank := newTestBank(t, store, nil)
+	n, err := bank.RunScheduledTransfers(context.Background(), now, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestRunError(t *testing.T) {
+	require.Equal(t, "not enough money", runError(newError(InsufficientFunds, "not enough money")))
+	require.Equal(t, "internal error", runError(internalError(context.DeadlineExceeded)))
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// StatementParams is the input of Statement
+type StatementParams struct {
+	AccountID int64
+	From      time.Time // Inclusive
+	To        time.Time // Exclusive
+}
+
+// Statement streams the opening balance, the entries created in [From, To) and the closing balance of an account
+// the actor can read to handler
+func (bank *Bank) Statement(ctx context.Context, actor Actor, arg StatementParams, handler db.StatementHandler) error {
+	if !arg.From.Before(arg.To) {
+		return newError(InvalidArgument, "statement period must end after it starts")
+	}
+	if _, err := bank.GetAccount(ctx, actor, arg.AccountID); err != nil {
+		return err
+	}
+	if err := bank.store.StatementTx(ctx, db.StatementTxParams{
+		AccountID: arg.AccountID,
+		From:      arg.From,
+		To:        arg.To,
+	}, handler); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
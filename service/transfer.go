@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+)
+
+// CreateTransferParams is the input of CreateTransfer
+type CreateTransferParams struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64  // Debited, in Currency
+	Currency      string // Must match the currency of the from account
+	Memo          string // Recorded on the entries of the transfer
+}
+
+// CreateTransfer moves money out of an account the actor can debit. Between accounts of different currencies,
+// the amount credited is converted at the current rate of the fx provider. The owner of the from account must
+// have verified its email address.
+func (bank *Bank) CreateTransfer(ctx context.Context, actor Actor, arg CreateTransferParams) (db.TransferTxResult, error) {
+	var result db.TransferTxResult
+	fromAccount, err := bank.getAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	// Checked first, so that the currency of others' accounts isn't told
+	if !actor.canDebit(fromAccount.Owner) {
+		return result, newError(Forbidden, "from account doesn't belong to authenticated user")
+	}
+	if fromAccount.Currency != arg.Currency {
+		return result, newError(CurrencyMismatch, "account [%d] currency mismatch: %s vs %s",
+			fromAccount.ID, fromAccount.Currency, arg.Currency)
+	}
+	toAccount, err := bank.getAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	for _, account := range []db.Account{fromAccount, toAccount} {
+		if err := checkOpen(account); err != nil {
+			return result, err
+		}
+	}
+	if err := bank.checkEmailVerified(ctx, fromAccount.Owner); err != nil {
+		return result, err
+	}
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = bank.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			Memo:          arg.Memo,
+		})
+	} else {
+		// Debit in the from currency, credit the converted amount in the to currency
+		rate, rateErr := bank.fxProvider.Rate(fromAccount.Currency, toAccount.Currency)
+		if rateErr != nil {
+			return result, &Error{Kind: FailedPrecondition, Err: rateErr}
+		}
+		toAmount, convErr := util.ConvertAmount(arg.Amount, fromAccount.Currency, toAccount.Currency, rate)
+		if convErr != nil {
+			return result, newError(InvalidArgument, "amount is too large to convert from %s to %s",
+				fromAccount.Currency, toAccount.Currency)
+		}
+		if toAmount <= 0 {
+			return result, newError(FailedPrecondition, "amount is too small to convert from %s to %s",
+				fromAccount.Currency, toAccount.Currency)
+		}
+		result, err = bank.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  util.FormatFXRate(rate),
+			Rounding:      util.RoundingHalfEven,
+			Memo:          arg.Memo,
+		})
+	}
+	if err != nil {
+		var fundsErr *db.ErrInsufficientFunds
+		if errors.As(err, &fundsErr) {
+			return result, &Error{Kind: InsufficientFunds, Err: fundsErr}
+		}
+		return result, internalError(err)
+	}
+	return result, nil
+}
+
+// GetTransfer returns the transfer, if the actor can read either side of it
+func (bank *Bank) GetTransfer(ctx context.Context, actor Actor, transferID int64) (db.Transfer, error) {
+	transfer, err := bank.store.GetTransfer(ctx, transferID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return transfer, newError(NotFound, "transfer [%d] not found", transferID)
+		}
+		return transfer, internalError(err)
+	}
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		account, err := bank.store.GetAccount(ctx, accountID)
+		if err != nil {
+			return transfer, internalError(err)
+		}
+		if actor.canRead(account.Owner) {
+			return transfer, nil
+		}
+	}
+	return transfer, newError(Forbidden, "transfer does not belong to authenticated user")
+}
+
+// ListTransfers returns a page of the transfers sent from or received by an account the actor can read, sorted by id,
+// and whether there are more in the page direction
+func (bank *Bank) ListTransfers(ctx context.Context, actor Actor, accountID int64, page Page) ([]db.Transfer, bool, error) {
+	if _, err := bank.GetAccount(ctx, actor, accountID); err != nil {
+		return nil, false, err
+	}
+	var transfers []db.Transfer
+	var err error
+	if page.BeforeID > 0 {
+		transfers, err = bank.store.ListTransfersBefore(ctx, db.ListTransfersBeforeParams{
+			AccountID: accountID,
+			BeforeID:  page.BeforeID,
+			Limit:     page.limit(),
+		})
+	} else {
+		transfers, err = bank.store.ListTransfersAfter(ctx, db.ListTransfersAfterParams{
+			AccountID: accountID,
+			AfterID:   page.AfterID,
+			Limit:     page.limit(),
+		})
+	}
+	if err != nil {
+		return nil, false, internalError(err)
+	}
+	n, hasMore := page.trim(len(transfers))
+	transfers = transfers[:n]
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].ID < transfers[j].ID })
+	return transfers, hasMore, nil
+}
+
+// ListTransfersByOffset returns the transfers of an account the actor can read skipping offset rows.
+//
+// Deprecated: use ListTransfers.
+func (bank *Bank) ListTransfersByOffset(ctx context.Context, actor Actor, accountID int64, limit, offset int32) ([]db.Transfer, error) {
+	if _, err := bank.GetAccount(ctx, actor, accountID); err != nil {
+		return nil, err
+	}
+	transfers, err := bank.store.ListTransfers(ctx, db.ListTransfersParams{
+		FromAccountID: accountID,
+		ToAccountID:   accountID,
+		Limit:         limit,
+		Offset:        offset,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return transfers, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	owner := util.RandomName()
+	account1 := randomAccount(owner, util.USD)
+	account2 := randomAccount(util.RandomName(), util.USD)
+	account2.ID = account1.ID + 1
+	account3 := randomAccount(util.RandomName(), util.EUR)
+	account3.ID = account1.ID + 2
+	user := db.User{Username: owner, IsEmailVerified: true}
+
+	testCases := []struct {
+		name       string
+		actor      Actor
+		arg        CreateTransferParams
+		rates      map[string]string
+		buildStubs func(store *mock.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name:  "OK",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD, Memo: "rent"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account2.ID,
+						Amount:        10,
+						Memo:          "rent",
+					})).
+					Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:  "Exchange",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: 1000, Currency: util.USD},
+			rates: map[string]string{"USD/EUR": "0.9"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ExchangeTransferTx(gomock.Any(), gomock.Eq(db.ExchangeTransferTxParams{
+						FromAccountID: account1.ID,
+						ToAccountID:   account3.ID,
+						Amount:        1000,
+						ToAmount:      900,
+						ExchangeRate:  "0.90000000",
+						Rounding:      util.RoundingHalfEven,
+					})).
+					Times(1)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:  "NotFound",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, NotFound, KindOf(err))
+			},
+		},
+		{
+			name:  "CurrencyMismatch",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.EUR},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, CurrencyMismatch, KindOf(err))
+			},
+		},
+		{
+			name:  "Forbidden",
+			actor: Actor{Username: account2.Owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, Forbidden, KindOf(err))
+			},
+		},
+		{
+			// The currency of others' accounts isn't told
+			name:  "ForbiddenCurrencyMismatch",
+			actor: Actor{Username: account2.Owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.EUR},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, Forbidden, KindOf(err))
+			},
+		},
+		{
+			name:  "NoExchangeRate",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().ExchangeTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, FailedPrecondition, KindOf(err))
+			},
+		},
+		{
+			name:  "ExchangeOverflow",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: math.MaxInt64, Currency: util.USD},
+			rates: map[string]string{"USD/EUR": "2"},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().ExchangeTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, InvalidArgument, KindOf(err))
+			},
+		},
+		{
+			name:  "AdminCannotDebit",
+			actor: Actor{Username: util.RandomName(), Role: util.AdminRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, Forbidden, KindOf(err))
+			},
+		},
+		{
+			name:  "FrozenAccount",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				frozenAccount := account2
+				frozenAccount.IsFrozen = true
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(frozenAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, FailedPrecondition, KindOf(err))
+			},
+		},
+		{
+			name:  "LedgerAccount",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				ledgerAccount := account2
+				ledgerAccount.Owner = db.SystemUsername
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(ledgerAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, FailedPrecondition, KindOf(err))
+			},
+		},
+		{
+			name:  "EmailNotVerified",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(db.User{Username: owner}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, FailedPrecondition, KindOf(err))
+			},
+		},
+		{
+			name:  "InsufficientFunds",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.ErrInsufficientFunds{AccountID: account1.ID, Available: 5, Amount: 10})
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, InsufficientFunds, KindOf(err))
+				var fundsErr *db.ErrInsufficientFunds
+				require.True(t, errors.As(err, &fundsErr))
+				require.Equal(t, int64(5), fundsErr.Available)
+			},
+		},
+		{
+			name:  "InternalError",
+			actor: Actor{Username: owner, Role: util.CustomerRole},
+			arg:   CreateTransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner)).Times(1).Return(user, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Equal(t, Internal, KindOf(err))
+				require.ErrorIs(t, err, sql.ErrTxDone)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			bank := newTestBank(t, store, testCase.rates)
+			_, err := bank.CreateTransfer(context.Background(), testCase.actor, testCase.arg)
+			testCase.checkError(t, err)
+		})
+	}
+}
+
+func TestGetTransfer(t *testing.T) {
+	account1 := randomAccount(util.RandomName(), util.USD)
+	account2 := randomAccount(util.RandomName(), util.USD)
+	transfer := db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).AnyTimes().Return(transfer, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).AnyTimes().Return(account2, nil)
+	bank := newTestBank(t, store, nil)
+
+	// The owner of either side, or an admin, may see the transfer
+	for _, actor := range []Actor{
+		{Username: account1.Owner, Role: util.CustomerRole},
+		{Username: account2.Owner, Role: util.CustomerRole},
+		{Username: util.RandomName(), Role: util.AdminRole},
+	} {
+		gotTransfer, err := bank.GetTransfer(context.Background(), actor, transfer.ID)
+		require.NoError(t, err)
+		require.Equal(t, transfer, gotTransfer)
+	}
+	for _, role := range []string{util.CustomerRole, util.TellerRole} {
+		_, err := bank.GetTransfer(context.Background(), Actor{Username: util.RandomName(), Role: role}, transfer.ID)
+		require.Equal(t, Forbidden, KindOf(err))
+	}
+}
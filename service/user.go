@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/mail"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+)
+
+// CreateUserParams is the input of CreateUser
+type CreateUserParams struct {
+	Username string
+	Password string
+	FullName string
+	Email    string
+}
+
+// CreateUser registers a user with a hash of the password, and emails it a link verifying its address once it is
+// created. The email is sent in the background: if it is lost, the user can ask for another with ResendVerifyEmail.
+func (bank *Bank) CreateUser(ctx context.Context, arg CreateUserParams) (db.User, error) {
+	hashedPassword, err := util.HashedPassword(arg.Password)
+	if err != nil {
+		return db.User{}, internalError(err)
+	}
+	secretCode, err := newSecret()
+	if err != nil {
+		return db.User{}, internalError(err)
+	}
+	result, err := bank.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       arg.Username,
+			HashedPassword: hashedPassword,
+			FullName:       arg.FullName,
+			Email:          arg.Email,
+		},
+		SecretCode: secretCode,
+		ExpiredAt:  time.Now().Add(bank.config.VerifyEmailDuration),
+	})
+	if err != nil {
+		// The error of the transaction wraps the one of the query
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return db.User{}, newError(Conflict, "user [%s] already exists", arg.Username)
+		}
+		return db.User{}, internalError(err)
+	}
+	bank.sendVerifyEmailInBackground(ctx, result.User, result.VerifyEmail)
+	return result.User, nil
+}
+
+// ResendVerifyEmail emails the user a new link verifying its address, for when the previous one was lost or expired
+func (bank *Bank) ResendVerifyEmail(ctx context.Context, username string) error {
+	user, err := bank.store.GetUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return newError(NotFound, "user [%s] not found", username)
+		}
+		return internalError(err)
+	}
+	if user.IsEmailVerified {
+		return newError(FailedPrecondition, "email address is already verified")
+	}
+	secretCode, err := newSecret()
+	if err != nil {
+		return internalError(err)
+	}
+	verifyEmail, err := bank.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+		ExpiredAt:  time.Now().Add(bank.config.VerifyEmailDuration),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	msg, err := bank.verifyEmailMessage(user, verifyEmail)
+	if err != nil {
+		return internalError(err)
+	}
+	if err := bank.mailer.Send(ctx, msg); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// sendVerifyEmailInBackground emails the user the link to verify its address with the code. It is called once the
+// code is committed, so that the link always works, and a failure is only logged as the user can ask for another.
+func (bank *Bank) sendVerifyEmailInBackground(ctx context.Context, user db.User, verifyEmail db.VerifyEmail) {
+	msg, err := bank.verifyEmailMessage(user, verifyEmail)
+	if err != nil {
+		util.LoggerFromContext(ctx).Error().Err(err).Str("username", user.Username).Msg("failed to send email")
+		return
+	}
+	bank.sendInBackground(ctx, msg)
+}
+
+// verifyEmailMessage is the email with the link verifying the address of the user with the code
+func (bank *Bank) verifyEmailMessage(user db.User, verifyEmail db.VerifyEmail) (mail.Message, error) {
+	link, err := withQuery(bank.config.EmailVerificationURL, url.Values{
+		"id":   {fmt.Sprint(verifyEmail.ID)},
+		"code": {verifyEmail.SecretCode},
+	})
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("invalid email verification url: %w", err)
+	}
+	return mail.Message{
+		To:      []string{verifyEmail.Email},
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Thank you for registering with us. Please verify your email address by opening this link:\n\n"+
+			"%s\n\n"+
+			"The link expires on %s.\n",
+			user.FullName, link, verifyEmail.ExpiredAt.UTC().Format(time.RFC1123)),
+	}, nil
+}
+
+// withQuery adds params to the query of the link
+func withQuery(link string, params url.Values) (string, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// VerifyEmail uses the code emailed to a new user, marking its address as verified
+func (bank *Bank) VerifyEmail(ctx context.Context, emailID int64, secretCode string) (db.User, error) {
+	result, err := bank.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    emailID,
+		SecretCode: secretCode,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result.User, newError(InvalidArgument, "verification code is invalid, used or expired")
+		}
+		return result.User, internalError(err)
+	}
+	return result.User, nil
+}
+
+// checkEmailVerified fails unless the user verified its email address, which it must before moving money
+func (bank *Bank) checkEmailVerified(ctx context.Context, username string) error {
+	user, err := bank.store.GetUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return newError(NotFound, "user [%s] not found", username)
+		}
+		return internalError(err)
+	}
+	if !user.IsEmailVerified {
+		return newError(FailedPrecondition, "user [%s] hasn't verified their email address", username)
+	}
+	return nil
+}
+
+// UpdateUserParams is the input of UpdateUser. Nil fields are left unchanged.
+type UpdateUserParams struct {
+	Username string
+	FullName *string
+	Email    *string
+}
+
+// UpdateUser changes the profile of a user. A new email address has to be verified again, so it is emailed a link
+// verifying it once the update is committed.
+func (bank *Bank) UpdateUser(ctx context.Context, arg UpdateUserParams) (db.User, error) {
+	if arg.FullName == nil && arg.Email == nil {
+		return db.User{}, newError(InvalidArgument, "nothing to update")
+	}
+	txArg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{Username: arg.Username},
+		ExpiredAt:        time.Now().Add(bank.config.VerifyEmailDuration),
+	}
+	if arg.FullName != nil {
+		txArg.FullName = sql.NullString{String: *arg.FullName, Valid: true}
+	}
+	if arg.Email != nil {
+		txArg.Email = sql.NullString{String: *arg.Email, Valid: true}
+	}
+	var err error
+	if txArg.SecretCode, err = newSecret(); err != nil {
+		return db.User{}, internalError(err)
+	}
+	result, err := bank.store.UpdateUserTx(ctx, txArg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.User{}, newError(NotFound, "user [%s] not found", arg.Username)
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return db.User{}, newError(Conflict, "email address is already in use")
+		}
+		return db.User{}, internalError(err)
+	}
+	if result.VerifyEmail != nil {
+		bank.sendVerifyEmailInBackground(ctx, result.User, *result.VerifyEmail)
+	}
+	return result.User, nil
+}
+
+// ChangePasswordParams is the input of ChangePassword
+type ChangePasswordParams struct {
+	Username    string
+	OldPassword string
+	NewPassword string
+}
+
+// ChangePassword replaces the password of a user once the old one is checked, then revokes the tokens and sessions
+// of the user, who has to log in again everywhere
+func (bank *Bank) ChangePassword(ctx context.Context, arg ChangePasswordParams) (db.User, error) {
+	user, err := bank.store.GetUser(ctx, arg.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return user, newError(NotFound, "user [%s] not found", arg.Username)
+		}
+		return user, internalError(err)
+	}
+	if err := util.CheckPassword(arg.OldPassword, user.HashedPassword); err != nil {
+		return user, &Error{Kind: Unauthenticated, Message: "incorrect password", Err: err}
+	}
+	if arg.NewPassword == arg.OldPassword {
+		return user, newError(InvalidArgument, "new password must differ from the old one")
+	}
+	hashedPassword, err := util.HashedPassword(arg.NewPassword)
+	if err != nil {
+		return user, internalError(err)
+	}
+	user, err = bank.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:          arg.Username,
+		HashedPassword:    sql.NullString{String: hashedPassword, Valid: true},
+		PasswordChangedAt: time.Now(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return user, newError(NotFound, "user [%s] not found", arg.Username)
+		}
+		return user, internalError(err)
+	}
+	if err := bank.revokeUser(ctx, user.Username); err != nil {
+		return user, internalError(err)
+	}
+	return user, nil
+}
+
+// ForgotPassword emails a link resetting the password to the user with the email address, if there is one. Whether
+// there is isn't told, so that it can't be used to find out who has an account: the email is sent in the
+// background, for the response not to take longer when it is.
+func (bank *Bank) ForgotPassword(ctx context.Context, email string) error {
+	user, err := bank.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return internalError(err)
+	}
+	resetToken, payload, err := bank.tokenMaker.CreatePurposeToken(
+		user.Username,
+		token.PurposePasswordReset,
+		bank.config.PasswordResetDuration,
+	)
+	if err != nil {
+		return internalError(err)
+	}
+	link, err := withQuery(bank.config.PasswordResetURL, url.Values{"token": {resetToken}})
+	if err != nil {
+		return internalError(fmt.Errorf("invalid password reset url: %w", err))
+	}
+	bank.sendInBackground(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your account [%s]. If it was you, open this link to choose a new one:\n\n"+
+			"%s\n\n"+
+			"The link can be used once and expires on %s. If it wasn't you, you can ignore this email.\n",
+			user.FullName, user.Username, link, payload.ExpiredAt.UTC().Format(time.RFC1123)),
+	})
+	return nil
+}
+
+// ResetPasswordParams is the input of ResetPassword
+type ResetPasswordParams struct {
+	Token       string // Emailed by ForgotPassword
+	NewPassword string
+}
+
+// ResetPassword replaces the password of the user the reset token was emailed to. The token can be used once, and as
+// with ChangePassword, the tokens and sessions of the user are revoked.
+func (bank *Bank) ResetPassword(ctx context.Context, arg ResetPasswordParams) (db.User, error) {
+	payload, err := bank.tokenMaker.VerifyPurposeToken(arg.Token, token.PurposePasswordReset)
+	if err != nil {
+		return db.User{}, &Error{Kind: InvalidArgument, Message: "reset token is invalid or expired", Err: err}
+	}
+	hashedPassword, err := util.HashedPassword(arg.NewPassword)
+	if err != nil {
+		return db.User{}, internalError(err)
+	}
+	user, err := bank.store.ResetUserPassword(ctx, db.ResetUserPasswordParams{
+		Username:          payload.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now(),
+		RequestedAt:       payload.IssuedAt,
+	})
+	if err != nil {
+		// The password changed since the token was issued, possibly with this very token
+		if err == sql.ErrNoRows {
+			return user, newError(InvalidArgument, "reset token has already been used")
+		}
+		return user, internalError(err)
+	}
+	if err := bank.revokeUser(ctx, user.Username); err != nil {
+		return user, internalError(err)
+	}
+	return user, nil
+}
+
+// LoginUserParams is the input of LoginUser. UserAgent and ClientIP describe the client on the session.
+type LoginUserParams struct {
+	Username  string
+	Password  string
+	UserAgent string
+	ClientIP  string
+}
+
+// LoginUserResult holds the tokens issued by LoginUser. For users with two-factor authentication, only MFAToken
+// is issued and the other tokens come from LoginUserMFA.
+type LoginUserResult struct {
+	User           db.User
+	Session        db.Session
+	AccessToken    string
+	AccessPayload  *token.Payload
+	RefreshToken   string
+	RefreshPayload *token.Payload
+	MFAToken       string
+	MFAPayload     *token.Payload
+}
+
+// LoginUser checks the user's password, then issues an access token and a refresh token backed by a new session,
+// or an MFA token if the user has two-factor authentication. Unknown users and wrong passwords get the same
+// error, and repeated failures are throttled with TooManyRequests.
+func (bank *Bank) LoginUser(ctx context.Context, arg LoginUserParams) (LoginUserResult, error) {
+	var result LoginUserResult
+	reservation, err := bank.reserveLogin(ctx, arg.Username, arg.ClientIP)
+	if err != nil {
+		return result, err
+	}
+	user, err := bank.store.GetUser(ctx, arg.Username)
+	if err != nil && err != sql.ErrNoRows {
+		return result, internalError(err)
+	}
+	hashedPassword := user.HashedPassword
+	if err == sql.ErrNoRows {
+		// Take as long to turn down an unknown user as a wrong password
+		hashedPassword = dummyPasswordHash()
+	}
+	if checkErr := util.CheckPassword(arg.Password, hashedPassword); err != nil || checkErr != nil {
+		// The reservation stands as the failure
+		return result, newError(Unauthenticated, "incorrect username or password")
+	}
+	if user.IsMfaEnabled {
+		// The failures aren't forgotten until the second factor is right too, or they'd allow guessing codes
+		if err := bank.loginLimiter.Release(ctx, reservation); err != nil {
+			return result, internalError(err)
+		}
+		result.User = user
+		result.MFAToken, result.MFAPayload, err = bank.tokenMaker.CreatePurposeToken(
+			user.Username,
+			token.PurposeMFAPending,
+			bank.config.MFATokenDuration,
+		)
+		if err != nil {
+			return result, internalError(err)
+		}
+		return result, nil
+	}
+	return bank.startSession(ctx, user, reservation, arg.UserAgent, arg.ClientIP)
+}
+
+// startSession forgets the failed logins of the user, releasing the reservation of the login, then issues an access
+// token and a refresh token backed by a new session
+func (bank *Bank) startSession(
+	ctx context.Context,
+	user db.User,
+	reservation *throttle.Reservation,
+	userAgent, clientIP string,
+) (LoginUserResult, error) {
+	result := LoginUserResult{User: user}
+	if err := bank.loginLimiter.Succeed(ctx, reservation); err != nil {
+		return result, internalError(err)
+	}
+	var err error
+	result.AccessToken, result.AccessPayload, err = bank.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		bank.config.ACCESS_TOKEN_DURATION,
+	)
+	if err != nil {
+		return result, internalError(err)
+	}
+	result.RefreshToken, result.RefreshPayload, err = bank.tokenMaker.CreateRefreshToken(
+		user.Username,
+		user.Role,
+		bank.config.RefreshTokenDuration,
+	)
+	if err != nil {
+		return result, internalError(err)
+	}
+	result.Session, err = bank.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:               result.RefreshPayload.ID,
+		Username:         user.Username,
+		RefreshTokenHash: hashToken(result.RefreshToken),
+		UserAgent:        userAgent,
+		ClientIp:         clientIP,
+		IsBlocked:        false,
+		ExpiresAt:        result.RefreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return result, internalError(err)
+	}
+	return result, nil
+}
+
+// reserveLogin returns TooManyRequests if the login of username from clientIP is throttled, else counts it as
+// failed until startSession or a release of the reservation says otherwise
+func (bank *Bank) reserveLogin(ctx context.Context, username, clientIP string) (*throttle.Reservation, error) {
+	var reservation *throttle.Reservation
+	err := bank.loginLimiter.Check(ctx, username, clientIP)
+	if err == nil {
+		reservation, err = bank.loginLimiter.Reserve(ctx, username, clientIP)
+	}
+	if err != nil {
+		var throttledErr *throttle.ErrThrottled
+		if errors.As(err, &throttledErr) {
+			return nil, &Error{Kind: TooManyRequests, Err: err}
+		}
+		return nil, internalError(err)
+	}
+	return reservation, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns the hash of a random password, which LoginUser checks when the user doesn't exist
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		// Hashing 32 bytes can't fail. If it did, the empty hash would only cost the timing.
+		dummyHash, _ = util.HashedPassword(util.RandomString(32))
+	})
+	return dummyHash
+}
+
+// ListUsers returns up to size users sorted by username, starting after afterUsername, and whether there are more.
+// Callers must restrict it to admins.
+func (bank *Bank) ListUsers(ctx context.Context, afterUsername string, size int32) ([]db.User, bool, error) {
+	page := Page{Size: size}
+	users, err := bank.store.ListUsers(ctx, db.ListUsersParams{
+		Limit:         page.limit(),
+		AfterUsername: afterUsername,
+	})
+	if err != nil {
+		return nil, false, internalError(err)
+	}
+	n, hasMore := page.trim(len(users))
+	return users[:n], hasMore, nil
+}
+
+// UpdateUserRole changes the role of a user, whose tokens and sessions are revoked so that the new role applies from
+// their next login. Callers must restrict it to admins.
+func (bank *Bank) UpdateUserRole(ctx context.Context, username, role string) (db.User, error) {
+	if !util.IsSupportedRole(role) {
+		return db.User{}, newError(InvalidArgument, "unsupported role %q", role)
+	}
+	user, err := bank.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: username,
+		Role:     role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return user, newError(NotFound, "user [%s] not found", username)
+		}
+		return user, internalError(err)
+	}
+	if err := bank.revokeUser(ctx, user.Username); err != nil {
+		return user, internalError(err)
+	}
+	return user, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/throttle"
+	"github.com/harrychopra/go-api/token"
+	"github.com/harrychopra/go-api/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	arg := CreateUserParams{
+		Username: util.RandomName(),
+		Password: util.RandomString(8),
+		FullName: util.RandomName(),
+		Email:    util.RandomEmail(),
+	}
+
+	testCases := []struct {
+		name       string
+		mailErr    error
+		buildStubs func(store *mock.MockStore)
+		check      func(t *testing.T, mailer *fakeMailer, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, txArg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						require.Equal(t, arg.Username, txArg.Username)
+						require.NoError(t, util.CheckPassword(arg.Password, txArg.HashedPassword))
+						require.Len(t, txArg.SecretCode, 64)
+						require.WithinDuration(t, time.Now().Add(time.Hour), txArg.ExpiredAt, time.Minute)
+
+						user := db.User{Username: txArg.Username, FullName: txArg.FullName, Email: txArg.Email}
+						verifyEmail := db.VerifyEmail{ID: 7, Username: user.Username, Email: user.Email,
+							SecretCode: txArg.SecretCode, ExpiredAt: txArg.ExpiredAt}
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil
+					})
+			},
+			check: func(t *testing.T, mailer *fakeMailer, err error) {
+				require.NoError(t, err)
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, []string{arg.Email}, mailer.sent[0].To)
+
+				// The email links to the verification with its id and code
+				var link string
+				for _, line := range strings.Split(mailer.sent[0].Body, "\n") {
+					if strings.HasPrefix(line, "http") {
+						link = line
+					}
+				}
+				parsed, err := url.Parse(link)
+				require.NoError(t, err)
+				require.Equal(t, "/users/verify_email", parsed.Path)
+				require.Equal(t, "7", parsed.Query().Get("id"))
+				require.Len(t, parsed.Query().Get("code"), 64)
+			},
+		},
+		{
+			name:    "MailFailure",
+			mailErr: errors.New("connection refused"),
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{User: db.User{Username: arg.Username}, VerifyEmail: db.VerifyEmail{ID: 1}}, nil)
+			},
+			check: func(t *testing.T, mailer *fakeMailer, err error) {
+				// The user is created all the same, and can ask for another email
+				require.NoError(t, err)
+				require.Empty(t, mailer.sent)
+			},
+		},
+		{
+			name: "Duplicate",
+			buildStubs: func(store *mock.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{}, fmt.Errorf("tx error: %w", &pq.Error{Code: "23505"}))
+			},
+			check: func(t *testing.T, mailer *fakeMailer, err error) {
+				require.Equal(t, Conflict, KindOf(err))
+				require.Empty(t, mailer.sent)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			testCase.buildStubs(store)
+
+			bank := newTestBank(t, store, nil)
+			mailer := &fakeMailer{err: testCase.mailErr}
+			bank.mailer = mailer
+			_, err := bank.CreateUser(context.Background(), arg)
+			bank.WaitForMail()
+			testCase.check(t, mailer, err)
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	password := util.RandomString(8)
+	hashedPassword, err := util.HashedPassword(password)
+	require.NoError(t, err)
+	user := db.User{Username: util.RandomName(), HashedPassword: hashedPassword}
+	unknown := util.RandomName()
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(6).Return(user, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(unknown)).Times(1).Return(db.User{}, sql.ErrNoRows)
+	store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(2).Return(db.Session{}, nil)
+	bank := newTestBank(t, store, nil)
+	login := func(username, password string) error {
+		_, err := bank.LoginUser(context.Background(), LoginUserParams{Username: username, Password: password, ClientIP: "10.0.0.1"})
+		return err
+	}
+
+	// Unknown users can't be told from wrong passwords
+	unknownErr := login(unknown, password)
+	wrongErr := login(user.Username, "incorrect")
+	require.Equal(t, Unauthenticated, KindOf(unknownErr))
+	require.Equal(t, unknownErr.Error(), wrongErr.Error())
+
+	// Logging in forgets the failures of the user
+	require.NoError(t, login(user.Username, password))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, Unauthenticated, KindOf(login(user.Username, "incorrect")))
+	}
+
+	// Past the free failures, even the right password has to wait
+	require.Equal(t, TooManyRequests, KindOf(login(user.Username, password)))
+	bank.loginLimiter = throttle.NewLimiter(throttle.NewMemoryStore(time.Minute), throttle.Options{
+		MaxFailures:      4,
+		MaxFailuresPerIP: 20,
+		LockoutDuration:  time.Minute,
+	})
+	require.NoError(t, login(user.Username, password))
+}
+
+func TestVerifyEmail(t *testing.T) {
+	user := db.User{Username: util.RandomName(), Email: util.RandomEmail(), IsEmailVerified: true}
+	arg := db.VerifyEmailTxParams{EmailID: util.RandomInt(1, 1000), SecretCode: util.RandomString(64)}
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.VerifyEmailTxResult{User: user}, nil),
+		store.EXPECT().
+			VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(db.VerifyEmailTxResult{}, fmt.Errorf("tx error: %w", sql.ErrNoRows)),
+	)
+	bank := newTestBank(t, store, nil)
+
+	gotUser, err := bank.VerifyEmail(context.Background(), arg.EmailID, arg.SecretCode)
+	require.NoError(t, err)
+	require.True(t, gotUser.IsEmailVerified)
+
+	// Codes are single-use
+	_, err = bank.VerifyEmail(context.Background(), arg.EmailID, arg.SecretCode)
+	require.Equal(t, InvalidArgument, KindOf(err))
+}
+
+func TestUpdateUser(t *testing.T) {
+	user := db.User{Username: util.RandomName(), FullName: util.RandomName(), Email: util.RandomEmail(), IsEmailVerified: true}
+	newEmail := util.RandomEmail()
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().
+		UpdateUserTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+			require.True(t, arg.Email.Valid)
+			require.False(t, arg.FullName.Valid)
+			require.Len(t, arg.SecretCode, 64)
+			updated := user
+			updated.Email = arg.Email.String
+			updated.IsEmailVerified = false
+			verifyEmail := &db.VerifyEmail{ID: 3, Username: user.Username, Email: arg.Email.String, SecretCode: arg.SecretCode}
+			return db.UpdateUserTxResult{User: updated, VerifyEmail: verifyEmail}, nil
+		})
+	bank := newTestBank(t, store, nil)
+	mailer := &fakeMailer{}
+	bank.mailer = mailer
+
+	_, err := bank.UpdateUser(context.Background(), UpdateUserParams{Username: user.Username})
+	require.Equal(t, InvalidArgument, KindOf(err))
+
+	// The new address is sent a link verifying it
+	updated, err := bank.UpdateUser(context.Background(), UpdateUserParams{Username: user.Username, Email: &newEmail})
+	require.NoError(t, err)
+	require.False(t, updated.IsEmailVerified)
+	bank.WaitForMail()
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, []string{newEmail}, mailer.sent[0].To)
+}
+
+func TestResendVerifyEmail(t *testing.T) {
+	user := db.User{Username: util.RandomName(), FullName: util.RandomName(), Email: util.RandomEmail()}
+	verified := db.User{Username: util.RandomName(), Email: util.RandomEmail(), IsEmailVerified: true}
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(2).Return(user, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(verified.Username)).Times(1).Return(verified, nil)
+	store.EXPECT().
+		CreateVerifyEmail(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, user.Email, arg.Email)
+			require.Len(t, arg.SecretCode, 64)
+			require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiredAt, time.Minute)
+			return db.VerifyEmail{ID: 9, Username: arg.Username, Email: arg.Email, SecretCode: arg.SecretCode,
+				ExpiredAt: arg.ExpiredAt}, nil
+		})
+	bank := newTestBank(t, store, nil)
+	mailer := &fakeMailer{}
+	bank.mailer = mailer
+
+	require.NoError(t, bank.ResendVerifyEmail(context.Background(), user.Username))
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, []string{user.Email}, mailer.sent[0].To)
+	require.Contains(t, mailer.sent[0].Body, "id=9")
+
+	// Unlike after signing up, the caller is told the email couldn't be sent
+	mailer.err = errors.New("smtp server unavailable")
+	require.Equal(t, Internal, KindOf(bank.ResendVerifyEmail(context.Background(), user.Username)))
+
+	err := bank.ResendVerifyEmail(context.Background(), verified.Username)
+	require.Equal(t, FailedPrecondition, KindOf(err))
+}
+
+func TestForgotPassword(t *testing.T) {
+	user := db.User{Username: util.RandomName(), FullName: util.RandomName(), Email: util.RandomEmail()}
+	unknownEmail := util.RandomEmail()
+
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(2).Return(user, nil)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(unknownEmail)).Times(1).Return(db.User{}, sql.ErrNoRows)
+	bank := newTestBank(t, store, nil)
+	mailer := &fakeMailer{}
+	bank.mailer = mailer
+
+	// Nothing is sent to unknown addresses, without telling
+	require.NoError(t, bank.ForgotPassword(context.Background(), unknownEmail))
+	bank.WaitForMail()
+	require.Empty(t, mailer.sent)
+
+	// The email is sent in the background, so failing to send it doesn't tell either
+	mailer.err = errors.New("smtp server unavailable")
+	require.NoError(t, bank.ForgotPassword(context.Background(), user.Email))
+	bank.WaitForMail()
+	require.Empty(t, mailer.sent)
+
+	mailer.err = nil
+	require.NoError(t, bank.ForgotPassword(context.Background(), user.Email))
+	bank.WaitForMail()
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, []string{user.Email}, mailer.sent[0].To)
+
+	// The email links to the page resetting the password with a reset token of the user
+	var link string
+	for _, line := range strings.Split(mailer.sent[0].Body, "\n") {
+		if strings.HasPrefix(line, "http") {
+			link = line
+		}
+	}
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "/reset_password", parsed.Path)
+	payload, err := bank.tokenMaker.VerifyPurposeToken(parsed.Query().Get("token"), token.PurposePasswordReset)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, payload.Username)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/webhook"
+)
+
+// maxWebhooks is the number of webhooks a user can register
+const maxWebhooks = 10
+
+// CreateWebhook registers a URL to which the events of the actor are delivered. The returned webhook holds the
+// secret of the signatures of its deliveries.
+func (bank *Bank) CreateWebhook(ctx context.Context, actor Actor, rawURL string) (db.Webhook, error) {
+	var webhook db.Webhook
+	parsed, err := parseWebhookURL(ctx, rawURL)
+	if err != nil {
+		return webhook, err
+	}
+	webhooks, err := bank.store.ListWebhooks(ctx, actor.Username)
+	if err != nil {
+		return webhook, internalError(err)
+	}
+	if len(webhooks) >= maxWebhooks {
+		return webhook, newError(FailedPrecondition, "user [%s] already has %d webhooks", actor.Username, maxWebhooks)
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return webhook, internalError(err)
+	}
+	webhook, err = bank.store.CreateWebhook(ctx, db.CreateWebhookParams{
+		Owner:  actor.Username,
+		Url:    parsed.String(),
+		Secret: secret,
+	})
+	if err != nil {
+		return webhook, internalError(err)
+	}
+	return webhook, nil
+}
+
+// parseWebhookURL parses the URL of a webhook, which must be absolute http or https and resolve only to public
+// addresses, lest deliveries reach the bank's own network
+func parseWebhookURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || len(parsed.Host) == 0 {
+		return nil, newError(InvalidArgument, "url must be an absolute http or https URL")
+	}
+	err = webhook.CheckHost(ctx, parsed)
+	if errors.Is(err, webhook.ErrInternalAddress) {
+		return nil, newError(InvalidArgument, "url must resolve to public addresses only")
+	}
+	if err != nil {
+		// The resolver's error names the bank's own DNS server
+		return nil, newError(InvalidArgument, "url host cannot be resolved")
+	}
+	return parsed, nil
+}
+
+// ListWebhooks returns the webhooks of the actor, sorted by id
+func (bank *Bank) ListWebhooks(ctx context.Context, actor Actor) ([]db.Webhook, error) {
+	webhooks, err := bank.store.ListWebhooks(ctx, actor.Username)
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook stops the deliveries to a webhook of the actor, including those pending
+func (bank *Bank) DeleteWebhook(ctx context.Context, actor Actor, id int64) error {
+	webhook, err := bank.getWebhook(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if !actor.canDebit(webhook.Owner) {
+		return newError(Forbidden, "webhook doesn't belong to authenticated user")
+	}
+	if err := bank.store.DeleteWebhook(ctx, id); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the latest deliveries to a webhook the actor can read, newest first
+func (bank *Bank) ListWebhookDeliveries(ctx context.Context, actor Actor, id int64, limit int32) ([]db.WebhookDelivery, error) {
+	if _, err := bank.getWebhook(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	deliveries, err := bank.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		WebhookID: id,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+	return deliveries, nil
+}
+
+// getWebhook returns the webhook, if the actor can read it
+func (bank *Bank) getWebhook(ctx context.Context, actor Actor, id int64) (db.Webhook, error) {
+	webhook, err := bank.store.GetWebhook(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return webhook, newError(NotFound, "webhook [%d] not found", id)
+		}
+		return webhook, internalError(err)
+	}
+	if !actor.canRead(webhook.Owner) {
+		return webhook, newError(Forbidden, "webhook doesn't belong to authenticated user")
+	}
+	return webhook, nil
+}
@@ -0,0 +1,170 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// baseDelay is the wait after the first failure beyond the free ones, doubled with each further failure
+const baseDelay = time.Second
+
+// ErrThrottled is returned by Limiter.Check and Limiter.Reserve while a login has to wait
+type ErrThrottled struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrThrottled) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %s", e.RetryAfter)
+}
+
+// Options configure a Limiter
+type Options struct {
+	// Failures of a username before it is locked out. The first half are free, the others delay the next login.
+	MaxFailures int32
+	// Failures from a client IP, whatever the username, before it is locked out
+	MaxFailuresPerIP int32
+	// How long a lockout lasts. Must be the window of the store, so that a lockout ends with its failures.
+	LockoutDuration time.Duration
+}
+
+// Limiter slows down then locks out logins after repeated failures, counted per username and per client IP.
+// Usernames are counted whether or not they exist, so that the responses don't tell.
+//
+// A login is checked, then reserved before the password is compared: it counts as a failure from the start, so
+// that concurrent guesses can't all pass the check, and is released or forgotten if it succeeds. Taking back a
+// reservation also restores the time of the last failure, which the lockout is measured from.
+type Limiter struct {
+	store   Store
+	options Options
+}
+
+// NewLimiter creates a Limiter counting failures in store
+func NewLimiter(store Store, options Options) *Limiter {
+	return &Limiter{store: store, options: options}
+}
+
+// Check returns an *ErrThrottled if the login of username from clientIP has to wait. clientIP may be empty.
+func (limiter *Limiter) Check(ctx context.Context, username, clientIP string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range limiter.keys(username, clientIP) {
+		attempts, err := limiter.store.Attempts(ctx, key.name)
+		if err != nil {
+			return err
+		}
+		if keyWait := limiter.wait(attempts, key.maxFailures, now); keyWait > wait {
+			wait = keyWait
+		}
+	}
+	if wait > 0 {
+		// Clients retrying right on time shouldn't be a little early
+		return &ErrThrottled{RetryAfter: wait.Truncate(time.Second) + time.Second}
+	}
+	return nil
+}
+
+// Reservation is a login counted as a failure by Reserve, until it is released or succeeds
+type Reservation struct {
+	username string
+	failures []reservedFailure
+}
+
+// reservedFailure is the failure a reservation added for a key
+type reservedFailure struct {
+	key      string
+	failedAt time.Time
+}
+
+// Reserve counts the login of username from clientIP as a failure until it is released. It returns an
+// *ErrThrottled, and takes the failure back, if other logins reserved since Check went over the maximum.
+func (limiter *Limiter) Reserve(ctx context.Context, username, clientIP string) (*Reservation, error) {
+	reservation := &Reservation{username: username}
+	for _, key := range limiter.keys(username, clientIP) {
+		attempts, err := limiter.store.AddFailure(ctx, key.name)
+		if err != nil {
+			return nil, err
+		}
+		reservation.failures = append(reservation.failures, reservedFailure{key: key.name, failedAt: attempts.LastFailedAt})
+		if attempts.Failures > key.maxFailures {
+			if err := limiter.Release(ctx, reservation); err != nil {
+				return nil, err
+			}
+			return nil, &ErrThrottled{RetryAfter: limiter.options.LockoutDuration}
+		}
+	}
+	return reservation, nil
+}
+
+// Release takes back a reservation whose login didn't fail, such as a right password awaiting its second factor
+func (limiter *Limiter) Release(ctx context.Context, reservation *Reservation) error {
+	for _, failure := range reservation.failures {
+		if err := limiter.store.RemoveFailure(ctx, failure.key, failure.failedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the failed logins of the reserved username and releases the reservation for the client IP. The
+// other failures of the client IP are kept: logging into an account of their own mustn't let a client go on
+// guessing the passwords of others.
+func (limiter *Limiter) Succeed(ctx context.Context, reservation *Reservation) error {
+	user := userKey(reservation.username)
+	if err := limiter.store.Reset(ctx, user); err != nil {
+		return err
+	}
+	for _, failure := range reservation.failures {
+		if failure.key == user {
+			continue
+		}
+		if err := limiter.store.RemoveFailure(ctx, failure.key, failure.failedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type limitedKey struct {
+	name        string
+	maxFailures int32
+}
+
+func (limiter *Limiter) keys(username, clientIP string) []limitedKey {
+	keys := []limitedKey{{name: userKey(username), maxFailures: limiter.options.MaxFailures}}
+	if len(clientIP) > 0 {
+		keys = append(keys, limitedKey{name: ipKey(clientIP), maxFailures: limiter.options.MaxFailuresPerIP})
+	}
+	return keys
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// wait returns how long a key with attempts must wait at now before its next login
+func (limiter *Limiter) wait(attempts Attempts, maxFailures int32, now time.Time) time.Duration {
+	lockout := limiter.options.LockoutDuration
+	free := maxFailures / 2
+	var delay time.Duration
+	switch {
+	case attempts.Failures >= maxFailures:
+		delay = lockout
+	case attempts.Failures > free:
+		delay = lockout
+		// Past 2^30 seconds the delay is longer than any sensible lockout anyway
+		if shift := attempts.Failures - free - 1; shift < 30 && baseDelay<<shift < lockout {
+			delay = baseDelay << shift
+		}
+	default:
+		return 0
+	}
+	if wait := attempts.LastFailedAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
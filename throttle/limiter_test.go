@@ -0,0 +1,163 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter() *Limiter {
+	return NewLimiter(NewMemoryStore(time.Hour), Options{
+		MaxFailures:      6,
+		MaxFailuresPerIP: 10,
+		LockoutDuration:  time.Hour,
+	})
+}
+
+// failTimes makes n logins which fail, left reserved
+func failTimes(t *testing.T, limiter *Limiter, username, clientIP string, n int) {
+	for i := 0; i < n; i++ {
+		_, err := limiter.Reserve(context.Background(), username, clientIP)
+		require.NoError(t, err)
+	}
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	var throttled *ErrThrottled
+	require.True(t, errors.As(err, &throttled), "expected *ErrThrottled, got %v", err)
+	return throttled.RetryAfter
+}
+
+func TestLimiterDelays(t *testing.T) {
+	limiter := newTestLimiter()
+	username, clientIP := util.RandomName(), "10.0.0.1"
+
+	// The first half of the failures are free
+	failTimes(t, limiter, username, clientIP, 3)
+	require.NoError(t, limiter.Check(context.Background(), username, clientIP))
+
+	// then each one doubles the delay
+	failTimes(t, limiter, username, clientIP, 1)
+	require.Equal(t, time.Second, retryAfter(t, limiter.Check(context.Background(), username, clientIP)))
+	failTimes(t, limiter, username, clientIP, 1)
+	require.Equal(t, 2*time.Second, retryAfter(t, limiter.Check(context.Background(), username, clientIP)))
+
+	// until the lockout
+	failTimes(t, limiter, username, clientIP, 1)
+	wait := retryAfter(t, limiter.Check(context.Background(), username, clientIP))
+	require.InDelta(t, time.Hour, wait, float64(time.Second))
+
+	// It doesn't matter where the next try comes from
+	wait = retryAfter(t, limiter.Check(context.Background(), username, "10.0.0.2"))
+	require.InDelta(t, time.Hour, wait, float64(time.Second))
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), "10.0.0.2"))
+}
+
+func TestLimiterClientIP(t *testing.T) {
+	limiter := newTestLimiter()
+	clientIP := "10.0.0.1"
+
+	// Spreading guesses over usernames doesn't escape the limit of the IP
+	for i := 0; i < 10; i++ {
+		failTimes(t, limiter, util.RandomName(), clientIP, 1)
+	}
+	wait := retryAfter(t, limiter.Check(context.Background(), util.RandomName(), clientIP))
+	require.InDelta(t, time.Hour, wait, float64(time.Second))
+
+	// Other clients can still log in, as can clients without a known IP
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), "10.0.0.2"))
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), ""))
+}
+
+func TestLimiterSucceed(t *testing.T) {
+	limiter := newTestLimiter()
+	username, clientIP := util.RandomName(), "10.0.0.1"
+
+	failTimes(t, limiter, username, clientIP, 5)
+	reservation, err := limiter.Reserve(context.Background(), username, clientIP)
+	require.NoError(t, err)
+	require.NoError(t, limiter.Succeed(context.Background(), reservation))
+	require.NoError(t, limiter.Check(context.Background(), username, "10.0.0.2"))
+
+	// The failures of the IP are kept, but not the login which succeeded
+	failTimes(t, limiter, util.RandomName(), clientIP, 1)
+	require.Equal(t, time.Second, retryAfter(t, limiter.Check(context.Background(), username, clientIP)))
+}
+
+func TestLimiterRelease(t *testing.T) {
+	limiter := newTestLimiter()
+	username, clientIP := util.RandomName(), "10.0.0.1"
+
+	// Logins which don't fail aren't counted against the username nor the IP
+	for i := 0; i < 10; i++ {
+		reservation, err := limiter.Reserve(context.Background(), username, clientIP)
+		require.NoError(t, err)
+		require.NoError(t, limiter.Release(context.Background(), reservation))
+	}
+	require.NoError(t, limiter.Check(context.Background(), username, clientIP))
+}
+
+func TestLimiterLockoutAfterSuccess(t *testing.T) {
+	limiter := newTestLimiter()
+	clientIP := "10.0.0.1"
+
+	// The failures of the IP delay it by 8 seconds, which have passed since the last one
+	for i := 0; i < 9; i++ {
+		failTimes(t, limiter, util.RandomName(), clientIP, 1)
+	}
+	store := limiter.store.(*MemoryStore)
+	attempts := store.attempts[ipKey(clientIP)]
+	attempts.LastFailedAt = attempts.LastFailedAt.Add(-time.Minute)
+	store.attempts[ipKey(clientIP)] = attempts
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), clientIP))
+
+	// A login from the IP which succeeds doesn't start the delay over
+	reservation, err := limiter.Reserve(context.Background(), util.RandomName(), clientIP)
+	require.NoError(t, err)
+	require.NoError(t, limiter.Succeed(context.Background(), reservation))
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), clientIP))
+
+	// nor does one awaiting its second factor
+	reservation, err = limiter.Reserve(context.Background(), util.RandomName(), clientIP)
+	require.NoError(t, err)
+	require.NoError(t, limiter.Release(context.Background(), reservation))
+	require.NoError(t, limiter.Check(context.Background(), util.RandomName(), clientIP))
+
+	// unlike one which fails, locking the IP out from then on
+	failTimes(t, limiter, util.RandomName(), clientIP, 1)
+	wait := retryAfter(t, limiter.Check(context.Background(), util.RandomName(), clientIP))
+	require.InDelta(t, time.Hour, wait, float64(time.Second))
+}
+
+func TestLimiterConcurrentReserve(t *testing.T) {
+	limiter := newTestLimiter()
+	username := util.RandomName()
+
+	// Guesses made at once all pass the check, but no more than the maximum get to compare a password
+	n := 20
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := limiter.Reserve(context.Background(), username, "")
+			errs <- err
+		}()
+	}
+	reserved := 0
+	for i := 0; i < n; i++ {
+		if err := <-errs; err == nil {
+			reserved++
+		} else {
+			require.Equal(t, time.Hour, retryAfter(t, err))
+		}
+	}
+	require.Equal(t, 6, reserved)
+
+	// Turned down reservations aren't counted
+	attempts, err := limiter.store.Attempts(context.Background(), userKey(username))
+	require.NoError(t, err)
+	require.Equal(t, int32(6), attempts.Failures)
+}
@@ -0,0 +1,98 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store for tests and single node deployments
+type MemoryStore struct {
+	mu           sync.Mutex
+	window       time.Duration
+	attempts     map[string]memoryAttempts
+	lastEviction time.Time
+}
+
+type memoryAttempts struct {
+	Attempts
+	// previousFailedAt is LastFailedAt before the last failure, restored if it is removed
+	previousFailedAt time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore forgetting failures after window
+func NewMemoryStore(window time.Duration) Store {
+	return &MemoryStore{
+		window:       window,
+		attempts:     make(map[string]memoryAttempts),
+		lastEviction: time.Now(),
+	}
+}
+
+// Attempts returns the recent failed logins of key
+func (store *MemoryStore) Attempts(ctx context.Context, key string) (Attempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	attempts, ok := store.attempts[key]
+	if !ok || store.isStale(attempts.Attempts, time.Now()) {
+		return Attempts{}, nil
+	}
+	return attempts.Attempts, nil
+}
+
+// AddFailure records a failed login of key
+func (store *MemoryStore) AddFailure(ctx context.Context, key string) (Attempts, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	attempts := store.attempts[key]
+	if store.isStale(attempts.Attempts, now) {
+		attempts = memoryAttempts{}
+	}
+	attempts.Failures++
+	attempts.previousFailedAt = attempts.LastFailedAt
+	attempts.LastFailedAt = now
+	store.attempts[key] = attempts
+	store.evictStale(now)
+	return attempts.Attempts, nil
+}
+
+// RemoveFailure takes back the failure of key added at failedAt
+func (store *MemoryStore) RemoveFailure(ctx context.Context, key string, failedAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if attempts, ok := store.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+		if attempts.LastFailedAt.Equal(failedAt) && !attempts.previousFailedAt.IsZero() {
+			attempts.LastFailedAt = attempts.previousFailedAt
+			attempts.previousFailedAt = time.Time{}
+		}
+		store.attempts[key] = attempts
+	}
+	return nil
+}
+
+// Reset forgets the failed logins of key
+func (store *MemoryStore) Reset(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.attempts, key)
+	return nil
+}
+
+func (store *MemoryStore) isStale(attempts Attempts, now time.Time) bool {
+	return !now.Before(attempts.LastFailedAt.Add(store.window))
+}
+
+// evictStale drops the forgotten failures. Caller must hold the lock.
+func (store *MemoryStore) evictStale(now time.Time) {
+	if now.Sub(store.lastEviction) < evictionInterval {
+		return
+	}
+	for key, attempts := range store.attempts {
+		if store.isStale(attempts.Attempts, now) {
+			delete(store.attempts, key)
+		}
+	}
+	store.lastEviction = now
+}
@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	key := util.RandomName()
+
+	for i := int32(1); i <= 3; i++ {
+		attempts, err := store.AddFailure(context.Background(), key)
+		require.NoError(t, err)
+		require.Equal(t, i, attempts.Failures)
+		require.WithinDuration(t, time.Now(), attempts.LastFailedAt, time.Second)
+	}
+
+	attempts, err := store.Attempts(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), attempts.Failures)
+
+	// Other keys are counted apart
+	attempts, err = store.Attempts(context.Background(), util.RandomName())
+	require.NoError(t, err)
+	require.Zero(t, attempts.Failures)
+
+	// Taking back the last failure restores the time of the one before
+	previous, err := store.Attempts(context.Background(), key)
+	require.NoError(t, err)
+	attempts, err = store.AddFailure(context.Background(), key)
+	require.NoError(t, err)
+	require.NoError(t, store.RemoveFailure(context.Background(), key, attempts.LastFailedAt))
+	attempts, err = store.Attempts(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), attempts.Failures)
+	require.True(t, previous.LastFailedAt.Equal(attempts.LastFailedAt))
+
+	require.NoError(t, store.Reset(context.Background(), key))
+	attempts, err = store.Attempts(context.Background(), key)
+	require.NoError(t, err)
+	require.Zero(t, attempts.Failures)
+}
+
+func TestMemoryStoreWindow(t *testing.T) {
+	store := NewMemoryStore(time.Hour).(*MemoryStore)
+	stale, live := util.RandomName(), util.RandomName()
+	store.attempts[stale] = memoryAttempts{Attempts: Attempts{Failures: 5, LastFailedAt: time.Now().Add(-time.Hour)}}
+
+	// Failures older than the window are forgotten, counting starts over
+	attempts, err := store.Attempts(context.Background(), stale)
+	require.NoError(t, err)
+	require.Zero(t, attempts.Failures)
+	attempts, err = store.AddFailure(context.Background(), stale)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), attempts.Failures)
+
+	// and swept on the next write
+	store.attempts[stale] = memoryAttempts{Attempts: Attempts{Failures: 5, LastFailedAt: time.Now().Add(-time.Hour)}}
+	store.lastEviction = time.Now().Add(-evictionInterval)
+	_, err = store.AddFailure(context.Background(), live)
+	require.NoError(t, err)
+	require.NotContains(t, store.attempts, stale)
+	require.Contains(t, store.attempts, live)
+}
@@ -0,0 +1,85 @@
+package throttle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// PostgresStore is a Store shared by every node using the same database
+type PostgresStore struct {
+	store  db.Querier
+	window time.Duration
+
+	mu           sync.Mutex
+	lastEviction time.Time
+}
+
+// NewPostgresStore creates a new PostgresStore forgetting failures after window
+func NewPostgresStore(store db.Querier, window time.Duration) Store {
+	return &PostgresStore{
+		store:        store,
+		window:       window,
+		lastEviction: time.Now(),
+	}
+}
+
+// Attempts returns the recent failed logins of key
+func (store *PostgresStore) Attempts(ctx context.Context, key string) (Attempts, error) {
+	attempt, err := store.store.GetLoginAttempt(ctx, db.GetLoginAttemptParams{
+		Key:          key,
+		ForgetBefore: time.Now().Add(-store.window),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Attempts{}, nil
+		}
+		return Attempts{}, err
+	}
+	return Attempts{Failures: attempt.Failures, LastFailedAt: attempt.LastFailedAt}, nil
+}
+
+// AddFailure records a failed login of key
+func (store *PostgresStore) AddFailure(ctx context.Context, key string) (Attempts, error) {
+	now := time.Now()
+	attempt, err := store.store.AddLoginFailure(ctx, db.AddLoginFailureParams{
+		Key:          key,
+		FailedAt:     now,
+		ForgetBefore: now.Add(-store.window),
+	})
+	if err != nil {
+		return Attempts{}, err
+	}
+	if err := store.evictStale(ctx, now); err != nil {
+		return Attempts{}, err
+	}
+	return Attempts{Failures: attempt.Failures, LastFailedAt: attempt.LastFailedAt}, nil
+}
+
+// RemoveFailure takes back the failure of key added at failedAt
+func (store *PostgresStore) RemoveFailure(ctx context.Context, key string, failedAt time.Time) error {
+	return store.store.RemoveLoginFailure(ctx, db.RemoveLoginFailureParams{Key: key, FailedAt: failedAt})
+}
+
+// Reset forgets the failed logins of key
+func (store *PostgresStore) Reset(ctx context.Context, key string) error {
+	return store.store.DeleteLoginAttempt(ctx, key)
+}
+
+// evictStale deletes the forgotten failures
+func (store *PostgresStore) evictStale(ctx context.Context, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if now.Sub(store.lastEviction) < evictionInterval {
+		return nil
+	}
+	if err := store.store.DeleteStaleLoginAttempts(ctx, now.Add(-store.window)); err != nil {
+		return err
+	}
+	store.lastEviction = now
+	return nil
+}
@@ -0,0 +1,43 @@
+package throttle
+
+import (
+	"context"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+)
+
+// evictionInterval is the minimum time between two sweeps of forgotten failures
+const evictionInterval = time.Minute
+
+// Attempts are the recent failed logins of a key
+type Attempts struct {
+	Failures     int32
+	LastFailedAt time.Time
+}
+
+// Store counts the failed logins of keys, such as a username or a client IP. Failures are forgotten
+// once the store's window has passed since the last one.
+type Store interface {
+	// Attempts returns the recent failed logins of key
+	Attempts(ctx context.Context, key string) (Attempts, error)
+
+	// AddFailure records a failed login of key, returning its recent failed logins including this one
+	AddFailure(ctx context.Context, key string) (Attempts, error)
+
+	// RemoveFailure takes back the failure added at failedAt for a login which turned out not to fail. Unless
+	// another failure was added since, the time of the last failure goes back to that of the one before.
+	RemoveFailure(ctx context.Context, key string, failedAt time.Time) error
+
+	// Reset forgets the failed logins of key
+	Reset(ctx context.Context, key string) error
+}
+
+// NewStore returns the store of the given kind, "postgres" or "memory", remembering failures for window.
+// It defaults to memory, which only counts the failures seen by the same store.
+func NewStore(kind string, store db.Querier, window time.Duration) Store {
+	if kind == "postgres" {
+		return NewPostgresStore(store, window)
+	}
+	return NewMemoryStore(window)
+}
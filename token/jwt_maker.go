@@ -23,18 +23,53 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey: secretKey}, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
+	return maker.sign(payload)
+}
+
+// CreateRefreshToken creates a new token for a specific username, role and duration, only valid for PurposeRefresh.
+// It keeps the role, which renewed access tokens are issued with.
+func (maker *JWTMaker) CreateRefreshToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Purpose = PurposeRefresh
+	return maker.sign(payload)
+}
+
+// CreatePurposeToken creates a new token for a specific username and duration, only valid for purpose
+func (maker *JWTMaker) CreatePurposeToken(username string, purpose string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, "", duration)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Purpose = purpose
+	return maker.sign(payload)
+}
+
+func (maker *JWTMaker) sign(payload *Payload) (string, *Payload, error) {
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	return token, payload, err
 }
 
-// VerifyToken checks if the token is valid or not
+// VerifyToken checks if the token is valid or not. Tokens created for a purpose are not.
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+	return maker.verify(token, "")
+}
+
+// VerifyPurposeToken checks if the token is valid and was created for purpose
+func (maker *JWTMaker) VerifyPurposeToken(token string, purpose string) (*Payload, error) {
+	return maker.verify(token, purpose)
+}
+
+func (maker *JWTMaker) verify(token string, purpose string) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		// Verify that the signing method in the token header is same as we're signing.
 		// Because we are using SigningMethodHS256:
@@ -54,7 +89,7 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 	payload, ok := jwtToken.Claims.(*Payload)
-	if !ok {
+	if !ok || payload.Purpose != purpose {
 		return nil, ErrInvalidToken
 	}
 	return payload, nil
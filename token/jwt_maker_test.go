@@ -14,19 +14,22 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := util.RandomName()
+	role := util.TellerRole
 	duration := time.Minute
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 	require.NotZero(t, payload.ID)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -35,17 +38,18 @@ func TestExpiredJWTToken(t *testing.T) {
 	maker, err := NewJWTMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomName(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomName(), util.CustomerRole, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
 
 func TestInvalidJWTToken(t *testing.T) {
-	payload, err := NewPayload(util.RandomName(), time.Minute)
+	payload, err := NewPayload(util.RandomName(), util.CustomerRole, time.Minute)
 	require.NoError(t, err)
 
 	// Create a jwt token with a different signing method to ours
@@ -63,3 +67,49 @@ func TestInvalidJWTToken(t *testing.T) {
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestJWTPurposeToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomName()
+	token, payload, err := maker.CreatePurposeToken(username, PurposePasswordReset, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, PurposePasswordReset, payload.Purpose)
+	require.Empty(t, payload.Role)
+
+	payload, err = maker.VerifyPurposeToken(token, PurposePasswordReset)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+
+	// Purpose tokens can't be used to authenticate, nor for another purpose
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	_, err = maker.VerifyPurposeToken(token, "other")
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	// Nor can access tokens be used for a purpose
+	accessToken, _, err := maker.CreateToken(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	_, err = maker.VerifyPurposeToken(accessToken, PurposePasswordReset)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestJWTRefreshToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomName()
+	token, payload, err := maker.CreateRefreshToken(username, util.AdminRole, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, PurposeRefresh, payload.Purpose)
+
+	payload, err = maker.VerifyPurposeToken(token, PurposeRefresh)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, util.AdminRole, payload.Role)
+
+	// Refresh tokens don't authenticate requests
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
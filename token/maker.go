@@ -4,9 +4,18 @@ import "time"
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token for a specific username, role and duration, returning it with its payload
+	CreateToken(username string, role string, duration time.Duration) (string, *Payload, error)
 
-	// VerifyToken checks if the token is valid or not
+	// CreateRefreshToken creates a new token for a specific username, role and duration, only valid for PurposeRefresh
+	CreateRefreshToken(username string, role string, duration time.Duration) (string, *Payload, error)
+
+	// CreatePurposeToken creates a new token for a specific username and duration, only valid for purpose
+	CreatePurposeToken(username string, purpose string, duration time.Duration) (string, *Payload, error)
+
+	// VerifyToken checks if the token is valid or not. Tokens created for a purpose are not.
 	VerifyToken(token string) (*Payload, error)
+
+	// VerifyPurposeToken checks if the token is valid and was created for purpose
+	VerifyPurposeToken(token string, purpose string) (*Payload, error)
 }
@@ -0,0 +1,91 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userRevocation revokes the tokens issued to a user at or before revokedBefore
+type userRevocation struct {
+	revokedBefore time.Time
+	expiresAt     time.Time
+}
+
+// MemoryRevocationStore is an in-process RevocationStore for tests and single node deployments
+type MemoryRevocationStore struct {
+	mu sync.RWMutex
+	// maxTokenDuration bounds how long a user revocation has to be kept
+	maxTokenDuration time.Duration
+	tokens           map[uuid.UUID]time.Time
+	users            map[string]userRevocation
+	lastEviction     time.Time
+}
+
+// NewMemoryRevocationStore creates a new MemoryRevocationStore.
+// maxTokenDuration must be the longest duration any token is issued for.
+func NewMemoryRevocationStore(maxTokenDuration time.Duration) RevocationStore {
+	return &MemoryRevocationStore{
+		maxTokenDuration: maxTokenDuration,
+		tokens:           make(map[uuid.UUID]time.Time),
+		users:            make(map[string]userRevocation),
+		lastEviction:     time.Now(),
+	}
+}
+
+// RevokeToken invalidates a single token until it expires
+func (store *MemoryRevocationStore) RevokeToken(ctx context.Context, payload *Payload) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.tokens[payload.ID] = payload.ExpiredAt
+	store.evictExpired()
+	return nil
+}
+
+// RevokeUser invalidates every token issued to the user up to now
+func (store *MemoryRevocationStore) RevokeUser(ctx context.Context, username string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	store.users[username] = userRevocation{
+		revokedBefore: now,
+		expiresAt:     now.Add(store.maxTokenDuration),
+	}
+	store.evictExpired()
+	return nil
+}
+
+// IsRevoked checks if the token has been revoked
+func (store *MemoryRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	now := time.Now()
+	if expiresAt, ok := store.tokens[payload.ID]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	if revocation, ok := store.users[payload.Username]; ok && now.Before(revocation.expiresAt) {
+		return !payload.IssuedAt.After(revocation.revokedBefore), nil
+	}
+	return false, nil
+}
+
+// evictExpired drops the revocations of tokens that have expired anyway. Caller must hold the write lock.
+func (store *MemoryRevocationStore) evictExpired() {
+	now := time.Now()
+	if now.Sub(store.lastEviction) < evictionInterval {
+		return
+	}
+	for id, expiresAt := range store.tokens {
+		if !now.Before(expiresAt) {
+			delete(store.tokens, id)
+		}
+	}
+	for username, revocation := range store.users {
+		if !now.Before(revocation.expiresAt) {
+			delete(store.users, username)
+		}
+	}
+	store.lastEviction = now
+}
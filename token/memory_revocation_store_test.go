@@ -0,0 +1,75 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStoreRevokeToken(t *testing.T) {
+	store := NewMemoryRevocationStore(time.Hour)
+
+	payload1, err := NewPayload(util.RandomName(), util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := NewPayload(payload1.Username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeToken(context.Background(), payload1))
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// Other tokens of the same user are still valid
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevocationStoreRevokeUser(t *testing.T) {
+	store := NewMemoryRevocationStore(time.Hour)
+	username := util.RandomName()
+
+	issuedBefore, err := NewPayload(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+	otherUser, err := NewPayload(util.RandomName(), util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeUser(context.Background(), username))
+
+	issuedAfter, err := NewPayload(username, util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), issuedBefore)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), issuedAfter)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherUser)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevocationStoreEviction(t *testing.T) {
+	store := NewMemoryRevocationStore(time.Hour).(*MemoryRevocationStore)
+
+	expired, err := NewPayload(util.RandomName(), util.CustomerRole, -time.Minute)
+	require.NoError(t, err)
+	live, err := NewPayload(util.RandomName(), util.CustomerRole, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeToken(context.Background(), expired))
+	require.Len(t, store.tokens, 1)
+
+	// Force the next write to sweep
+	store.lastEviction = time.Now().Add(-evictionInterval)
+	require.NoError(t, store.RevokeToken(context.Background(), live))
+	require.Len(t, store.tokens, 1)
+	require.Contains(t, store.tokens, live.ID)
+}
@@ -27,21 +27,59 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return maker.encrypt(payload)
 }
 
-// VerifyToken checks if the token is valid or not
+// CreateRefreshToken creates a new token for a specific username, role and duration, only valid for PurposeRefresh.
+// It keeps the role, which renewed access tokens are issued with.
+func (maker *PasetoMaker) CreateRefreshToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Purpose = PurposeRefresh
+	return maker.encrypt(payload)
+}
+
+// CreatePurposeToken creates a new token for a specific username and duration, only valid for purpose
+func (maker *PasetoMaker) CreatePurposeToken(username string, purpose string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, "", duration)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Purpose = purpose
+	return maker.encrypt(payload)
+}
+
+func (maker *PasetoMaker) encrypt(payload *Payload) (string, *Payload, error) {
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return token, payload, err
+}
+
+// VerifyToken checks if the token is valid or not. Tokens created for a purpose are not.
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	return maker.decrypt(token, "")
+}
+
+// VerifyPurposeToken checks if the token is valid and was created for purpose
+func (maker *PasetoMaker) VerifyPurposeToken(token string, purpose string) (*Payload, error) {
+	return maker.decrypt(token, purpose)
+}
+
+func (maker *PasetoMaker) decrypt(token string, purpose string) (*Payload, error) {
 	payload := &Payload{}
 	if err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil); err != nil {
 		return nil, ErrInvalidToken
 	}
+	if payload.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
 	if err := payload.Valid(); err != nil {
 		return nil, err
 	}
@@ -12,17 +12,31 @@ var (
 	ErrInvalidToken = errors.New("token is invalid")
 )
 
+const (
+	// PurposeRefresh is the purpose of refresh tokens, which only renew access tokens
+	PurposeRefresh = "refresh"
+	// PurposePasswordReset is the purpose of the tokens emailed to users who forgot their password
+	PurposePasswordReset = "password_reset"
+	// PurposeMFAPending is the purpose of the tokens issued for a correct password when the user has two-factor
+	// authentication enabled, to be exchanged with a code for the access and refresh tokens
+	PurposeMFAPending = "mfa_pending"
+)
+
 // PayLoad contains the payload data of the token
 type Payload struct {
 	// ID To invalidate specific token, for eg. when they are leaked
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`  // Time token is issued at
 	ExpiredAt time.Time `json:"expired_at"` // Time at which token is expired
+	// Purpose restricts the token to a single use, such as PurposeRefresh or PurposePasswordReset.
+	// Access tokens have none.
+	Purpose string `json:"purpose,omitempty"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role and duration
+func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -30,6 +44,7 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
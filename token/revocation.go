@@ -0,0 +1,22 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+// evictionInterval is the minimum time between two sweeps of expired revocations
+const evictionInterval = time.Minute
+
+// RevocationStore keeps track of tokens invalidated before their expiry. Besides the memory one, the db package
+// has one shared by every node using the same database.
+type RevocationStore interface {
+	// RevokeToken invalidates a single token until it expires
+	RevokeToken(ctx context.Context, payload *Payload) error
+
+	// RevokeUser invalidates every token issued to the user up to now, as when they change their password
+	RevokeUser(ctx context.Context, username string) error
+
+	// IsRevoked checks if the token has been revoked
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
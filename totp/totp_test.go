@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCode(t *testing.T) {
+	// SHA1 test vectors of RFC 6238, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, testCase := range testCases {
+		code, err := Code(secret, Step(time.Unix(testCase.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, testCase.code, code)
+	}
+
+	_, err := Code("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	// Clocks a step apart still agree
+	_, ok = Validate(secret, code, now.Add(Period))
+	require.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(3*Period))
+	require.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	require.False(t, ok)
+	other, err := NewSecret()
+	require.NoError(t, err)
+	_, ok = Validate(other, code, now)
+	require.False(t, ok)
+}
+
+func TestURL(t *testing.T) {
+	link, err := url.Parse(URL("Simple Bank", "alice", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", link.Scheme)
+	require.Equal(t, "totp", link.Host)
+	require.Equal(t, "/Simple Bank:alice", link.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", link.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", link.Query().Get("issuer"))
+}
@@ -11,8 +11,59 @@ type Config struct {
 	DBDriver              string        `mapstructure:"DB_DRIVER"`
 	DBSource              string        `mapstructure:"DB_SOURCE"`
 	ServerAddress         string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress     string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	MetricsAddress        string        `mapstructure:"METRICS_ADDRESS"` // Internal address serving /metrics, empty disables it
 	TokenSymmetricKey     string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	ACCESS_TOKEN_DURATION time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration  time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	IdempotencyKeyTTL     time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	TokenRevocationStore  string        `mapstructure:"TOKEN_REVOCATION_STORE"` // "memory" or "postgres"
+	FXRatesFile           string        `mapstructure:"FX_RATES_FILE"`
+	FXRatesReloadInterval time.Duration `mapstructure:"FX_RATES_RELOAD_INTERVAL"`
+	SlowQueryThreshold    time.Duration `mapstructure:"SLOW_QUERY_THRESHOLD"`
+	HTTPReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"` // Bounds whole responses, streamed statements included
+	HTTPIdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout       time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`   // Deadline for in-flight requests to drain
+	SchedulerInterval     time.Duration `mapstructure:"SCHEDULER_INTERVAL"` // Polling of due scheduled transfers, 0 disables it
+	// Polling of the outbox and of due webhook deliveries, 0 disables it
+	WebhookDispatchInterval time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookTimeout          time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts      int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"` // Failed attempts before a delivery is dead
+	Mailer                  string        `mapstructure:"MAILER"`               // "smtp", or "log" to write emails to MAIL_LOG_FILE or the log
+	SMTPAddress             string        `mapstructure:"SMTP_ADDRESS"`         // host:port
+	SMTPUsername            string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword            string        `mapstructure:"SMTP_PASSWORD"`
+	MailFrom                string        `mapstructure:"MAIL_FROM"`
+	MailLogFile             string        `mapstructure:"MAIL_LOG_FILE"`
+	// Link sent to new users to verify their email address, the id and code of the verification are added to it
+	EmailVerificationURL string        `mapstructure:"EMAIL_VERIFICATION_URL"`
+	VerifyEmailDuration  time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	// Page of the client sent to users who forgot their password, with the reset token added to it. It posts the
+	// token and the new password to /users/password/reset.
+	PasswordResetURL      string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetDuration time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	// Failed logins are counted per username and per client IP: past half the maximum each failure delays the
+	// next login, at the maximum logins are locked out for LOGIN_LOCKOUT_DURATION
+	LoginAttemptStore     string        `mapstructure:"LOGIN_ATTEMPT_STORE"` // "memory" or "postgres"
+	LoginMaxFailures      int32         `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxFailuresPerIP int32         `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LoginLockoutDuration  time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	// Comma separated addresses or CIDRs of the reverse proxies whose X-Forwarded-For and X-Real-IP headers give
+	// the client IP. Empty if the server is reached directly, the client IP is then the peer address.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+	// Name of the bank in authenticator apps
+	TOTPIssuer string `mapstructure:"TOTP_ISSUER"`
+	// Time users with two-factor authentication have to enter their code once they gave their password
+	MFATokenDuration time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+}
+
+// MaxTokenDuration is the longest lifetime of the access and refresh tokens
+func (config Config) MaxTokenDuration() time.Duration {
+	if config.RefreshTokenDuration > config.ACCESS_TOKEN_DURATION {
+		return config.RefreshTokenDuration
+	}
+	return config.ACCESS_TOKEN_DURATION
 }
 
 // LoadConfig reads configuration from file and env vars
@@ -1,5 +1,7 @@
 package util
 
+import "math/big"
+
 // currencies definition for the app
 const (
 	USD = "USD"
@@ -7,9 +9,28 @@ const (
 	GBP = "GBP"
 	EUR = "EUR"
 	AUD = "AUD"
+	NZD = "NZD"
+	CHF = "CHF"
+	SEK = "SEK"
+	SGD = "SGD"
+	JPY = "JPY"
 )
 
-var supportedCurrencies = []string{USD, CAD, GBP, EUR, AUD}
+var supportedCurrencies = []string{USD, CAD, GBP, EUR, AUD, NZD, CHF, SEK, SGD, JPY}
+
+// minorUnits is the number of decimal places between each currency's major and minor unit (ISO 4217)
+var minorUnits = map[string]int{
+	USD: 2,
+	CAD: 2,
+	GBP: 2,
+	EUR: 2,
+	AUD: 2,
+	NZD: 2,
+	CHF: 2,
+	SEK: 2,
+	SGD: 2,
+	JPY: 0,
+}
 
 // IsSupportedCurrency returns true if the currency is supported by the application
 func IsSupportedCurrency(currency string) bool {
@@ -20,3 +41,15 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// CurrencyExponent returns the number of decimal places between the major and the minor unit of a currency,
+// e.g. 2 for USD, whose amounts are in cents
+func CurrencyExponent(currency string) int {
+	return minorUnits[currency]
+}
+
+// FormatAmount formats an amount in minor units of the currency as a decimal in major units, e.g. -1205 USD as -12.05
+func FormatAmount(amount int64, currency string) string {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(minorUnits[currency])), nil)
+	return new(big.Rat).SetFrac(big.NewInt(amount), scale).FloatString(minorUnits[currency])
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	require.Equal(t, "12.05", FormatAmount(1205, USD))
+	require.Equal(t, "-0.50", FormatAmount(-50, EUR))
+	require.Equal(t, "0.00", FormatAmount(0, GBP))
+	require.Equal(t, "-1205", FormatAmount(-1205, JPY))
+}
+
+func TestCurrencyExponent(t *testing.T) {
+	require.Equal(t, 2, CurrencyExponent(USD))
+	require.Equal(t, 0, CurrencyExponent(JPY))
+}
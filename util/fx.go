@@ -0,0 +1,204 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rounding modes recorded against converted amounts
+const (
+	RoundingNone     = "none"
+	RoundingHalfEven = "half_even"
+)
+
+// fxRatePrecision is the number of decimal places rates are rounded to before use, matching the ledger column
+const fxRatePrecision = 8
+
+// FXProvider is an interface for looking up foreign exchange rates
+type FXProvider interface {
+	// Rate returns how many units of the to currency one unit of the from currency buys
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// StaticFXProvider serves rates from a fixed table
+type StaticFXProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewFXProvider returns a provider reading the rates file, reloaded at most every reloadInterval.
+// Without a rates file, only same currency transfers are possible.
+func NewFXProvider(ratesFile string, reloadInterval time.Duration) (FXProvider, error) {
+	if len(ratesFile) == 0 {
+		return NewStaticFXProvider(nil)
+	}
+	return NewFileFXProvider(ratesFile, reloadInterval)
+}
+
+// NewStaticFXProvider creates a new StaticFXProvider.
+// Rates are decimal strings keyed by currency pair, e.g. {"USD/EUR": "0.92"}.
+func NewStaticFXProvider(rates map[string]string) (FXProvider, error) {
+	parsed, err := parseFXRates(rates)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticFXProvider{rates: parsed}, nil
+}
+
+// Rate returns how many units of the to currency one unit of the from currency buys
+func (provider *StaticFXProvider) Rate(from, to string) (*big.Rat, error) {
+	return lookupFXRate(provider.rates, from, to)
+}
+
+// FileFXProvider serves rates from a JSON file of pair to rate, reloading it when the file changes
+type FileFXProvider struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu        sync.Mutex
+	rates     map[string]*big.Rat
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileFXProvider creates a new FileFXProvider.
+// The file is checked for changes at most once per reloadInterval.
+func NewFileFXProvider(path string, reloadInterval time.Duration) (FXProvider, error) {
+	provider := &FileFXProvider{
+		path:           path,
+		reloadInterval: reloadInterval,
+	}
+	if err := provider.reload(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// Rate returns how many units of the to currency one unit of the from currency buys
+func (provider *FileFXProvider) Rate(from, to string) (*big.Rat, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if time.Since(provider.checkedAt) >= provider.reloadInterval {
+		// Keep serving the last good rates if the file is being rewritten
+		_ = provider.reload()
+	}
+	return lookupFXRate(provider.rates, from, to)
+}
+
+// reload reads the rates file if it has been modified since it was last read
+func (provider *FileFXProvider) reload() error {
+	provider.checkedAt = time.Now()
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat fx rates file: %w", err)
+	}
+	if provider.rates != nil && info.ModTime().Equal(provider.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(provider.path)
+	if err != nil {
+		return fmt.Errorf("failed to read fx rates file: %w", err)
+	}
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return fmt.Errorf("failed to parse fx rates file: %w", err)
+	}
+	parsed, err := parseFXRates(rates)
+	if err != nil {
+		return err
+	}
+	provider.rates = parsed
+	provider.modTime = info.ModTime()
+	return nil
+}
+
+// parseFXRates validates a table of decimal rates keyed by "FROM/TO" currency pair
+func parseFXRates(rates map[string]string) (map[string]*big.Rat, error) {
+	parsed := make(map[string]*big.Rat, len(rates))
+	for pair, rate := range rates {
+		currencies := strings.Split(pair, "/")
+		if len(currencies) != 2 || !IsSupportedCurrency(currencies[0]) || !IsSupportedCurrency(currencies[1]) {
+			return nil, fmt.Errorf("invalid currency pair: %s", pair)
+		}
+		r, ok := new(big.Rat).SetString(rate)
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %s", pair, rate)
+		}
+		parsed[pair] = roundFXRate(r)
+	}
+	return parsed, nil
+}
+
+// lookupFXRate finds the rate of a pair, falling back to the inverse of the opposite pair
+func lookupFXRate(rates map[string]*big.Rat, from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if rate, ok := rates[from+"/"+to]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := rates[to+"/"+from]; ok {
+		return roundFXRate(new(big.Rat).Inv(rate)), nil
+	}
+	return nil, fmt.Errorf("no exchange rate from %s to %s", from, to)
+}
+
+// roundFXRate rounds a rate to the precision stored on the ledger, so the recorded rate is the one applied
+func roundFXRate(rate *big.Rat) *big.Rat {
+	r, _ := new(big.Rat).SetString(rate.FloatString(fxRatePrecision))
+	return r
+}
+
+// FormatFXRate formats a rate as a decimal string with the ledger's precision
+func FormatFXRate(rate *big.Rat) string {
+	return rate.FloatString(fxRatePrecision)
+}
+
+// ErrAmountOverflow is returned when a converted amount doesn't fit in an int64 of minor units
+var ErrAmountOverflow = errors.New("converted amount overflows")
+
+// ConvertAmount converts an amount in minor units of the from currency into minor units of the to currency,
+// rounding half to even. It fails with ErrAmountOverflow if the result is out of range.
+func ConvertAmount(amount int64, from, to string, rate *big.Rat) (int64, error) {
+	converted := new(big.Rat).Mul(big.NewRat(amount, 1), rate)
+	// Shift between the two currencies' minor units
+	exponent := minorUnits[to] - minorUnits[from]
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent >= 0 {
+		converted.Mul(converted, new(big.Rat).SetInt(scale))
+	} else {
+		converted.Quo(converted, new(big.Rat).SetInt(scale))
+	}
+	return roundHalfEven(converted)
+}
+
+// roundHalfEven rounds a non-negative rational to the nearest integer, ties to even
+func roundHalfEven(r *big.Rat) (int64, error) {
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Compare 2 * remainder with the denominator
+	twice := new(big.Int).Mul(remainder, big.NewInt(2))
+	switch twice.Cmp(r.Denom()) {
+	case 1:
+		quotient.Add(quotient, big.NewInt(1))
+	case 0:
+		if quotient.Bit(0) == 1 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	if !quotient.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return quotient.Int64(), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFXProvider(t *testing.T) {
+	provider, err := NewStaticFXProvider(map[string]string{"USD/EUR": "0.8"})
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.80000000", FormatFXRate(rate))
+
+	// Inverse of the configured pair
+	rate, err = provider.Rate(EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, "1.25000000", FormatFXRate(rate))
+
+	rate, err = provider.Rate(GBP, GBP)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1, 1), rate)
+
+	_, err = provider.Rate(USD, GBP)
+	require.Error(t, err)
+}
+
+func TestInvalidFXRates(t *testing.T) {
+	for _, rates := range []map[string]string{
+		{"USD-EUR": "0.8"},
+		{"USD/XXX": "0.8"},
+		{"USD/EUR": "abc"},
+		{"USD/EUR": "-1"},
+	} {
+		_, err := NewStaticFXProvider(rates)
+		require.Error(t, err)
+	}
+}
+
+func TestFileFXProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD/EUR": "0.8"}`), 0o600))
+
+	provider, err := NewFileFXProvider(path, 0)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.80000000", FormatFXRate(rate))
+
+	// Rewritten file is picked up
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD/EUR": "0.9"}`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	rate, err = provider.Rate(USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.90000000", FormatFXRate(rate))
+
+	// A broken file keeps the last good rates
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+
+	rate, err = provider.Rate(USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.90000000", FormatFXRate(rate))
+}
+
+func TestConvertAmount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   int64
+		from, to string
+		rate     string
+		want     int64
+	}{
+		{name: "SameExponent", amount: 1000, from: USD, to: EUR, rate: "0.92", want: 920},
+		{name: "ToZeroDecimals", amount: 1000, from: USD, to: JPY, rate: "151.5", want: 1515},
+		{name: "FromZeroDecimals", amount: 1515, from: JPY, to: USD, rate: "0.0066", want: 1000},
+		{name: "TieRoundsToEven", amount: 5, from: USD, to: EUR, rate: "0.5", want: 2},
+		{name: "TieRoundsUpToEven", amount: 7, from: USD, to: EUR, rate: "0.5", want: 4},
+		{name: "AboveHalfRoundsUp", amount: 1, from: USD, to: EUR, rate: "0.6", want: 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rate, ok := new(big.Rat).SetString(testCase.rate)
+			require.True(t, ok)
+			got, err := ConvertAmount(testCase.amount, testCase.from, testCase.to, rate)
+			require.NoError(t, err)
+			require.Equal(t, testCase.want, got)
+		})
+	}
+
+	// Amounts near the limit can convert to more minor units than an int64 holds
+	rate, ok := new(big.Rat).SetString("151.5")
+	require.True(t, ok)
+	_, err := ConvertAmount(math.MaxInt64, USD, JPY, rate)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
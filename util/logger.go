@@ -0,0 +1,32 @@
+package util
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// GinLoggerKey is the key of the request scoped logger set on a *gin.Context, which only resolves string keys
+// when it is used as a context.Context
+const GinLoggerKey = "logger"
+
+// loggerKey is the context key of the request scoped logger
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying the logger
+func ContextWithLogger(ctx context.Context, logger zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, &logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or set on it under GinLoggerKey, or the global logger if
+// there is none
+func LoggerFromContext(ctx context.Context) *zerolog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zerolog.Logger); ok {
+		return logger
+	}
+	if logger, ok := ctx.Value(GinLoggerKey).(*zerolog.Logger); ok {
+		return logger
+	}
+	return &log.Logger
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).With().Str("request_id", "abc").Logger()
+
+	ctx := ContextWithLogger(context.Background(), logger)
+	LoggerFromContext(ctx).Info().Msg("hello")
+	require.Contains(t, buf.String(), `"request_id":"abc"`)
+	require.Contains(t, buf.String(), `"message":"hello"`)
+}
+
+func TestLoggerFromContextDefault(t *testing.T) {
+	require.Equal(t, &log.Logger, LoggerFromContext(context.Background()))
+}
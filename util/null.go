@@ -0,0 +1,35 @@
+package util
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// NullInt64 is a nullable bigint column that, unlike sql.NullInt64, is a plain number or null in JSON
+type NullInt64 struct {
+	sql.NullInt64
+}
+
+// NewNullInt64 returns a valid NullInt64 holding n
+func NewNullInt64(n int64) NullInt64 {
+	return NullInt64{sql.NullInt64{Int64: n, Valid: true}}
+}
+
+func (n NullInt64) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Int64)
+}
+
+func (n *NullInt64) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*n = NullInt64{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &n.Int64); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullInt64JSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Set   NullInt64 `json:"set"`
+		Unset NullInt64 `json:"unset"`
+	}{Set: NewNullInt64(42)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"set": 42, "unset": null}`, string(data))
+
+	var decoded struct {
+		Set   NullInt64 `json:"set"`
+		Unset NullInt64 `json:"unset"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, NewNullInt64(42), decoded.Set)
+	require.False(t, decoded.Unset.Valid)
+}
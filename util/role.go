@@ -0,0 +1,20 @@
+package util
+
+// roles a user can have
+const (
+	CustomerRole = "customer"
+	TellerRole   = "teller"
+	AdminRole    = "admin"
+)
+
+var supportedRoles = []string{CustomerRole, TellerRole, AdminRole}
+
+// IsSupportedRole returns true if the role is known to the application
+func IsSupportedRole(role string) bool {
+	for _, supportedRole := range supportedRoles {
+		if supportedRole == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinScheduleInterval is the shortest interval of an @every schedule
+const MinScheduleInterval = time.Hour
+
+// Schedule tells when a recurring job runs next
+type Schedule interface {
+	// Next returns the first run strictly after the time, or the zero time if there's none
+	Next(after time.Time) time.Time
+}
+
+// scheduleDescriptors are the cron shorthands ParseSchedule accepts
+var scheduleDescriptors = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// ParseSchedule parses either a standard five field cron expression (minute, hour, day of month, month,
+// day of week) evaluated in UTC, one of @yearly, @monthly, @weekly, @daily and @hourly, or "@every <duration>"
+// for a fixed interval of at least MinScheduleInterval
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		if interval < MinScheduleInterval {
+			return nil, fmt.Errorf("interval must be at least %s", MinScheduleInterval)
+		}
+		return intervalSchedule(interval), nil
+	}
+	if expression, ok := scheduleDescriptors[spec]; ok {
+		spec = expression
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.New("schedule must be a cron expression of 5 fields, a descriptor such as @monthly or @every <duration>")
+	}
+	var schedule cronSchedule
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute: %w", err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour: %w", err)
+	}
+	if schedule.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month: %w", err)
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month: %w", err)
+	}
+	if schedule.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week: %w", err)
+	}
+	// Both 0 and 7 are Sunday
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+	schedule.anyDayOfMonth = fields[2] == "*"
+	schedule.anyDayOfWeek = fields[4] == "*"
+	if schedule.Next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, errors.New("schedule never runs")
+	}
+	return schedule, nil
+}
+
+type intervalSchedule time.Duration
+
+func (interval intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(interval))
+}
+
+// cronSchedule holds a bit per value each field matches
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	anyDayOfMonth, anyDayOfWeek                bool
+}
+
+// cronSearchYears bounds the search for the next run, past it a schedule such as Feb 30 never runs
+const cronSearchYears = 5
+
+func (schedule cronSchedule) Next(after time.Time) time.Time {
+	after = after.UTC()
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	limit := day.AddDate(cronSearchYears, 0, 0)
+	for ; day.Before(limit); day = day.AddDate(0, 0, 1) {
+		if !schedule.matchesDay(day) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if schedule.hour&(1<<hour) == 0 {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if schedule.minute&(1<<minute) == 0 {
+					continue
+				}
+				if run := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute); run.After(after) {
+					return run
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay follows cron: when both the day of month and the day of week are restricted, either may match
+func (schedule cronSchedule) matchesDay(day time.Time) bool {
+	if schedule.month&(1<<day.Month()) == 0 {
+		return false
+	}
+	dayOfMonth := schedule.dayOfMonth&(1<<day.Day()) != 0
+	dayOfWeek := schedule.dayOfWeek&(1<<day.Weekday()) != 0
+	switch {
+	case schedule.anyDayOfMonth && schedule.anyDayOfWeek:
+		return true
+	case schedule.anyDayOfMonth:
+		return dayOfWeek
+	case schedule.anyDayOfWeek:
+		return dayOfMonth
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// parseCronField parses a comma separated list of *, values and ranges, each with an optional /step
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if step > 1 {
+				// As in cron, n/step runs from n to the end of the range
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", rangePart, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	after := time.Date(2026, time.October, 18, 9, 30, 0, 0, time.UTC) // A Sunday
+
+	testCases := []struct {
+		spec string
+		next []time.Time
+	}{
+		{
+			spec: "@monthly",
+			next: []time.Time{
+				time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "0 9 1 * *",
+			next: []time.Time{time.Date(2026, time.November, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			spec: "*/15 9-10 * * 1-5",
+			next: []time.Time{
+				time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC),
+				time.Date(2026, time.October, 19, 9, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Either the 1st or a Sunday
+			spec: "45 9 1 * 7",
+			next: []time.Time{
+				time.Date(2026, time.October, 18, 9, 45, 0, 0, time.UTC),
+				time.Date(2026, time.October, 25, 9, 45, 0, 0, time.UTC),
+				time.Date(2026, time.November, 1, 9, 45, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "0 0 29 2 *",
+			next: []time.Time{time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			spec: "@every 36h",
+			next: []time.Time{after.Add(36 * time.Hour), after.Add(72 * time.Hour)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(testCase.spec)
+			require.NoError(t, err)
+			run := after
+			for _, next := range testCase.next {
+				run = schedule.Next(run)
+				require.Equal(t, next, run)
+			}
+		})
+	}
+}
+
+func TestParseInvalidSchedule(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"0 0 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@every 5m",
+		"@every tomorrow",
+	} {
+		_, err := ParseSchedule(spec)
+		require.Error(t, err, spec)
+	}
+}
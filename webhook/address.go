@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// ErrInternalAddress is returned for a webhook host that resolves to an address of the bank's own network
+var ErrInternalAddress = errors.New("webhook host resolves to an internal address")
+
+// PublicIP reports whether ip can be the address of a webhook, that is neither loopback, private, link-local
+// (including the cloud metadata address 169.254.169.254), multicast nor unspecified
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// CheckHost resolves the host of a webhook URL, failing with ErrInternalAddress if any of its addresses isn't
+// public. Deliveries check the address they dial again, as the host can resolve differently by then.
+func CheckHost(ctx context.Context, u *url.URL) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return ErrInternalAddress
+		}
+	}
+	return nil
+}
+
+// newTransport returns a transport which only connects to the addresses allowed by allow. The address is
+// checked once resolved, right before the connection is made, so that a host can't be pointed elsewhere
+// between its registration and a delivery.
+func newTransport(allow func(net.IP) bool) *http.Transport {
+	dialer := &net.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return ErrInternalAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the webhook, bypassing the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,224 @@
+// Package webhook delivers the events of the outbox to the webhooks registered by their owners
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultBatchSize   = 50
+	defaultTimeout     = 10 * time.Second
+	defaultMaxAttempts = 8
+	// A failed delivery is retried after baseBackoff, doubled after each further failure up to maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Options configure a Dispatcher
+type Options struct {
+	// Interval between polls of the outbox and of the due deliveries
+	Interval time.Duration
+	// Timeout of each delivery, 10s if zero
+	Timeout time.Duration
+	// MaxAttempts is the number of failed attempts after which a delivery is dead, 8 if zero
+	MaxAttempts int32
+	// BatchSize is the number of events or deliveries claimed at once, 50 if zero
+	BatchSize int32
+}
+
+// Dispatcher fans the outbox events out to the webhooks of their owners and delivers them, retrying failed
+// deliveries with exponential backoff. Any number of dispatchers can share a database, each event and delivery
+// is claimed by one of them at a time.
+type Dispatcher struct {
+	store  db.Store
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+// NewDispatcher returns a dispatcher of the outbox of store
+func NewDispatcher(store db.Store, opts Options) *Dispatcher {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Transport: newTransport(PublicIP),
+			Timeout:   opts.Timeout,
+			// A redirect is answered as a failed delivery rather than followed to wherever it points
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// Run polls until ctx is done. Deliveries in progress are finished rather than cut short, so that their
+// outcome is recorded.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.opts.Interval)
+	defer ticker.Stop()
+	for {
+		dispatcher.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue fans out the pending events, then makes the due deliveries, in batches until one comes back
+// short of a full batch, or ctx is done
+func (dispatcher *Dispatcher) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := dispatcher.store.DispatchOutboxTx(context.Background(), dispatcher.opts.BatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to dispatch outbox events")
+			return
+		}
+		if n < int(dispatcher.opts.BatchSize) {
+			break
+		}
+	}
+	for ctx.Err() == nil {
+		n, err := dispatcher.deliverDue()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to claim webhook deliveries")
+			return
+		}
+		if n < int(dispatcher.opts.BatchSize) {
+			return
+		}
+	}
+}
+
+// deliverDue makes a batch of due deliveries concurrently, returning the number made
+func (dispatcher *Dispatcher) deliverDue() (int, error) {
+	now := dispatcher.now()
+	claimed, err := dispatcher.store.ClaimWebhookDeliveriesTx(context.Background(), db.ClaimWebhookDeliveriesTxParams{
+		Now:   now,
+		Limit: dispatcher.opts.BatchSize,
+		// Long enough for the whole batch to be delivered and recorded
+		LeaseUntil: now.Add(2 * dispatcher.opts.Timeout),
+	})
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, delivery := range claimed {
+		wg.Add(1)
+		go func(delivery db.ListDueWebhookDeliveriesRow) {
+			defer wg.Done()
+			dispatcher.deliver(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// deliver makes an attempt at a claimed delivery and records its outcome
+func (dispatcher *Dispatcher) deliver(delivery db.ListDueWebhookDeliveriesRow) {
+	sendErr := dispatcher.send(delivery)
+	now := dispatcher.now()
+	update := db.UpdateWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        db.WebhookDeliveryStatusDelivered,
+		NextAttemptAt: now,
+	}
+	switch {
+	case sendErr == nil:
+		update.DeliveredAt = sql.NullTime{Time: now, Valid: true}
+	case delivery.Attempts >= dispatcher.opts.MaxAttempts:
+		update.Status = db.WebhookDeliveryStatusDead
+		update.LastError = sendErr.Error()
+	default:
+		update.Status = db.WebhookDeliveryStatusPending
+		update.NextAttemptAt = now.Add(backoff(delivery.Attempts))
+		update.LastError = sendErr.Error()
+	}
+	logger := log.With().
+		Int64("delivery_id", delivery.ID).
+		Int64("webhook_id", delivery.WebhookID).
+		Int32("attempts", delivery.Attempts).
+		Logger()
+	if sendErr != nil {
+		logger.Warn().Err(sendErr).Str("status", string(update.Status)).Msg("webhook delivery failed")
+	}
+	if _, err := dispatcher.store.UpdateWebhookDelivery(context.Background(), update); err != nil {
+		// The lease runs out and the delivery is attempted again
+		logger.Error().Err(err).Msg("failed to record webhook delivery")
+	}
+}
+
+// send posts the signed event of a delivery to its webhook, failing unless it is answered with a 2xx status
+func (dispatcher *Dispatcher) send(delivery db.ListDueWebhookDeliveriesRow) error {
+	body, err := json.Marshal(Event{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventCreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dispatcher.opts.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, dispatcher.now(), body))
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, fmt.Sprint(delivery.ID))
+	response, err := dispatcher.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// The body isn't kept, the last error of a delivery can be read by the owner of the webhook
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt at a delivery which failed attempts times
+func backoff(attempts int32) time.Duration {
+	delay := baseBackoff
+	for i := int32(1); i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/harrychopra/go-api/db/mock"
+	db "github.com/harrychopra/go-api/db/models"
+	"github.com/harrychopra/go-api/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherDeliver(t *testing.T) {
+	secret := util.RandomString(32)
+	now := time.Now().Truncate(time.Second)
+	payload := json.RawMessage(`{"id":7,"amount":10}`)
+
+	testCases := []struct {
+		name     string
+		attempts int32
+		// The receiver listens on loopback, which deliveries are only allowed to reach in tests
+		internal    bool
+		handler     http.HandlerFunc
+		checkUpdate func(t *testing.T, update db.UpdateWebhookDeliveryParams)
+	}{
+		{
+			name:     "Delivered",
+			attempts: 1,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// The handler runs outside of the test goroutine, which require can't stop
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.NoError(t, Verify(secret, r.Header.Get(SignatureHeader), body, time.Minute))
+				assert.Equal(t, db.EventTransferCreated, r.Header.Get(EventHeader))
+				assert.Equal(t, "1", r.Header.Get(DeliveryHeader))
+
+				var event Event
+				assert.NoError(t, json.Unmarshal(body, &event))
+				assert.Equal(t, int64(3), event.ID)
+				assert.Equal(t, db.EventTransferCreated, event.Type)
+				assert.JSONEq(t, string(payload), string(event.Data))
+				w.WriteHeader(http.StatusNoContent)
+			},
+			checkUpdate: func(t *testing.T, update db.UpdateWebhookDeliveryParams) {
+				require.Equal(t, db.WebhookDeliveryStatusDelivered, update.Status)
+				require.True(t, update.DeliveredAt.Valid)
+				require.Empty(t, update.LastError)
+			},
+		},
+		{
+			name:     "Retried",
+			attempts: 2,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+			checkUpdate: func(t *testing.T, update db.UpdateWebhookDeliveryParams) {
+				require.Equal(t, db.WebhookDeliveryStatusPending, update.Status)
+				require.Equal(t, now.Add(2*baseBackoff), update.NextAttemptAt)
+				// The body of the response isn't kept
+				require.Equal(t, "unexpected status 500", update.LastError)
+				require.False(t, update.DeliveredAt.Valid)
+			},
+		},
+		{
+			name:     "Redirect",
+			attempts: 1,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+			},
+			checkUpdate: func(t *testing.T, update db.UpdateWebhookDeliveryParams) {
+				require.Equal(t, db.WebhookDeliveryStatusPending, update.Status)
+				require.Contains(t, update.LastError, "unexpected status 302")
+			},
+		},
+		{
+			name:     "InternalAddress",
+			attempts: 1,
+			internal: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				t.Error("delivery reached an internal address")
+			},
+			checkUpdate: func(t *testing.T, update db.UpdateWebhookDeliveryParams) {
+				require.Equal(t, db.WebhookDeliveryStatusPending, update.Status)
+				require.Contains(t, update.LastError, ErrInternalAddress.Error())
+			},
+		},
+		{
+			name:     "Dead",
+			attempts: defaultMaxAttempts,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			checkUpdate: func(t *testing.T, update db.UpdateWebhookDeliveryParams) {
+				require.Equal(t, db.WebhookDeliveryStatusDead, update.Status)
+				require.Equal(t, "unexpected status 400", update.LastError)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			receiver := httptest.NewServer(testCase.handler)
+			defer receiver.Close()
+
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStore(ctrl)
+			store.EXPECT().DispatchOutboxTx(gomock.Any(), gomock.Eq(int32(defaultBatchSize))).Times(1).Return(0, nil)
+			store.EXPECT().
+				ClaimWebhookDeliveriesTx(gomock.Any(), gomock.Eq(db.ClaimWebhookDeliveriesTxParams{
+					Now:        now,
+					Limit:      defaultBatchSize,
+					LeaseUntil: now.Add(2 * defaultTimeout),
+				})).
+				Times(1).
+				Return([]db.ListDueWebhookDeliveriesRow{{
+					ID:             1,
+					WebhookID:      2,
+					Attempts:       testCase.attempts,
+					Url:            receiver.URL,
+					Secret:         secret,
+					EventID:        3,
+					EventType:      db.EventTransferCreated,
+					Payload:        payload,
+					EventCreatedAt: now,
+				}}, nil)
+			// Deliveries are recorded from their own goroutine, so the update is checked once they're done
+			var update db.UpdateWebhookDeliveryParams
+			store.EXPECT().
+				UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+					update = arg
+					return db.WebhookDelivery{}, nil
+				})
+
+			dispatcher := NewDispatcher(store, Options{Interval: time.Hour})
+			if !testCase.internal {
+				dispatcher.client.Transport = newTransport(func(net.IP) bool { return true })
+			}
+			dispatcher.now = func() time.Time { return now }
+			dispatcher.dispatchDue(context.Background())
+			require.Equal(t, int64(1), update.ID)
+			testCase.checkUpdate(t, update)
+		})
+	}
+}
+
+func TestDispatcherBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStore(ctrl)
+	// Full batches of events are followed by another right away
+	gomock.InOrder(
+		store.EXPECT().DispatchOutboxTx(gomock.Any(), gomock.Eq(int32(2))).Return(2, nil),
+		store.EXPECT().DispatchOutboxTx(gomock.Any(), gomock.Eq(int32(2))).Return(1, nil),
+		store.EXPECT().ClaimWebhookDeliveriesTx(gomock.Any(), gomock.Any()).Return([]db.ListDueWebhookDeliveriesRow{}, nil),
+	)
+
+	dispatcher := NewDispatcher(store, Options{Interval: time.Hour, BatchSize: 2})
+	dispatcher.dispatchDue(context.Background())
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, baseBackoff, backoff(1))
+	require.Equal(t, 2*baseBackoff, backoff(2))
+	require.Equal(t, 4*baseBackoff, backoff(3))
+	require.Equal(t, maxBackoff, backoff(30))
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, address := range []string{"8.8.8.8", "203.0.113.10", "2001:4860:4860::8888"} {
+		require.True(t, PublicIP(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0",
+		"::1", "fd00::1", "fe80::1", "ff02::1", "::ffff:127.0.0.1",
+	} {
+		require.False(t, PublicIP(net.ParseIP(address)), address)
+	}
+}
@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	header := Sign("secret", time.Now(), body)
+	require.Regexp(t, `^t=\d+,v1=[0-9a-f]{64}$`, header)
+	require.NoError(t, Verify("secret", header, body, time.Minute))
+
+	require.ErrorIs(t, Verify("other", header, body, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", header, []byte(`{"id":2}`), time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", "v1=abc", body, time.Minute), ErrInvalidSignature)
+
+	// A replayed delivery is rejected once out of tolerance
+	old := Sign("secret", time.Now().Add(-time.Hour), body)
+	err := Verify("secret", old, body, time.Minute)
+	require.True(t, errors.Is(err, ErrInvalidSignature))
+}